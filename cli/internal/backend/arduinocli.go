@@ -0,0 +1,326 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// ── Backend: arduino-cli ──────────────────────────────────────────────────────
+
+func init() {
+	Register("arduino-cli", func(t Tools) Backend {
+		return &arduinoCLI{bin: or(t.ArduinoCLI, "arduino-cli")}
+	})
+}
+
+type arduinoCLI struct {
+	bin string
+}
+
+func (a *arduinoCLI) Name() string { return "arduino-cli" }
+
+func (a *arduinoCLI) Compile(req CompileRequest) ([]byte, error) {
+	fqbn, err := FQBN(req.Board)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{
+		"compile",
+		"--fqbn", fqbn,
+		"--build-path", req.BuildDir,
+		"--warnings", "all",
+	}
+	for _, prop := range buildProperties(req) {
+		args = append(args, "--build-property", prop)
+	}
+	if req.Verbose {
+		args = append(args, "--verbose")
+	}
+	args = append(args, req.SketchDir)
+
+	cmd := exec.Command(a.bin, args...)
+	cmd.Dir = req.SketchDir
+	return cmd.CombinedOutput()
+}
+
+// CompileCommands reads the compilation database arduino-cli writes into
+// the build path on every compile.
+func (a *arduinoCLI) CompileCommands(req CompileRequest) ([]CompileCommand, error) {
+	return readCompileDB(filepath.Join(req.BuildDir, CompileDBFile),
+		filepath.Join(req.BuildDir, "sketch"), req.SketchDir)
+}
+
+// buildProperties translates the build settings into the extra_flags
+// properties every Arduino platform leaves empty for users. They come after
+// the platform's own flags, so -std and -O given here win.
+func buildProperties(req CompileRequest) []string {
+	var c, cpp, elf []string
+	if req.Optimize != "" {
+		opt := optimizeFlag(req.Optimize)
+		c, cpp, elf = append(c, opt), append(cpp, opt), append(elf, opt)
+	}
+	if req.CppStd != "" {
+		cpp = append(cpp, stdFlag(req.CppStd))
+	}
+	c = append(c, req.ExtraFlags...)
+	cpp = append(cpp, req.ExtraFlags...)
+
+	var props []string
+	for _, p := range []struct {
+		key   string
+		flags []string
+	}{
+		{"compiler.c.extra_flags", c},
+		{"compiler.cpp.extra_flags", cpp},
+		{"compiler.c.elf.extra_flags", elf},
+	} {
+		if len(p.flags) > 0 {
+			props = append(props, p.key+"="+strings.Join(p.flags, " "))
+		}
+	}
+	return props
+}
+
+func (a *arduinoCLI) Upload(req UploadRequest) ([]byte, error) {
+	argv, err := a.UploadCommand(req)
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(a.bin, argv[1:]...).CombinedOutput()
+}
+
+func (a *arduinoCLI) UploadCommand(req UploadRequest) ([]string, error) {
+	fqbn, err := FQBN(req.Board)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{
+		"arduino-cli", "upload",
+		"--fqbn", fqbn,
+		"--port", req.Port,
+		"--input-dir", req.BuildDir,
+	}
+	if req.Baud > 0 {
+		args = append(args, "--upload-property", fmt.Sprintf("upload.speed=%d", req.Baud))
+	}
+	if req.Verbose {
+		args = append(args, "--verbose")
+	}
+	// arduino-cli asks the uploader (avrdude, bossac, esptool…) to read the
+	// flash back after writing it.
+	if req.Verify {
+		args = append(args, "--verify")
+	}
+	return args, nil
+}
+
+// VerifiesUpload reports that --verify is handled by arduino-cli, which
+// fails the upload itself on a mismatch.
+func (a *arduinoCLI) VerifiesUpload() bool { return true }
+
+func (a *arduinoCLI) DetectPorts() ([]Port, error) {
+	out, err := exec.Command(a.bin, "board", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("arduino-cli board list failed: %w", err)
+	}
+	return parsePorts(string(out)), nil
+}
+
+func (a *arduinoCLI) InstallLibrary(name, version string) error {
+	spec := name
+	if version != "" {
+		spec += "@" + version
+	}
+	if err := stream(a.bin, "lib", "install", spec); err != nil {
+		return fmt.Errorf("arduino-cli lib install %q failed: %w", spec, err)
+	}
+	return nil
+}
+
+// arduinoLibrary is a library in arduino-cli's JSON output.
+type arduinoLibrary struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Sentence string `json:"sentence"`
+}
+
+func (a *arduinoCLI) Libraries() ([]Library, error) {
+	out, err := exec.Command(a.bin, "lib", "list", "--format", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("arduino-cli lib list failed: %w", err)
+	}
+	type installed struct {
+		Library arduinoLibrary `json:"library"`
+	}
+	// arduino-cli 1.x wraps the list in an object; 0.x prints it bare.
+	var list []installed
+	if err := json.Unmarshal(out, &list); err != nil {
+		var wrapped struct {
+			InstalledLibraries []installed `json:"installed_libraries"`
+		}
+		if err := json.Unmarshal(out, &wrapped); err != nil {
+			return nil, fmt.Errorf("reading arduino-cli lib list: %w", err)
+		}
+		list = wrapped.InstalledLibraries
+	}
+	libs := make([]Library, 0, len(list))
+	for _, l := range list {
+		libs = append(libs, Library{Name: l.Library.Name, Version: l.Library.Version, Description: l.Library.Sentence})
+	}
+	return libs, nil
+}
+
+func (a *arduinoCLI) SearchLibraries(query string) ([]Library, error) {
+	out, err := exec.Command(a.bin, "lib", "search", query, "--format", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("arduino-cli lib search failed: %w", err)
+	}
+	// 0.x reports the latest release as an object, 1.x by version.
+	var res struct {
+		Libraries []struct {
+			Name          string                    `json:"name"`
+			Latest        *arduinoLibrary           `json:"latest"`
+			LatestVersion string                    `json:"latest_version"`
+			Releases      map[string]arduinoLibrary `json:"releases"`
+		} `json:"libraries"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, fmt.Errorf("reading arduino-cli lib search: %w", err)
+	}
+	libs := make([]Library, 0, len(res.Libraries))
+	for _, l := range res.Libraries {
+		latest := arduinoLibrary{Version: l.LatestVersion}
+		if l.Latest != nil {
+			latest = *l.Latest
+		} else if r, ok := l.Releases[l.LatestVersion]; ok {
+			latest.Sentence = r.Sentence
+		}
+		libs = append(libs, Library{Name: l.Name, Version: latest.Version, Description: latest.Sentence})
+	}
+	return libs, nil
+}
+
+func (a *arduinoCLI) RemoveLibrary(name string) error {
+	if err := stream(a.bin, "lib", "uninstall", name); err != nil {
+		return fmt.Errorf("arduino-cli lib uninstall %q failed: %w", name, err)
+	}
+	return nil
+}
+
+func (a *arduinoCLI) InstallCore(board string) error {
+	platform, err := Platform(board)
+	if err != nil {
+		return err
+	}
+	if a.coreInstalled(platform) {
+		return nil
+	}
+	ui.SectionTitle(fmt.Sprintf("Installing %s core  [arduino-cli]", platform))
+	if err := stream(a.bin, "core", "update-index"); err != nil {
+		return fmt.Errorf("arduino-cli core update-index failed: %w", err)
+	}
+	if err := stream(a.bin, "core", "install", platform); err != nil {
+		return fmt.Errorf("arduino-cli core install %s failed: %w", platform, err)
+	}
+	ui.Success(fmt.Sprintf("%s core installed", platform))
+	return nil
+}
+
+func (a *arduinoCLI) Core(board string) (Core, error) {
+	platform, err := Platform(board)
+	if err != nil {
+		return Core{}, err
+	}
+	cores, err := a.Cores()
+	if err != nil {
+		return Core{}, err
+	}
+	for _, c := range cores {
+		if c.ID == platform {
+			return c, nil
+		}
+	}
+	return Core{ID: platform}, nil
+}
+
+func (a *arduinoCLI) Cores() ([]Core, error) {
+	out, err := exec.Command(a.bin, "core", "list", "--format", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("arduino-cli core list failed: %w", err)
+	}
+	// arduino-cli 1.x wraps the list in an object and renames the fields.
+	type platform struct {
+		ID               string `json:"id"`
+		Installed        string `json:"installed"`
+		Latest           string `json:"latest"`
+		InstalledVersion string `json:"installed_version"`
+		LatestVersion    string `json:"latest_version"`
+	}
+	var list []platform
+	if err := json.Unmarshal(out, &list); err != nil {
+		var wrapped struct {
+			Platforms []platform `json:"platforms"`
+		}
+		if err := json.Unmarshal(out, &wrapped); err != nil {
+			return nil, fmt.Errorf("reading arduino-cli core list: %w", err)
+		}
+		list = wrapped.Platforms
+	}
+	cores := make([]Core, 0, len(list))
+	for _, p := range list {
+		cores = append(cores, Core{
+			ID:      p.ID,
+			Version: or(p.InstalledVersion, p.Installed),
+			Latest:  or(p.LatestVersion, p.Latest),
+		})
+	}
+	return cores, nil
+}
+
+func (a *arduinoCLI) InstallCoreVersion(id, version string) error {
+	if err := stream(a.bin, "core", "update-index"); err != nil {
+		return fmt.Errorf("arduino-cli core update-index failed: %w", err)
+	}
+	if err := stream(a.bin, "core", "install", id+"@"+version); err != nil {
+		return fmt.Errorf("arduino-cli core install %s@%s failed: %w", id, version, err)
+	}
+	return nil
+}
+
+func (a *arduinoCLI) UpdateCore(id string) error {
+	if err := stream(a.bin, "core", "update-index"); err != nil {
+		return fmt.Errorf("arduino-cli core update-index failed: %w", err)
+	}
+	if err := stream(a.bin, "core", "upgrade", id); err != nil {
+		return fmt.Errorf("arduino-cli core upgrade %s failed: %w", id, err)
+	}
+	return nil
+}
+
+func (a *arduinoCLI) RemoveCore(id string) error {
+	if err := stream(a.bin, "core", "uninstall", id); err != nil {
+		return fmt.Errorf("arduino-cli core uninstall %s failed: %w", id, err)
+	}
+	return nil
+}
+
+// coreInstalled reports whether `arduino-cli core list` shows platform.
+// When the list cannot be read the core is assumed present, and compile
+// reports the real problem.
+func (a *arduinoCLI) coreInstalled(platform string) bool {
+	out, err := exec.Command(a.bin, "core", "list").Output()
+	if err != nil {
+		return true
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == platform {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: backend  —  the toolchains that compile and upload a sketch
+//
+//  A Backend turns the generated sketch into firmware and gets it onto a
+//  board. Built-in backends register themselves by name (arduino-cli,
+//  platformio, tsuki-flash, tsuki-flash+cores); any other name is looked
+//  up as an external command template (see external.go), so a team can
+//  drive its own Makefile without touching the CLI.
+//
+//  Which backend runs is decided in one place, Resolve:
+//    --backend flag  >  manifest "backend"  >  config backend  >  arduino-cli
+// ─────────────────────────────────────────────────────────────────────────────
+
+package backend
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/tsuki/cli/internal/config"
+	"github.com/tsuki/cli/internal/manifest"
+)
+
+// Default is the backend used when neither the flag, the manifest nor the
+// config names one.
+const Default = "arduino-cli"
+
+// Backend compiles and uploads sketches with one toolchain.
+type Backend interface {
+	// Name is the backend's registered name, e.g. "arduino-cli".
+	Name() string
+	// Compile builds the sketch in req.SketchDir into req.BuildDir. The
+	// compiler output is returned either way; on failure it holds the errors.
+	Compile(req CompileRequest) ([]byte, error)
+	// Upload writes the firmware in req.BuildDir to req.Port once — retries
+	// are the caller's. The uploader output is returned either way.
+	Upload(req UploadRequest) ([]byte, error)
+	// DetectPorts lists the serial ports with a board attached.
+	DetectPorts() ([]Port, error)
+	// InstallLibrary installs an Arduino library by its library-manager name,
+	// at version or, when version is empty, the latest release.
+	InstallLibrary(name, version string) error
+	// InstallCore installs the core and toolchain board needs, streaming the
+	// progress. It does nothing when they are already installed.
+	InstallCore(board string) error
+}
+
+// UploadCommander is implemented by backends that can print the argv of an
+// upload, for instructions on machines without tsuki.
+type UploadCommander interface {
+	UploadCommand(req UploadRequest) ([]string, error)
+}
+
+// Verifier is implemented by backends whose Upload honours
+// UploadRequest.Verify itself. Uploads through other backends are verified
+// by reading the flash back afterwards.
+type Verifier interface {
+	VerifiesUpload() bool
+}
+
+// LibraryManager is implemented by backends that can list, search and
+// remove Arduino libraries — what `tsuki lib` and the library check before
+// a compile need beyond InstallLibrary.
+type LibraryManager interface {
+	// Libraries lists the installed libraries.
+	Libraries() ([]Library, error)
+	// SearchLibraries returns the latest release of each library matching
+	// query.
+	SearchLibraries(query string) ([]Library, error)
+	RemoveLibrary(name string) error
+}
+
+// LibraryFetcher is implemented by backends whose Compile fetches
+// CompileRequest.Libraries itself, so nothing has to be installed first.
+type LibraryFetcher interface {
+	FetchesLibraries() bool
+}
+
+// CoreManager is implemented by backends that can report, update and
+// remove board cores — what `tsuki core` and the build-info need beyond
+// InstallCore.
+type CoreManager interface {
+	// Core returns the core board compiles against. Its Version is empty
+	// when the core is not installed.
+	Core(board string) (Core, error)
+	// Cores lists the installed cores.
+	Cores() ([]Core, error)
+	// InstallCoreVersion installs exactly version of the core, for
+	// toolchains pinned in the manifest.
+	InstallCoreVersion(id, version string) error
+	UpdateCore(id string) error
+	RemoveCore(id string) error
+}
+
+// CompileRequest describes one compile.
+type CompileRequest struct {
+	Board     string
+	SketchDir string
+	BuildDir  string
+	// Name is the sketch name, which is also the firmware's base name.
+	Name   string
+	CppStd string
+	// Optimize is the optimisation level, e.g. "Os" or "O2".
+	Optimize string
+	// ExtraFlags are passed to every C and C++ compile.
+	ExtraFlags []string
+	// Includes are the include directories of the installed tsuki packages.
+	Includes []string
+	// Libraries are the Arduino libraries the project needs, with their
+	// pinned versions.
+	Libraries []Library
+	Verbose   bool
+}
+
+// UploadRequest describes one upload.
+type UploadRequest struct {
+	Board    string
+	Port     string
+	BuildDir string
+	// Name is the firmware's base name in BuildDir.
+	Name string
+	// Baud overrides the board's upload speed; 0 keeps it.
+	Baud    int
+	Verify  bool
+	Verbose bool
+}
+
+// Port is a serial port with a board attached.
+type Port struct {
+	Address string `json:"address"`
+	// Board is what the backend reports for the port; may be empty.
+	Board string `json:"board,omitempty"`
+}
+
+// Library is an Arduino library, by its library-manager name.
+type Library struct {
+	Name string `json:"name"`
+	// Version is the installed release, or the latest one in search results.
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Core is a board core in the backend's naming: "arduino:avr" for
+// arduino-cli, "avr" for tsuki-modules, "atmelavr" for PlatformIO.
+type Core struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+	// Latest is the newest release, when the backend knows it.
+	Latest string `json:"latest,omitempty"`
+}
+
+// String is "id@version", or the ID alone when the version is unknown.
+func (c Core) String() string {
+	if c.Version == "" {
+		return c.ID
+	}
+	return c.ID + "@" + c.Version
+}
+
+// Tools are the binaries and places a backend works with.
+type Tools struct {
+	ArduinoCLI  string
+	FlashBinary string
+	PlatformIO  string
+	// ProjectDir is searched for external backend templates; may be empty.
+	ProjectDir string
+	Verbose    bool
+}
+
+// ── Registry ──────────────────────────────────────────────────────────────────
+
+// Factory creates a backend from the configured tools.
+type Factory func(tools Tools) Backend
+
+var registry = map[string]Factory{}
+
+// Register makes a backend available under name. Registering a name twice
+// replaces the earlier factory.
+func Register(name string, f Factory) {
+	registry[name] = f
+}
+
+// Names returns the registered backends, sorted.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the backend called name: a registered one, or else an
+// external command template of that name.
+func New(name string, tools Tools) (Backend, error) {
+	if name == "" {
+		name = Default
+	}
+	if f, ok := registry[name]; ok {
+		return f(tools), nil
+	}
+	path := findTemplate(name, tools.ProjectDir)
+	if path == "" {
+		return nil, fmt.Errorf("unknown backend %q — use one of %s, or add a template at %s",
+			name, strings.Join(Names(), ", "), strings.Join(templatePaths(name, tools.ProjectDir), " or "))
+	}
+	return loadExternal(name, path, tools)
+}
+
+// Resolve picks the backend name: flag, then the manifest, then the config,
+// then Default. m and cfg may be nil.
+func Resolve(flag string, m *manifest.Manifest, cfg *config.Config) string {
+	if flag != "" {
+		return flag
+	}
+	if m != nil && m.Backend != "" {
+		return m.Backend
+	}
+	if cfg != nil && cfg.Backend != "" {
+		return cfg.Backend
+	}
+	return Default
+}
+
+// EnsureCore installs the core board needs unless b reports it installed,
+// so builds and uploads do not go through InstallCore — and the network —
+// every time. Backends that cannot tell, such as external templates, are
+// asked to install it.
+func EnsureCore(b Backend, board string) error {
+	if cm, ok := b.(CoreManager); ok {
+		if c, err := cm.Core(board); err == nil && c.Version != "" {
+			return nil
+		}
+	}
+	return b.InstallCore(board)
+}
+
+// ── Helpers ───────────────────────────────────────────────────────────────────
+
+// or returns bin, or def when bin is empty.
+func or(bin, def string) string {
+	if bin == "" {
+		return def
+	}
+	return bin
+}
+
+// stream runs a command with its output on the terminal.
+func stream(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// parsePorts reads a port listing — arduino-cli board list, tsuki-flash
+// detect — whose lines start with the port address.
+func parsePorts(out string) []Port {
+	var ports []Port
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		addr := fields[0]
+		if !strings.HasPrefix(addr, "/dev/") && !strings.HasPrefix(addr, "COM") {
+			continue
+		}
+		p := Port{Address: addr}
+		if len(fields) > 1 {
+			p.Board = strings.Join(fields[1:], " ")
+		}
+		ports = append(ports, p)
+	}
+	return ports
+}
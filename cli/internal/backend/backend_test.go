@@ -0,0 +1,55 @@
+package backend
+
+import "testing"
+
+// coreBackend is a Backend and CoreManager that only answers Core and
+// counts InstallCore calls; the other methods are not used.
+type coreBackend struct {
+	Backend
+	CoreManager
+	version  string
+	installs int
+}
+
+func (b *coreBackend) Core(board string) (Core, error) {
+	return Core{ID: "arduino:avr", Version: b.version}, nil
+}
+
+func (b *coreBackend) InstallCore(board string) error {
+	b.installs++
+	b.version = "1.8.6"
+	return nil
+}
+
+func TestEnsureCore(t *testing.T) {
+	b := &coreBackend{}
+	for i := 0; i < 2; i++ {
+		if err := EnsureCore(b, "uno"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.installs != 1 {
+		t.Errorf("InstallCore ran %d times, want once: only while the core is missing", b.installs)
+	}
+}
+
+// installOnly cannot report its cores.
+type installOnly struct {
+	Backend
+	installs int
+}
+
+func (b *installOnly) InstallCore(board string) error {
+	b.installs++
+	return nil
+}
+
+func TestEnsureCoreWithoutCoreManager(t *testing.T) {
+	b := &installOnly{}
+	if err := EnsureCore(b, "uno"); err != nil {
+		t.Fatal(err)
+	}
+	if b.installs != 1 {
+		t.Errorf("InstallCore ran %d times, want once", b.installs)
+	}
+}
@@ -0,0 +1,49 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// boardFQBN maps short board IDs to fully qualified board names.
+var boardFQBN = map[string]string{
+	"uno":      "arduino:avr:uno",
+	"nano":     "arduino:avr:nano",
+	"mega":     "arduino:avr:mega",
+	"leonardo": "arduino:avr:leonardo",
+	"micro":    "arduino:avr:micro",
+	"due":      "arduino:sam:arduino_due_x",
+	"mkr1000":  "arduino:samd:mkr1000",
+	"esp32":    "esp32:esp32:esp32",
+	"esp8266":  "esp8266:esp8266:generic",
+	"pico":     "rp2040:rp2040:rpipico",
+	"teensy40": "teensy:avr:teensy40",
+}
+
+// FQBN maps a short board ID to its fully qualified board name.
+func FQBN(board string) (string, error) {
+	fqbn, ok := boardFQBN[strings.ToLower(board)]
+	if !ok {
+		return "", fmt.Errorf("unknown board %q — run `tsuki boards list` for the full list", board)
+	}
+	return fqbn, nil
+}
+
+// Platform returns the "vendor:arch" core a board needs, e.g. "arduino:avr".
+func Platform(board string) (string, error) {
+	fqbn, err := FQBN(board)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(fqbn, ":")
+	return parts[0] + ":" + parts[1], nil
+}
+
+// Arch returns the architecture of a board's core, e.g. "avr".
+func Arch(board string) (string, error) {
+	fqbn, err := FQBN(board)
+	if err != nil {
+		return "", err
+	}
+	return strings.Split(fqbn, ":")[1], nil
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CompileDBFile is the name of a JSON compilation database.
+const CompileDBFile = "compile_commands.json"
+
+// CompileDatabase is implemented by backends that can report the compiler
+// invocations of a compile, for editors such as clangd.
+type CompileDatabase interface {
+	// CompileCommands returns the commands the last Compile of req ran,
+	// with the sketch sources at their paths in req.SketchDir.
+	CompileCommands(req CompileRequest) ([]CompileCommand, error)
+}
+
+// CompileCommand is one entry of a compilation database. Toolchains write
+// either Arguments or Command.
+type CompileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Arguments []string `json:"arguments,omitempty"`
+	Command   string   `json:"command,omitempty"`
+	Output    string   `json:"output,omitempty"`
+}
+
+// readCompileDB reads the compilation database at path. Toolchains compile
+// a copy of the sketch; entries for files in copyDir that also exist in
+// sketchDir are pointed at the sketch's file, so an editor opening the
+// sketch finds its flags. Everything else — the core, libraries, generated
+// files — is kept as the toolchain wrote it.
+func readCompileDB(path, copyDir, sketchDir string) ([]CompileCommand, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cmds []CompileCommand
+	if err := json.Unmarshal(data, &cmds); err != nil {
+		return nil, err
+	}
+	if copyDir == "" || copyDir == sketchDir {
+		return cmds, nil
+	}
+	for i, c := range cmds {
+		file := c.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(c.Directory, file)
+		}
+		rel, err := filepath.Rel(copyDir, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		orig := filepath.Join(sketchDir, rel)
+		if _, err := os.Stat(orig); err != nil {
+			continue
+		}
+		replace := func(args []string) {
+			for j, arg := range args {
+				if arg == c.File || arg == file {
+					args[j] = orig
+				}
+			}
+		}
+		replace(cmds[i].Arguments)
+		if c.Command != "" {
+			// Only whole words: the file name recurs inside the -o path.
+			words := strings.Split(c.Command, " ")
+			replace(words)
+			cmds[i].Command = strings.Join(words, " ")
+		}
+		cmds[i].File = orig
+	}
+	return cmds, nil
+}
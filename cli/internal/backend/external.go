@@ -0,0 +1,362 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tsuki/cli/internal/config"
+)
+
+// ── Backend: external command template ────────────────────────────────────────
+//
+// A backend name that is not registered is looked up as a TOML template,
+// first in the project, then in the user config:
+//
+//   <project>/.tsuki/backends/<name>.toml
+//   ~/.config/tsuki/backends/<name>.toml
+//
+// The template maps each operation to a command line:
+//
+//   [commands]
+//   compile         = "make -C {project_dir} BOARD={board} OUT={build_dir}"
+//   upload          = "make -C {project_dir} flash PORT={port}"
+//   detect          = "make -s -C {project_dir} ports"
+//   install_library = "make -C {project_dir} lib NAME={library}"
+//   install_core    = ""
+//
+// Commands are split into arguments like a shell would (quotes group, no
+// expansion) and run in the project directory; placeholders are replaced
+// inside each argument, so paths with spaces stay whole; {extra_flags}
+// expands to the flags joined by spaces, {baud} to "" when unset. Only
+// compile is required. A template cannot report which cores are installed,
+// so install_core runs before every build and upload: it must do nothing,
+// quickly, when the core is already there.
+
+// placeholders are the names a template command may use in braces.
+var placeholders = map[string]bool{
+	"board": true, "fqbn": true, "port": true, "library": true, "version": true,
+	"sketch_dir": true, "build_dir": true, "project_dir": true,
+	"name": true, "cpp_std": true, "optimize": true, "extra_flags": true, "baud": true,
+}
+
+// settingPlaceholders name the command and placeholder that carry each
+// setting; a template that leaves one out ignores the setting.
+var settingPlaceholders = map[string][2]string{
+	SettingCppStd:     {"compile", "cpp_std"},
+	SettingOptimize:   {"compile", "optimize"},
+	SettingExtraFlags: {"compile", "extra_flags"},
+	SettingUploadBaud: {"upload", "baud"},
+}
+
+var placeholderRe = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// templateCommands are the keys of the [commands] table.
+var templateCommands = []string{"compile", "upload", "detect", "install_library", "install_core"}
+
+type external struct {
+	name     string
+	path     string
+	dir      string
+	commands map[string][]string
+}
+
+// templatePaths are the places New looks for an external backend, in order.
+func templatePaths(name, projectDir string) []string {
+	var paths []string
+	if projectDir != "" {
+		paths = append(paths, filepath.Join(projectDir, ".tsuki", "backends", name+".toml"))
+	}
+	if cfgPath, err := config.Path(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(cfgPath), "backends", name+".toml"))
+	}
+	return paths
+}
+
+func findTemplate(name, projectDir string) string {
+	// A name is a file name, never a path.
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return ""
+	}
+	for _, p := range templatePaths(name, projectDir) {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+func loadExternal(name, path string, tools Tools) (Backend, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	commands, err := parseTemplate(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("backend template %s: %w", path, err)
+	}
+	if len(commands["compile"]) == 0 {
+		return nil, fmt.Errorf("backend template %s: [commands] compile is required", path)
+	}
+	return &external{name: name, path: path, dir: tools.ProjectDir, commands: commands}, nil
+}
+
+// parseTemplate reads the [commands] table of a template. Like the
+// tsukilib.toml reader it handles one `key = "value"` per line; values are
+// TOML basic ("…") or literal ('…') strings.
+func parseTemplate(src string) (map[string][]string, error) {
+	known := map[string]bool{}
+	for _, k := range templateCommands {
+		known[k] = true
+	}
+
+	commands := map[string][]string{}
+	section := ""
+	for i, raw := range strings.Split(src, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			if section != "commands" {
+				return nil, fmt.Errorf("line %d: unknown table [%s] (only [commands] is read)", i+1, section)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = \"value\"", i+1)
+		}
+		key = strings.TrimSpace(key)
+		if section != "commands" {
+			return nil, fmt.Errorf("line %d: %s is outside [commands]", i+1, key)
+		}
+		if !known[key] {
+			return nil, fmt.Errorf("line %d: unknown command %q (use %s)", i+1, key, strings.Join(templateCommands, ", "))
+		}
+		str, err := tomlString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", i+1, key, err)
+		}
+		argv, err := splitArgs(str)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", i+1, key, err)
+		}
+		for _, m := range placeholderRe.FindAllStringSubmatch(str, -1) {
+			if !placeholders[m[1]] {
+				return nil, fmt.Errorf("line %d: %s: unknown placeholder {%s}", i+1, key, m[1])
+			}
+		}
+		commands[key] = argv
+	}
+	return commands, nil
+}
+
+// tomlString decodes a basic or literal TOML string, ignoring a trailing
+// comment.
+func tomlString(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, "'"):
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return v[1 : end+1], nil
+	case strings.HasPrefix(v, `"`):
+		for i := 1; i < len(v); i++ {
+			switch v[i] {
+			case '\\':
+				i++
+			case '"':
+				return strconv.Unquote(v[:i+1])
+			}
+		}
+		return "", fmt.Errorf("unterminated string")
+	}
+	return "", fmt.Errorf("value must be a quoted string")
+}
+
+// splitArgs splits a command line into arguments. Single and double quotes
+// group; nothing else is special.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+func (e *external) Name() string { return e.name }
+
+// Honours reports whether the template's command uses the setting's
+// placeholder.
+func (e *external) Honours(setting string) bool {
+	p, ok := settingPlaceholders[setting]
+	if !ok {
+		return false
+	}
+	for _, arg := range e.commands[p[0]] {
+		if strings.Contains(arg, "{"+p[1]+"}") {
+			return true
+		}
+	}
+	return false
+}
+
+// command expands the template for op, or returns nil when it has none.
+func (e *external) command(op string, vars map[string]string) ([]string, error) {
+	argv := e.commands[op]
+	if len(argv) == 0 {
+		return nil, nil
+	}
+	vars["project_dir"] = e.dir
+	if board := vars["board"]; board != "" {
+		if fqbn, err := FQBN(board); err == nil {
+			vars["fqbn"] = fqbn
+		}
+	}
+
+	out := make([]string, len(argv))
+	for i, arg := range argv {
+		var missing string
+		out[i] = placeholderRe.ReplaceAllStringFunc(arg, func(m string) string {
+			name := m[1 : len(m)-1]
+			v, ok := vars[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return v
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("backend %s: {%s} is not available to the %s command (%s)", e.name, missing, op, e.path)
+		}
+	}
+	return out, nil
+}
+
+func (e *external) run(argv []string) ([]byte, error) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = e.dir
+	return cmd.CombinedOutput()
+}
+
+func (e *external) Compile(req CompileRequest) ([]byte, error) {
+	argv, err := e.command("compile", map[string]string{
+		"board": req.Board, "sketch_dir": req.SketchDir, "build_dir": req.BuildDir,
+		"name": req.Name, "cpp_std": req.CppStd, "optimize": req.Optimize,
+		"extra_flags": strings.Join(req.ExtraFlags, " "),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e.run(argv)
+}
+
+func (e *external) Upload(req UploadRequest) ([]byte, error) {
+	argv, err := e.UploadCommand(req)
+	if err != nil {
+		return nil, err
+	}
+	return e.run(argv)
+}
+
+func (e *external) UploadCommand(req UploadRequest) ([]string, error) {
+	argv, err := e.command("upload", map[string]string{
+		"board": req.Board, "port": req.Port, "build_dir": req.BuildDir, "name": req.Name,
+		"baud": baudValue(req.Baud),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if argv == nil {
+		return nil, fmt.Errorf("backend %s cannot upload — add [commands] upload to %s", e.name, e.path)
+	}
+	return argv, nil
+}
+
+func baudValue(baud int) string {
+	if baud == 0 {
+		return ""
+	}
+	return strconv.Itoa(baud)
+}
+
+func (e *external) DetectPorts() ([]Port, error) {
+	argv, err := e.command("detect", map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+	if argv == nil {
+		return nil, fmt.Errorf("backend %s cannot detect boards — pass --port, or add [commands] detect to %s", e.name, e.path)
+	}
+	out, err := e.run(argv)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", argv[0], err)
+	}
+	return parsePorts(string(out)), nil
+}
+
+func (e *external) InstallLibrary(name, version string) error {
+	argv, err := e.command("install_library", map[string]string{"library": name, "version": version})
+	if err != nil {
+		return err
+	}
+	if argv == nil {
+		return fmt.Errorf("backend %s cannot install libraries — add [commands] install_library to %s", e.name, e.path)
+	}
+	return e.stream(argv)
+}
+
+// InstallCore runs install_core when the template has one; without it the
+// toolchain is assumed to manage its own cores. install_core is run every
+// time, so it has to be idempotent.
+func (e *external) InstallCore(board string) error {
+	argv, err := e.command("install_core", map[string]string{"board": board})
+	if err != nil || argv == nil {
+		return err
+	}
+	return e.stream(argv)
+}
+
+func (e *external) stream(argv []string) error {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = e.dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", strings.Join(argv, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,506 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/tsuki/cli/internal/firmware"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// ── Backend: PlatformIO ───────────────────────────────────────────────────────
+//
+// The transpiled sketch is laid out as a PlatformIO project next to the
+// firmware, in <build dir>/platformio:
+//
+//   platformio.ini   one [env:<board>] with the board's platform and the
+//                    Arduino libraries as lib_deps
+//   src/             a copy of the sketch's C/C++ sources
+//
+// `pio run` then builds it, and the image is copied back into the build dir
+// as <name>.hex / .bin / .elf, where the other backends leave theirs.
+
+func init() {
+	Register("platformio", func(t Tools) Backend {
+		return &platformIO{bin: or(t.PlatformIO, "pio")}
+	})
+}
+
+// pioBoard is a PlatformIO platform and board ID.
+type pioBoard struct {
+	platform string
+	board    string
+}
+
+// pioBoards maps tsuki board IDs to PlatformIO boards. The environment in
+// platformio.ini is named after the tsuki ID.
+var pioBoards = map[string]pioBoard{
+	"uno":      {"atmelavr", "uno"},
+	"nano":     {"atmelavr", "nanoatmega328"},
+	"mega":     {"atmelavr", "megaatmega2560"},
+	"leonardo": {"atmelavr", "leonardo"},
+	"micro":    {"atmelavr", "micro"},
+	"due":      {"atmelsam", "due"},
+	"mkr1000":  {"atmelsam", "mkr1000USB"},
+	"esp32":    {"espressif32", "esp32dev"},
+	"esp8266":  {"espressif8266", "esp01_1m"},
+	"pico":     {"raspberrypi", "pico"},
+	"teensy40": {"teensy", "teensy40"},
+}
+
+// sourceExts are the sketch files copied into the project's src/. The .ino
+// stub is left out: it only exists for arduino-cli.
+var sourceExts = map[string]bool{".cpp": true, ".c": true, ".h": true, ".hpp": true}
+
+type platformIO struct {
+	bin string
+}
+
+func (p *platformIO) Name() string { return "platformio" }
+
+func pioEnv(board string) (string, pioBoard, error) {
+	env := strings.ToLower(board)
+	b, ok := pioBoards[env]
+	if !ok {
+		return "", pioBoard{}, fmt.Errorf("board %q has no PlatformIO mapping — run `tsuki boards list` for the full list", board)
+	}
+	return env, b, nil
+}
+
+// pioProject is the generated project directory for a build dir.
+func pioProject(buildDir string) string {
+	return filepath.Join(buildDir, "platformio")
+}
+
+func (p *platformIO) Compile(req CompileRequest) ([]byte, error) {
+	env, b, err := pioEnv(req.Board)
+	if err != nil {
+		return nil, err
+	}
+	proj := pioProject(req.BuildDir)
+	if err := writePIOProject(proj, env, b, req); err != nil {
+		return nil, fmt.Errorf("generating the PlatformIO project: %w", err)
+	}
+	if err := syncSources(req.SketchDir, filepath.Join(proj, "src")); err != nil {
+		return nil, fmt.Errorf("copying the sketch into the PlatformIO project: %w", err)
+	}
+
+	args := []string{"run", "-d", proj, "-e", env}
+	if req.Verbose {
+		args = append(args, "-v")
+	}
+	out, err := exec.Command(p.bin, args...).CombinedOutput()
+	if err != nil {
+		return out, err
+	}
+
+	// Bring the image back to where build and upload look for it.
+	name := or(req.Name, "firmware")
+	found := false
+	for _, ext := range []string{".hex", ".bin", ".elf"} {
+		src := filepath.Join(proj, ".pio", "build", env, "firmware"+ext)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := copyFile(src, filepath.Join(req.BuildDir, name+ext)); err != nil {
+			return out, err
+		}
+		found = found || ext != ".elf"
+	}
+	if !found {
+		return out, fmt.Errorf("pio run succeeded but left no firmware in %s", filepath.Join(proj, ".pio", "build", env))
+	}
+	return out, nil
+}
+
+// CompileCommands generates the project's compilation database with the
+// compiledb target, which reuses the last build's configuration.
+func (p *platformIO) CompileCommands(req CompileRequest) ([]CompileCommand, error) {
+	env, _, err := pioEnv(req.Board)
+	if err != nil {
+		return nil, err
+	}
+	proj := pioProject(req.BuildDir)
+	if out, err := exec.Command(p.bin, "run", "-d", proj, "-e", env, "-t", "compiledb").CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pio run -t compiledb: %v\n%s", err, out)
+	}
+	return readCompileDB(filepath.Join(proj, CompileDBFile), filepath.Join(proj, "src"), req.SketchDir)
+}
+
+// writePIOProject writes platformio.ini for one environment.
+func writePIOProject(dir, env string, b pioBoard, req CompileRequest) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var ini strings.Builder
+	ini.WriteString("; Generated by tsuki — do not edit, it is rewritten on every build.\n\n")
+	fmt.Fprintf(&ini, "[env:%s]\n", env)
+	fmt.Fprintf(&ini, "platform = %s\n", b.platform)
+	fmt.Fprintf(&ini, "board = %s\n", b.board)
+	ini.WriteString("framework = arduino\n")
+
+	// The Arduino frameworks pass their own -std and -Os; replace them.
+	var flags, unflags []string
+	if req.CppStd != "" {
+		unflags = append(unflags, "-std=gnu++11", "-std=gnu++14", "-std=gnu++17")
+		flags = append(flags, stdFlag(req.CppStd))
+	}
+	if req.Optimize != "" {
+		unflags = append(unflags, "-Os")
+		flags = append(flags, optimizeFlag(req.Optimize))
+	}
+	flags = append(flags, req.ExtraFlags...)
+	if len(unflags) > 0 {
+		fmt.Fprintf(&ini, "build_unflags = %s\n", strings.Join(unflags, " "))
+	}
+	for _, inc := range req.Includes {
+		flag := "-I" + inc
+		if strings.ContainsAny(flag, " \t") {
+			flag = `"` + flag + `"`
+		}
+		flags = append(flags, flag)
+	}
+	if len(flags) > 0 {
+		fmt.Fprintf(&ini, "build_flags = %s\n", strings.Join(flags, " "))
+	}
+	if len(req.Libraries) > 0 {
+		ini.WriteString("lib_deps =\n")
+		for _, lib := range req.Libraries {
+			fmt.Fprintf(&ini, "    %s\n", pioSpec(lib.Name, lib.Version))
+		}
+	}
+
+	return os.WriteFile(filepath.Join(dir, "platformio.ini"), []byte(ini.String()), 0644)
+}
+
+// syncSources replaces dst with the C/C++ sources of sketchDir.
+func syncSources(sketchDir, dst string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(sketchDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !sourceExts[strings.ToLower(filepath.Ext(e.Name()))] {
+			continue
+		}
+		if err := copyFile(filepath.Join(sketchDir, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// Upload flashes the image the last compile left in the build dir. There is
+// no UploadCommand: pio can only upload from inside a project, which a
+// `tsuki dist` archive does not carry.
+func (p *platformIO) Upload(req UploadRequest) ([]byte, error) {
+	env, _, err := pioEnv(req.Board)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.prepareUpload(req); err != nil {
+		return nil, err
+	}
+	// nobuild flashes the existing image instead of rebuilding the project.
+	args := []string{
+		"run",
+		"-d", pioProject(req.BuildDir),
+		"-e", env,
+		"-t", "nobuild", "-t", "upload",
+		"--upload-port", req.Port,
+	}
+	if req.Verbose {
+		args = append(args, "-v")
+	}
+	return exec.Command(p.bin, args...).CombinedOutput()
+}
+
+// prepareUpload makes sure the PlatformIO project exists and holds the
+// build dir's image, so that the last build is flashed whichever backend
+// made it, and an image from `tsuki upload --from` can be flashed too.
+func (p *platformIO) prepareUpload(req UploadRequest) error {
+	env, b, err := pioEnv(req.Board)
+	if err != nil {
+		return err
+	}
+	proj := pioProject(req.BuildDir)
+	if _, err := os.Stat(filepath.Join(proj, "platformio.ini")); err != nil {
+		if err := writePIOProject(proj, env, b, CompileRequest{}); err != nil {
+			return err
+		}
+	}
+	if err := setUploadSpeed(filepath.Join(proj, "platformio.ini"), req.Baud); err != nil {
+		return err
+	}
+	out := filepath.Join(proj, ".pio", "build", env)
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	image := firmware.Find(req.BuildDir)
+	if image == "" {
+		return fmt.Errorf("no firmware in %s — run `tsuki build --compile` first", req.BuildDir)
+	}
+	// Replace the image of the last upload, whichever format it was in.
+	for _, ext := range []string{".hex", ".bin"} {
+		if err := os.Remove(filepath.Join(out, "firmware"+ext)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return copyFile(image, filepath.Join(out, "firmware"+filepath.Ext(image)))
+}
+
+// setUploadSpeed sets upload_speed in the generated platformio.ini, or
+// drops it when baud is 0. It is the last line, after any lib_deps list.
+func setUploadSpeed(ini string, baud int) error {
+	data, err := os.ReadFile(ini)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if !strings.HasPrefix(line, "upload_speed") {
+			lines = append(lines, line)
+		}
+	}
+	if baud > 0 {
+		lines = append(lines, fmt.Sprintf("upload_speed = %d", baud))
+	}
+	return os.WriteFile(ini, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+func (p *platformIO) DetectPorts() ([]Port, error) {
+	out, err := exec.Command(p.bin, "device", "list", "--serial", "--json-output").Output()
+	if err != nil {
+		return nil, fmt.Errorf("pio device list failed: %w", err)
+	}
+	var devices []struct {
+		Port        string `json:"port"`
+		Description string `json:"description"`
+		HWID        string `json:"hwid"`
+	}
+	if err := json.Unmarshal(out, &devices); err != nil {
+		return nil, fmt.Errorf("reading pio device list: %w", err)
+	}
+	var ports []Port
+	for _, d := range devices {
+		// Built-in serial ports (no USB device behind them) report "n/a".
+		if d.HWID == "n/a" {
+			continue
+		}
+		desc := d.Description
+		if desc == "n/a" {
+			desc = ""
+		}
+		ports = append(ports, Port{Address: d.Port, Board: desc})
+	}
+	return ports, nil
+}
+
+// pioSpec is a PlatformIO package spec: name, or name@version for an exact
+// release.
+func pioSpec(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+// InstallLibrary installs into PlatformIO's global storage. Builds do not
+// need it — they fetch their lib_deps — but it resolves the version that
+// `tsuki lib install` pins.
+func (p *platformIO) InstallLibrary(name, version string) error {
+	spec := pioSpec(name, version)
+	if err := stream(p.bin, "pkg", "install", "--global", "--library", spec); err != nil {
+		return fmt.Errorf("pio pkg install --library %q failed: %w", spec, err)
+	}
+	return nil
+}
+
+// FetchesLibraries reports that every build installs its lib_deps.
+func (p *platformIO) FetchesLibraries() bool { return true }
+
+// Libraries reads the package manifest (.piopm) of each library in the
+// global storage, <PlatformIO home>/lib.
+func (p *platformIO) Libraries() ([]Library, error) {
+	manifests, _ := filepath.Glob(filepath.Join(pioHome(), "lib", "*", ".piopm"))
+	libs := []Library{}
+	for _, path := range manifests {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var pm struct {
+			Type    string `json:"type"`
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(data, &pm); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if pm.Type == "library" {
+			libs = append(libs, Library{Name: pm.Name, Version: pm.Version})
+		}
+	}
+	return libs, nil
+}
+
+// SearchLibraries reads `pio pkg search`, which prints each hit as
+//
+//	owner/Name
+//	Library • 1.2.3 • Published on …
+//	Description
+func (p *platformIO) SearchLibraries(query string) ([]Library, error) {
+	out, err := exec.Command(p.bin, "pkg", "search", "type:library "+query).Output()
+	if err != nil {
+		return nil, fmt.Errorf("pio pkg search failed: %w", err)
+	}
+	lines := strings.Split(string(out), "\n")
+	var libs []Library
+	for i := 1; i < len(lines); i++ {
+		fields := strings.Split(lines[i], " • ")
+		if len(fields) < 2 || strings.TrimSpace(fields[0]) != "Library" {
+			continue
+		}
+		name := strings.TrimSpace(lines[i-1])
+		if _, n, ok := strings.Cut(name, "/"); ok {
+			name = n
+		}
+		lib := Library{Name: name, Version: strings.TrimSpace(fields[1])}
+		if i+1 < len(lines) {
+			lib.Description = strings.TrimSpace(lines[i+1])
+		}
+		libs = append(libs, lib)
+	}
+	return libs, nil
+}
+
+func (p *platformIO) RemoveLibrary(name string) error {
+	if err := stream(p.bin, "pkg", "uninstall", "--global", "--library", name); err != nil {
+		return fmt.Errorf("pio pkg uninstall --library %q failed: %w", name, err)
+	}
+	return nil
+}
+
+// InstallCore installs the board's development platform. `pio run` would
+// fetch it too, but silently behind the compile spinner.
+func (p *platformIO) InstallCore(board string) error {
+	_, b, err := pioEnv(board)
+	if err != nil {
+		return err
+	}
+	if PlatformIOInstalled(b.platform) {
+		return nil
+	}
+	ui.SectionTitle(fmt.Sprintf("Installing %s platform  [platformio]", b.platform))
+	if err := stream(p.bin, "pkg", "install", "--global", "--platform", b.platform); err != nil {
+		return fmt.Errorf("pio pkg install --platform %s failed: %w", b.platform, err)
+	}
+	ui.Success(fmt.Sprintf("%s platform installed", b.platform))
+	return nil
+}
+
+func (p *platformIO) Core(board string) (Core, error) {
+	platform, err := PlatformIOPlatform(board)
+	if err != nil {
+		return Core{}, err
+	}
+	if c, ok := pioCore(platform); ok {
+		return c, nil
+	}
+	return Core{ID: platform}, nil
+}
+
+// Cores reads the package manifest (.piopm) of each installed platform.
+func (p *platformIO) Cores() ([]Core, error) {
+	dirs, _ := filepath.Glob(filepath.Join(pioHome(), "platforms", "*"))
+	cores := []Core{}
+	for _, dir := range dirs {
+		if c, ok := pioCore(filepath.Base(dir)); ok {
+			cores = append(cores, c)
+		}
+	}
+	return cores, nil
+}
+
+func pioCore(platform string) (Core, bool) {
+	dir := filepath.Join(pioHome(), "platforms", platform)
+	if _, err := os.Stat(dir); err != nil {
+		return Core{}, false
+	}
+	c := Core{ID: platform}
+	// Platforms installed by older PlatformIO releases have no .piopm.
+	for _, name := range []string{".piopm", "platform.json"} {
+		var pm struct {
+			Version string `json:"version"`
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil && json.Unmarshal(data, &pm) == nil && pm.Version != "" {
+			c.Version = pm.Version
+			break
+		}
+	}
+	c.Version = or(c.Version, "unknown")
+	return c, true
+}
+
+func (p *platformIO) InstallCoreVersion(id, version string) error {
+	if err := stream(p.bin, "pkg", "install", "--global", "--platform", id+"@"+version); err != nil {
+		return fmt.Errorf("pio pkg install --platform %s@%s failed: %w", id, version, err)
+	}
+	return nil
+}
+
+func (p *platformIO) UpdateCore(id string) error {
+	if err := stream(p.bin, "pkg", "update", "--global", "--platform", id); err != nil {
+		return fmt.Errorf("pio pkg update --platform %s failed: %w", id, err)
+	}
+	return nil
+}
+
+func (p *platformIO) RemoveCore(id string) error {
+	if err := stream(p.bin, "pkg", "uninstall", "--global", "--platform", id); err != nil {
+		return fmt.Errorf("pio pkg uninstall --platform %s failed: %w", id, err)
+	}
+	return nil
+}
+
+// PlatformIOPlatform returns the PlatformIO development platform a board
+// needs, e.g. "atmelavr".
+func PlatformIOPlatform(board string) (string, error) {
+	_, b, err := pioEnv(board)
+	return b.platform, err
+}
+
+// PlatformIOInstalled reports whether a development platform is in
+// PlatformIO's home.
+func PlatformIOInstalled(platform string) bool {
+	_, err := os.Stat(filepath.Join(pioHome(), "platforms", platform))
+	return err == nil
+}
+
+// pioHome is $PLATFORMIO_CORE_DIR, or ~/.platformio.
+func pioHome() string {
+	if dir := os.Getenv("PLATFORMIO_CORE_DIR"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".platformio")
+}
@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWritePIOProject(t *testing.T) {
+	dir := t.TempDir()
+	env, b, err := pioEnv("Nano")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := CompileRequest{
+		Board:      "nano",
+		CppStd:     "c++17",
+		Optimize:   "O2",
+		ExtraFlags: []string{"-DDEBUG=1"},
+		Includes:   []string{"/libs/ws2812/1.0.0", "/my libs/dht/2.0.0"},
+		Libraries: []Library{
+			{Name: "Adafruit NeoPixel", Version: "1.12.0"},
+			{Name: "DHT sensor library"},
+		},
+	}
+	if err := writePIOProject(dir, env, b, req); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "platformio.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `; Generated by tsuki — do not edit, it is rewritten on every build.
+
+[env:nano]
+platform = atmelavr
+board = nanoatmega328
+framework = arduino
+build_unflags = -std=gnu++11 -std=gnu++14 -std=gnu++17 -Os
+build_flags = -std=gnu++17 -O2 -DDEBUG=1 -I/libs/ws2812/1.0.0 "-I/my libs/dht/2.0.0"
+lib_deps =
+    Adafruit NeoPixel@1.12.0
+    DHT sensor library
+`
+	if string(got) != want {
+		t.Errorf("platformio.ini:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWritePIOProjectDefaults(t *testing.T) {
+	dir := t.TempDir()
+	env, b, err := pioEnv("esp32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writePIOProject(dir, env, b, CompileRequest{Board: "esp32"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "platformio.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"build_unflags", "build_flags", "lib_deps"} {
+		if strings.Contains(string(got), key) {
+			t.Errorf("platformio.ini sets %s without settings that need it:\n%s", key, got)
+		}
+	}
+	if !strings.Contains(string(got), "[env:esp32]\nplatform = espressif32\nboard = esp32dev\n") {
+		t.Errorf("platformio.ini has the wrong environment:\n%s", got)
+	}
+}
+
+func TestPIOEnvUnknownBoard(t *testing.T) {
+	if _, _, err := pioEnv("arduino101"); err == nil {
+		t.Error("pioEnv accepted a board without a PlatformIO mapping")
+	}
+}
+
+// fakePIO records its arguments in $TSUKI_FAKE_PIO_ARGS and leaves a
+// firmware image where `pio run` would.
+const fakePIO = `#!/bin/sh
+printf '%s\n' "$@" > "$TSUKI_FAKE_PIO_ARGS"
+mkdir -p "$3/.pio/build/$5"
+echo ':00000001FF' > "$3/.pio/build/$5/firmware.hex"
+echo 'elf' > "$3/.pio/build/$5/firmware.elf"
+`
+
+func TestPlatformIOCompile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake pio is a shell script")
+	}
+	tmp := t.TempDir()
+	argsFile := filepath.Join(tmp, "args")
+	t.Setenv("TSUKI_FAKE_PIO_ARGS", argsFile)
+	bin := filepath.Join(tmp, "pio")
+	if err := os.WriteFile(bin, []byte(fakePIO), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sketch := filepath.Join(tmp, "sketch")
+	if err := os.MkdirAll(sketch, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range map[string]string{
+		"blink.ino": "",
+		"main.cpp":  "void setup() {}\nvoid loop() {}\n",
+		"notes.txt": "not a source",
+	} {
+		if err := os.WriteFile(filepath.Join(sketch, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buildDir := filepath.Join(tmp, "build")
+
+	b, err := New("platformio", Tools{PlatformIO: bin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := b.Compile(CompileRequest{Board: "uno", SketchDir: sketch, BuildDir: buildDir, Name: "blink"}); err != nil {
+		t.Fatalf("Compile: %v\n%s", err, out)
+	}
+
+	proj := pioProject(buildDir)
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "run\n-d\n" + proj + "\n-e\nuno\n"; string(args) != want {
+		t.Errorf("pio args = %q, want %q", args, want)
+	}
+	if _, err := os.Stat(filepath.Join(proj, "src", "main.cpp")); err != nil {
+		t.Errorf("main.cpp not copied into the project: %v", err)
+	}
+	// The .ino stub only exists for arduino-cli.
+	for _, name := range []string{"blink.ino", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(proj, "src", name)); err == nil {
+			t.Errorf("%s copied into the project", name)
+		}
+	}
+	for _, name := range []string{"blink.hex", "blink.elf"} {
+		if _, err := os.Stat(filepath.Join(buildDir, name)); err != nil {
+			t.Errorf("%s not brought back to the build dir: %v", name, err)
+		}
+	}
+}
+
+// An upload flashes the build dir's current image, not one a previous
+// upload left in the PlatformIO project, nor a bootloader next to it.
+func TestPlatformIOUploadCopiesCurrentImage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake pio is a shell script")
+	}
+	tmp := t.TempDir()
+	argsFile := filepath.Join(tmp, "args")
+	t.Setenv("TSUKI_FAKE_PIO_ARGS", argsFile)
+	bin := filepath.Join(tmp, "pio")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > \"$TSUKI_FAKE_PIO_ARGS\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	buildDir := filepath.Join(tmp, "build")
+	out := filepath.Join(pioProject(buildDir), ".pio", "build", "uno")
+	for path, data := range map[string]string{
+		filepath.Join(buildDir, "blink.ino.bootloader.bin"): "bootloader",
+		filepath.Join(buildDir, "blink.hex"):                ":new",
+		filepath.Join(out, "firmware.hex"):                  ":stale",
+		filepath.Join(out, "firmware.bin"):                  "stale",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := New("platformio", Tools{PlatformIO: bin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := b.Upload(UploadRequest{Board: "uno", Port: "/dev/ttyACM0", BuildDir: buildDir, Name: "blink"}); err != nil {
+		t.Fatalf("Upload: %v\n%s", err, out)
+	}
+
+	if got, err := os.ReadFile(filepath.Join(out, "firmware.hex")); err != nil || string(got) != ":new" {
+		t.Errorf("firmware.hex = %q, %v; want the build dir's image", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "firmware.bin")); err == nil {
+		t.Error("the stale firmware.bin was kept")
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "-t\nnobuild\n-t\nupload\n--upload-port\n/dev/ttyACM0\n") {
+		t.Errorf("pio args = %q", args)
+	}
+}
@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ── Build settings ────────────────────────────────────────────────────────────
+//
+// The manifest's build section and the upload options are backend-neutral;
+// each backend translates them into its own flags:
+//
+//   setting      arduino-cli                         tsuki-flash     platformio
+//   cpp_std      compiler.cpp.extra_flags=-std=…     --cpp-std       build_flags -std=…
+//   optimize     compiler.*.extra_flags=-O…          --optimize      build_flags -O…
+//   extra_flags  compiler.{c,cpp}.extra_flags        --flag          build_flags
+//   upload_baud  --upload-property upload.speed=…    upload --baud   upload_speed
+//
+// External templates honour a setting when their command uses its
+// placeholder. A setting a backend ignores is reported by Ignored, so the
+// build can warn instead of silently producing a different firmware.
+
+// Setting names, as they appear in tsuki_package.json.
+const (
+	SettingCppStd     = "cpp_std"
+	SettingOptimize   = "optimize"
+	SettingExtraFlags = "extra_flags"
+	SettingUploadBaud = "upload_baud"
+)
+
+// SettingsHonourer is implemented by backends that can tell which settings
+// they translate. Backends without it are assumed to honour all of them.
+type SettingsHonourer interface {
+	Honours(setting string) bool
+}
+
+// Settings returns the settings req sets, with their values.
+func (req CompileRequest) Settings() map[string]string {
+	set := map[string]string{}
+	if req.CppStd != "" {
+		set[SettingCppStd] = req.CppStd
+	}
+	if req.Optimize != "" {
+		set[SettingOptimize] = req.Optimize
+	}
+	if len(req.ExtraFlags) > 0 {
+		set[SettingExtraFlags] = strings.Join(req.ExtraFlags, " ")
+	}
+	return set
+}
+
+// Settings returns the settings req sets, with their values.
+func (req UploadRequest) Settings() map[string]string {
+	set := map[string]string{}
+	if req.Baud > 0 {
+		set[SettingUploadBaud] = strconv.Itoa(req.Baud)
+	}
+	return set
+}
+
+// Ignored returns a description of each setting in set that b does not
+// honour, e.g. `optimize = "O2"`, sorted by setting name.
+func Ignored(b Backend, set map[string]string) []string {
+	h, ok := b.(SettingsHonourer)
+	if !ok {
+		return nil
+	}
+	var ignored []string
+	for _, name := range []string{SettingCppStd, SettingOptimize, SettingExtraFlags, SettingUploadBaud} {
+		if v, ok := set[name]; ok && !h.Honours(name) {
+			ignored = append(ignored, fmt.Sprintf("%s = %q", name, v))
+		}
+	}
+	return ignored
+}
+
+// optimizeFlag is the compiler flag for an optimisation level given as
+// "Os" or "-Os".
+func optimizeFlag(level string) string {
+	return "-" + strings.TrimPrefix(level, "-")
+}
+
+// stdFlag is the -std flag for a C++ standard given as "c++17" or
+// "gnu++17". Arduino code relies on GNU extensions, so c++NN maps to gnu++NN
+// as tsuki-flash does.
+func stdFlag(std string) string {
+	return "-std=gnu++" + strings.TrimPrefix(strings.TrimPrefix(std, "gnu++"), "c++")
+}
@@ -0,0 +1,312 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// ── Backend: tsuki-flash ──────────────────────────────────────────────────────
+//
+// tsuki-flash takes its SDK from TSUKI_SDK_ROOT, tsuki-modules or .arduino15.
+// tsuki-flash+cores is the fully standalone variant: it only ever uses the
+// tsuki-modules store, installing a board family's core on first use.
+
+func init() {
+	Register("tsuki-flash", func(t Tools) Backend {
+		return &tsukiFlash{bin: or(t.FlashBinary, "tsuki-flash")}
+	})
+	Register("tsuki-flash+cores", func(t Tools) Backend {
+		return &tsukiFlash{bin: or(t.FlashBinary, "tsuki-flash"), modules: true}
+	})
+}
+
+type tsukiFlash struct {
+	bin     string
+	modules bool
+}
+
+func (f *tsukiFlash) Name() string {
+	if f.modules {
+		return "tsuki-flash+cores"
+	}
+	return "tsuki-flash"
+}
+
+func (f *tsukiFlash) Compile(req CompileRequest) ([]byte, error) {
+	cppStd := req.CppStd
+	if cppStd == "" {
+		cppStd = "c++11"
+	}
+	// tsuki-flash takes c++NN and always compiles with GNU extensions.
+	cppStd = "c++" + strings.TrimPrefix(strings.TrimPrefix(cppStd, "gnu++"), "c++")
+	args := []string{
+		"compile",
+		"--board", req.Board,
+		"--sketch", req.SketchDir,
+		"--build-dir", req.BuildDir,
+		"--name", req.Name,
+		"--cpp-std", cppStd,
+	}
+	if req.Optimize != "" {
+		args = append(args, "--optimize", strings.TrimPrefix(req.Optimize, "-"))
+	}
+	for _, flag := range req.ExtraFlags {
+		// = keeps clap from reading "-DFOO" as an option of its own.
+		args = append(args, "--flag="+flag)
+	}
+	for _, inc := range req.Includes {
+		args = append(args, "--include", inc)
+	}
+	if req.Verbose {
+		args = append(args, "--verbose")
+	}
+	if f.modules {
+		// Use ~/.tsuki/modules as the SDK root instead of .arduino15.
+		args = append(args, "--use-modules")
+	}
+	return exec.Command(f.bin, args...).CombinedOutput()
+}
+
+// CompileCommands reads the compilation database tsuki-flash writes into
+// the build dir, which already names the sketch's own files.
+func (f *tsukiFlash) CompileCommands(req CompileRequest) ([]CompileCommand, error) {
+	return readCompileDB(filepath.Join(req.BuildDir, CompileDBFile), "", req.SketchDir)
+}
+
+func (f *tsukiFlash) Upload(req UploadRequest) ([]byte, error) {
+	argv, err := f.UploadCommand(req)
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(f.bin, argv[1:]...).CombinedOutput()
+}
+
+func (f *tsukiFlash) UploadCommand(req UploadRequest) ([]string, error) {
+	args := []string{
+		filepath.Base(f.bin), "upload",
+		"--board", req.Board,
+		"--port", req.Port,
+		"--build-dir", req.BuildDir,
+	}
+	// tsuki-flash looks for firmware.hex unless told the sketch's name.
+	if req.Name != "" {
+		args = append(args, "--name", req.Name)
+	}
+	if req.Baud > 0 {
+		args = append(args, "--baud", strconv.Itoa(req.Baud))
+	}
+	if req.Verbose {
+		args = append(args, "--verbose")
+	}
+	return args, nil
+}
+
+func (f *tsukiFlash) DetectPorts() ([]Port, error) {
+	out, err := exec.Command(f.bin, "detect").Output()
+	if err != nil {
+		return nil, fmt.Errorf("tsuki-flash detect failed: %w", err)
+	}
+	return parsePorts(string(out)), nil
+}
+
+func (f *tsukiFlash) InstallLibrary(name, version string) error {
+	args := []string{"lib", "install", name}
+	if version != "" {
+		args = append(args, "--version", version)
+	}
+	if err := stream(f.bin, args...); err != nil {
+		return fmt.Errorf("tsuki-flash lib install %q failed: %w", name, err)
+	}
+	return nil
+}
+
+func (f *tsukiFlash) Libraries() ([]Library, error) {
+	return f.libraryJSON("list", "--json")
+}
+
+func (f *tsukiFlash) SearchLibraries(query string) ([]Library, error) {
+	return f.libraryJSON("search", query, "--json")
+}
+
+func (f *tsukiFlash) RemoveLibrary(name string) error {
+	if err := stream(f.bin, "lib", "remove", name); err != nil {
+		return fmt.Errorf("tsuki-flash lib remove %q failed: %w", name, err)
+	}
+	return nil
+}
+
+// libraryJSON runs a `tsuki-flash lib` listing that prints JSON.
+func (f *tsukiFlash) libraryJSON(args ...string) ([]Library, error) {
+	out, err := exec.Command(f.bin, append([]string{"lib"}, args...)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("tsuki-flash lib %s failed: %w", args[0], err)
+	}
+	var libs []Library
+	if err := json.Unmarshal(out, &libs); err != nil {
+		return nil, fmt.Errorf("reading tsuki-flash lib %s: %w", args[0], err)
+	}
+	return libs, nil
+}
+
+// InstallCore installs the board's core into tsuki-modules. Plain
+// tsuki-flash only needs it when no other SDK is found; sdk-info resolves
+// the SDK exactly as compile does.
+func (f *tsukiFlash) InstallCore(board string) error {
+	arch, err := Arch(board)
+	if err != nil {
+		return err
+	}
+	if !f.modules && exec.Command(f.bin, "sdk-info", board).Run() == nil {
+		return nil
+	}
+	return EnsureModules(f.bin, arch)
+}
+
+// Core reports the tsuki-modules core for board. Plain tsuki-flash may
+// compile against another SDK instead; sdk-info names its version.
+func (f *tsukiFlash) Core(board string) (Core, error) {
+	arch, err := Arch(board)
+	if err != nil {
+		return Core{}, err
+	}
+	if c, ok := ModulesCore(arch); ok {
+		return c, nil
+	}
+	if !f.modules {
+		if out, err := exec.Command(f.bin, "sdk-info", board).Output(); err == nil {
+			// sdk-info succeeding means the core is there, named or not.
+			return Core{ID: arch, Version: or(sdkVersion(string(out)), "unknown")}, nil
+		}
+	}
+	return Core{ID: arch}, nil
+}
+
+// sdkVersion picks the version out of sdk-info's "✓ SDK found  (1.8.6)".
+func sdkVersion(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.Contains(line, "SDK found") {
+			continue
+		}
+		if i, j := strings.LastIndex(line, "("), strings.LastIndex(line, ")"); i >= 0 && j > i {
+			return line[i+1 : j]
+		}
+	}
+	return ""
+}
+
+func (f *tsukiFlash) Cores() ([]Core, error) {
+	return ModulesCores()
+}
+
+func (f *tsukiFlash) InstallCoreVersion(id, version string) error {
+	if err := stream(f.bin, "modules", "install", id, "--version", version); err != nil {
+		return fmt.Errorf("tsuki-flash modules install %s --version %s failed: %w", id, version, err)
+	}
+	return nil
+}
+
+// UpdateCore refreshes the package index and installs the newest release;
+// the old version stays on disk until the core is removed.
+func (f *tsukiFlash) UpdateCore(id string) error {
+	if err := stream(f.bin, "modules", "update"); err != nil {
+		return fmt.Errorf("tsuki-flash modules update failed: %w", err)
+	}
+	return InstallModules(f.bin, id)
+}
+
+func (f *tsukiFlash) RemoveCore(id string) error {
+	if err := stream(f.bin, "modules", "remove", id); err != nil {
+		return fmt.Errorf("tsuki-flash modules remove %s failed: %w", id, err)
+	}
+	return nil
+}
+
+// ── tsuki-modules ─────────────────────────────────────────────────────────────
+//
+// A core counts as installed once tsuki-flash has written
+// installed/<arch>.json, exactly as `tsuki-flash modules list` sees it.
+
+// ModulesRoot is the tsuki-modules store: $TSUKI_MODULES_ROOT, or
+// ~/.tsuki/modules.
+func ModulesRoot() string {
+	if root := os.Getenv("TSUKI_MODULES_ROOT"); root != "" {
+		return root
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".tsuki", "modules")
+}
+
+// ModulesInstalled reports whether the core for arch is in the store.
+func ModulesInstalled(arch string) bool {
+	_, err := os.Stat(filepath.Join(ModulesRoot(), "installed", arch+".json"))
+	return err == nil
+}
+
+// ModulesCore reads the manifest tsuki-flash wrote for arch's core.
+func ModulesCore(arch string) (Core, bool) {
+	data, err := os.ReadFile(filepath.Join(ModulesRoot(), "installed", arch+".json"))
+	if err != nil {
+		return Core{}, false
+	}
+	var m struct {
+		Arch    string `json:"arch"`
+		Version string `json:"version"`
+	}
+	if json.Unmarshal(data, &m) != nil {
+		return Core{}, false
+	}
+	return Core{ID: arch, Version: or(m.Version, "unknown")}, true
+}
+
+// ModulesCores lists the cores in the tsuki-modules store.
+func ModulesCores() ([]Core, error) {
+	paths, err := filepath.Glob(filepath.Join(ModulesRoot(), "installed", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	cores := []Core{}
+	for _, p := range paths {
+		if c, ok := ModulesCore(strings.TrimSuffix(filepath.Base(p), ".json")); ok {
+			cores = append(cores, c)
+		}
+	}
+	return cores, nil
+}
+
+// InstallModules runs `tsuki-flash modules install <arch>`, streaming its
+// download progress to the terminal.
+func InstallModules(flashBin, arch string) error {
+	flashBin = or(flashBin, "tsuki-flash")
+	if err := stream(flashBin, "modules", "install", arch); err != nil {
+		return fmt.Errorf("installing the %s core failed: %w\n  Retry with: %s modules install %s",
+			arch, err, filepath.Base(flashBin), arch)
+	}
+	return nil
+}
+
+// EnsureModules installs the core for arch unless it is already in the
+// store. The first build for a board family downloads its SDK and
+// toolchain, so the progress is shown rather than hidden behind a spinner.
+func EnsureModules(flashBin, arch string) error {
+	if ModulesInstalled(arch) {
+		return nil
+	}
+	ui.SectionTitle(fmt.Sprintf("Installing %s core  [tsuki-modules]", arch))
+	ui.Info(fmt.Sprintf("%s core not found in %s — downloading it once", arch, ModulesRoot()))
+	if err := InstallModules(flashBin, arch); err != nil {
+		ui.Fail(fmt.Sprintf("%s core not installed", arch))
+		return err
+	}
+	if !ModulesInstalled(arch) {
+		return fmt.Errorf("tsuki-flash modules install %s finished but %s has no %s core", arch, ModulesRoot(), arch)
+	}
+	ui.Success(fmt.Sprintf("%s core installed", arch))
+	return nil
+}
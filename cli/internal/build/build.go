@@ -10,46 +10,88 @@
 //    2. Generate              build/<project-name>/<project-name>.ino
 //    3. Pass the sketch dir   build/<project-name>/   to arduino-cli
 //    4. Cache .hex/.elf into  build/.cache/
+//    5. Write                 build/.cache/<name>.buildinfo.json
 // ─────────────────────────────────────────────────────────────────────────────
 
-package cli
+package build
 
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
-	"github.com/spf13/cobra"
+	"github.com/tsuki/cli/internal/backend"
 	"github.com/tsuki/cli/internal/core"
+	"github.com/tsuki/cli/internal/firmware"
+	"github.com/tsuki/cli/internal/host"
 	"github.com/tsuki/cli/internal/manifest"
 	"github.com/tsuki/cli/internal/pkgmgr"
+	"github.com/tsuki/cli/internal/toolchain"
 	"github.com/tsuki/cli/internal/ui"
 )
 
 // Options controls the build pipeline.
 type Options struct {
-	Board       string
-	Compile     bool
-	OutputDir   string
-	SourceMap   bool
-	Verbose     bool
-	CoreBin     string
-	ArduinoCLI  string
-	// FlashBinary is the path to tsuki-flash (used when Backend == "tsuki-flash").
+	Board      string
+	Compile    bool
+	OutputDir  string
+	SourceMap  bool
+	Verbose    bool
+	CoreBin    string
+	ArduinoCLI string
+	// FlashBinary is the path to tsuki-flash (used by the tsuki-flash backends).
 	FlashBinary string
-	// Backend selects the compiler: "tsuki-flash" or "arduino-cli".
-	// Defaults to "arduino-cli" if empty.
-	Backend     string
+	// PlatformIO is the path to pio (used by the platformio backend).
+	PlatformIO string
+	// Backend names the compiler — a registered backend or an external
+	// template (see backend.New). Callers resolve it with backend.Resolve;
+	// empty means backend.Default.
+	Backend string
+	// Profile labels the build (e.g. "release", "debug") in buildinfo.Profile
+	// and the build-info file. Defaults to "release".
+	Profile string
+	// Defines are extra buildinfo string constants, already merged with the
+	// manifest's build.defines (see MergeDefines).
+	Defines map[string]string
+	// Transpiler converts the sources. Nil means core.New(CoreBin, Verbose);
+	// Run closes the ones it creates.
+	Transpiler core.Transpiler
+	// Target is what the sketch is compiled for: TargetBoard (the default)
+	// through the backend, or TargetHost with HostCompiler against the
+	// simulated Arduino API, which implies Compile.
+	Target       string
+	HostCompiler string
+	// Tests adds src/*_test.go to a TargetHost build and compiles a test
+	// executable instead of the sketch's own (see host.WriteTestMain).
+	// Without test functions it builds nothing and returns an empty Result.
+	Tests bool
 }
 
 // Result holds the outputs of a successful build.
 type Result struct {
-	CppFiles    []string
-	SketchDir   string // path to the generated Arduino sketch dir
-	FirmwareHex string
-	Warnings    []string
+	CppFiles    []string `json:"cpp_files"`
+	SketchDir   string   `json:"sketch_dir"` // path to the generated Arduino sketch dir
+	FirmwareHex string   `json:"firmware_hex"`
+	// Firmware is the application image (.hex or .bin) after --compile.
+	Firmware  string `json:"firmware"`
+	ELF       string `json:"elf"`
+	BuildInfo string `json:"build_info"` // path to the build-info JSON written next to Firmware
+	// Host is the executable of a TargetHost build.
+	Host string `json:"host,omitempty"`
+	// Tests are the test functions compiled into Host by a Tests build.
+	Tests []host.TestFunc `json:"tests,omitempty"`
+	// Core is the board core compiled against, "id@version", when the
+	// backend reports it.
+	Core string `json:"core,omitempty"`
+	// CompileDB is the compile_commands.json written into SketchDir, when
+	// the backend reports its compiler invocations.
+	CompileDB string   `json:"compile_db,omitempty"`
+	Warnings  []string `json:"warnings"`
+	// Diagnostics are the located problems reported while building: the
+	// core's for each source file and, after a failed --compile, the
+	// compiler errors. They are kept on failure too, for CI reports.
+	Diagnostics []core.Diagnostic `json:"diagnostics"`
 }
 
 // Run executes the full build pipeline.
@@ -58,6 +100,19 @@ func Run(projectDir string, m *manifest.Manifest, opts Options) (*Result, error)
 	if board == "" {
 		board = m.Board
 	}
+	if opts.Tests && opts.Target == "" {
+		opts.Target = TargetHost
+	}
+	switch opts.Target {
+	case "", TargetBoard:
+		if opts.Tests {
+			return nil, fmt.Errorf("tests run on the host, not on a board")
+		}
+	case TargetHost:
+		opts.Compile = false // the backend is not involved
+	default:
+		return nil, fmt.Errorf("unknown target %q (want %s or %s)", opts.Target, TargetBoard, TargetHost)
+	}
 
 	// Base build directory: <project>/build/
 	baseOutDir := opts.OutputDir
@@ -68,33 +123,86 @@ func Run(projectDir string, m *manifest.Manifest, opts Options) (*Result, error)
 	// ── Arduino sketch directory ─────────────────────────────────────────────
 	// arduino-cli compile requires a sketch directory whose name matches the
 	// .ino file inside it:  build/<name>/<name>.ino
-	sketchName := sanitizeSketchName(m.Name)
-	if sketchName == "" {
-		sketchName = "sketch"
-	}
+	sketchName := SketchName(m.Name)
 	sketchDir := filepath.Join(baseOutDir, sketchName)
+	if opts.Tests {
+		// Kept apart, so the test sources never reach a board build.
+		sketchDir = filepath.Join(baseOutDir, "test", sketchName)
+	}
 
 	if err := os.MkdirAll(sketchDir, 0755); err != nil {
 		return nil, fmt.Errorf("creating sketch dir: %w", err)
 	}
 
-	transpiler := core.New(opts.CoreBin, opts.Verbose)
+	// Tools pinned in the manifest replace the configured ones.
+	pinned, err := toolchain.Resolve(m.Toolchain)
+	if err != nil {
+		return nil, err
+	}
+	pinned.Apply(&opts.CoreBin, &opts.FlashBinary, &opts.ArduinoCLI)
+
+	transpiler := opts.Transpiler
+	if transpiler == nil {
+		transpiler = core.New(opts.CoreBin, opts.Verbose)
+		defer transpiler.Close()
+	}
 	if !transpiler.Installed() {
 		return nil, fmt.Errorf(
-			"tsuki-core not found — install it or set core_binary in config\n"+
+			"tsuki-core not found — install it or set core_binary in config\n" +
 				"  tsuki config set core_binary /path/to/tsuki-core",
 		)
 	}
 
+	// Resolve the backend up front: a typo in its name should not cost a
+	// transpile.
+	var b backend.Backend
+	if opts.Compile {
+		var err error
+		b, err = backend.New(opts.Backend, backend.Tools{
+			ArduinoCLI:  opts.ArduinoCLI,
+			FlashBinary: opts.FlashBinary,
+			PlatformIO:  opts.PlatformIO,
+			ProjectDir:  projectDir,
+			Verbose:     opts.Verbose,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Likewise a missing Arduino library, which would otherwise surface as
+	// an #include error from the compiler.
+	libraries := RequiredLibraries(m)
+	if opts.Compile {
+		missing, err := CheckLibraries(b, libraries)
+		if err != nil {
+			ui.Warn(fmt.Sprintf("could not check Arduino libraries: %v", err))
+		} else if len(missing) > 0 {
+			return nil, missingLibrariesError(missing)
+		}
+	}
+
 	srcDir := filepath.Join(projectDir, "src")
-	goFiles, err := filepath.Glob(filepath.Join(srcDir, "*.go"))
+	goFiles, testFiles, err := SourceFiles(srcDir)
 	if err != nil || len(goFiles) == 0 {
 		return nil, fmt.Errorf("no .go files found in %s", srcDir)
 	}
+	var tests []host.TestFunc
+	if opts.Tests {
+		if tests, err = host.DiscoverTests(testFiles); err != nil {
+			return nil, err
+		}
+		if len(tests) == 0 {
+			// Nothing to build: the caller reports "no test files".
+			return &Result{}, nil
+		}
+		goFiles = append(goFiles, testFiles...)
+		opts.SourceMap = true // failures are reported at their Go line
+	}
 
 	// Resolve declared packages
 	pkgNames := m.PackageNames()
-	libsDir  := pkgmgr.LibsDir()
+	libsDir := pkgmgr.LibsDir()
 
 	if len(pkgNames) > 0 {
 		ui.SectionTitle(fmt.Sprintf("Transpiling  [board: %s]  [packages: %s]",
@@ -111,31 +219,60 @@ func Run(projectDir string, m *manifest.Manifest, opts Options) (*Result, error)
 		ui.SectionTitle(fmt.Sprintf("Transpiling  [board: %s]", board))
 	}
 
-	result := &Result{SketchDir: sketchDir}
-
-	for _, goFile := range goFiles {
-		base    := strings.TrimSuffix(filepath.Base(goFile), ".go")
-		cppFile := filepath.Join(sketchDir, base+".cpp") // write INTO sketch dir
+	// The transpiler sees the installed packages plus the generated
+	// buildinfo package (see buildinfo.go).
+	meta := ProjectMetadata(projectDir, m, board, opts.Profile)
+	defines := opts.Defines
+	if defines == nil {
+		if defines, err = MergeDefines(m.Build.Defines, nil); err != nil {
+			return nil, err
+		}
+	}
+	coreLibsDir, corePkgNames, err := CoreLibs(baseOutDir, meta, defines, libsDir, pkgNames)
+	if err != nil {
+		return nil, err
+	}
 
-		sp := ui.NewSpinner(fmt.Sprintf("%s → %s", filepath.Base(goFile), filepath.Base(cppFile)))
-		sp.Start()
+	result := &Result{
+		SketchDir:   sketchDir,
+		Tests:       tests,
+		CppFiles:    []string{},
+		Warnings:    []string{},
+		Diagnostics: []core.Diagnostic{},
+	}
 
-		tr, err := transpiler.Transpile(core.TranspileRequest{
+	reqs := make([]core.TranspileRequest, 0, len(goFiles))
+	for _, goFile := range goFiles {
+		base := strings.TrimSuffix(filepath.Base(goFile), ".go")
+		reqs = append(reqs, core.TranspileRequest{
 			InputFile:  goFile,
-			OutputFile: cppFile,
+			OutputFile: filepath.Join(sketchDir, base+".cpp"), // write INTO sketch dir
 			Board:      board,
 			SourceMap:  opts.SourceMap || m.Build.SourceMap,
-			LibsDir:    libsDir,
-			PkgNames:   pkgNames,
+			LibsDir:    coreLibsDir,
+			PkgNames:   corePkgNames,
 		})
-		if err != nil {
-			sp.Stop(false, fmt.Sprintf("failed: %s", filepath.Base(goFile)))
-			return nil, err
-		}
+	}
 
-		sp.Stop(true, fmt.Sprintf("%s  →  %s", filepath.Base(goFile), filepath.Base(cppFile)))
+	sp := ui.NewSpinner(fmt.Sprintf("transpiling %d file(s)", len(reqs)))
+	sp.Start()
+	trs, err := transpiler.Transpile(reqs)
+	if err != nil {
+		sp.Stop(false, err.Error())
+	} else {
+		sp.Stop(true, fmt.Sprintf("%d file(s) transpiled", len(reqs)))
+	}
+	for _, tr := range trs {
+		result.Diagnostics = append(result.Diagnostics, tr.Diagnostics...)
+		if tr.Err != nil {
+			continue
+		}
+		ui.Step("transpile", fmt.Sprintf("%s  →  %s", filepath.Base(tr.InputFile), filepath.Base(tr.OutputFile)))
 		result.CppFiles = append(result.CppFiles, tr.OutputFile)
-		result.Warnings  = append(result.Warnings, tr.Warnings...)
+		result.Warnings = append(result.Warnings, tr.Warnings...)
+	}
+	if err != nil {
+		return result, err
 	}
 
 	for _, w := range result.Warnings {
@@ -149,60 +286,154 @@ func Run(projectDir string, m *manifest.Manifest, opts Options) (*Result, error)
 	}
 	ui.Step("sketch", fmt.Sprintf("wrote %s/%s.ino", sketchName, sketchName))
 
+	if opts.Target == TargetHost {
+		hostDir := filepath.Join(baseOutDir, "host")
+		if opts.Tests {
+			hostDir = filepath.Dir(sketchDir)
+		}
+		return result, compileHost(result, m, hostDir, sketchName, packageIncludes(libsDir, pkgNames), opts)
+	}
 	if !opts.Compile {
 		return result, nil
 	}
 
-	// ── Compile — dispatch to selected backend ────────────────────────────
-	ui.SectionTitle("Compiling")
+	// ── Compile — through the selected backend ────────────────────────────
+	// Show the backend badge before the section title so it's visible at the
+	// top of the compile phase for tsuki-flash / tsuki-flash+cores.
+	ui.FlashBadge(b.Name())
 
-	backend := opts.Backend
-	if backend == "" {
-		backend = "arduino-cli"
+	// A board family's first build installs its core, with the download
+	// progress shown before the compile spinner starts.
+	if err := backend.EnsureCore(b, board); err != nil {
+		return result, err
 	}
+	boardCore, err := CheckCore(b, board, m.Toolchain)
+	if err != nil {
+		return result, err
+	}
+	if boardCore.Version != "" {
+		result.Core = boardCore.String()
+		ui.Step("core", result.Core)
+	}
+	ui.SectionTitle("Compiling")
 
 	buildCacheDir := filepath.Join(baseOutDir, ".cache")
 	_ = os.MkdirAll(buildCacheDir, 0755)
 
-	switch backend {
-	case "tsuki-flash":
-		if err := compileTsukiFlash(result, m, board, opts, buildCacheDir, pkgNames, libsDir); err != nil {
-			return result, err
-		}
-	default: // "arduino-cli" or anything unrecognised
-		if err := compileArduinoCLI(result, board, opts, sketchDir, buildCacheDir); err != nil {
-			return result, err
-		}
+	req := backend.CompileRequest{
+		Board:      board,
+		SketchDir:  sketchDir,
+		BuildDir:   buildCacheDir,
+		Name:       sketchName,
+		CppStd:     m.Build.CppStd,
+		Optimize:   m.Build.Optimize,
+		ExtraFlags: m.Build.ExtraFlags,
+		Includes:   packageIncludes(libsDir, pkgNames),
+		Libraries:  backendLibraries(libraries),
+		Verbose:    opts.Verbose,
+	}
+	for _, s := range backend.Ignored(b, req.Settings()) {
+		w := fmt.Sprintf("backend %s ignores build.%s from %s — the firmware is built without it", b.Name(), s, manifest.FileName)
+		result.Warnings = append(result.Warnings, w)
+		ui.Warn(w)
+	}
+	compileErr := compileSketch(result, b, req)
+	// Editors read the database even after a failed compile, to show its
+	// errors in place, so it is written either way.
+	if db, err := WriteCompileDB(b, req); err == nil && db != "" {
+		result.CompileDB = db
+		ui.Step("compiledb", filepath.Join(sketchName, backend.CompileDBFile))
+	} else if err != nil && compileErr == nil {
+		ui.Warn(fmt.Sprintf("could not write %s: %v", backend.CompileDBFile, err))
+	}
+	if compileErr != nil {
+		return result, compileErr
 	}
 
 	hexFiles, _ := filepath.Glob(filepath.Join(buildCacheDir, "*.hex"))
 	if len(hexFiles) > 0 {
 		result.FirmwareHex = hexFiles[0]
 	}
+	if elfFiles, _ := filepath.Glob(filepath.Join(buildCacheDir, "*.elf")); len(elfFiles) > 0 {
+		result.ELF = elfFiles[0]
+	}
+
+	if err := stampFirmware(result, meta, b.Name(), buildCacheDir); err != nil {
+		return result, err
+	}
 
 	return result, nil
 }
 
+// stampFirmware validates the compiled image and writes its build-info file.
+// The metadata is the same that was compiled into the buildinfo package,
+// so the file and the running firmware agree on commit and timestamp.
+func stampFirmware(result *Result, meta Metadata, backendName, buildCacheDir string) error {
+	image := firmware.Find(buildCacheDir)
+	if image == "" {
+		ui.Warn(fmt.Sprintf("no firmware image found in %s — build-info not written", buildCacheDir))
+		return nil
+	}
+	result.Firmware = image
+
+	img, err := firmware.Load(image)
+	if err != nil {
+		return fmt.Errorf("firmware image is invalid: %w", err)
+	}
+
+	info := &firmware.BuildInfo{
+		Project:   meta.Project,
+		Version:   meta.Version,
+		Board:     meta.Board,
+		Backend:   backendName,
+		Core:      result.Core,
+		Profile:   meta.Profile,
+		GitCommit: meta.Commit,
+		GitDirty:  meta.Dirty,
+		BuiltAt:   meta.BuiltAt,
+	}
+	info.Describe(img)
+
+	result.BuildInfo = firmware.BuildInfoPath(image)
+	if err := firmware.WriteBuildInfo(result.BuildInfo, info); err != nil {
+		return fmt.Errorf("writing build-info: %w", err)
+	}
+	ui.Step("firmware", fmt.Sprintf("%s  %d bytes  sha256 %s", filepath.Base(image), info.Size, info.SHA256[:12]))
+	return nil
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
-//  Backend: tsuki-flash
+//  Compile
 // ─────────────────────────────────────────────────────────────────────────────
 
-func compileTsukiFlash(
-	result *Result,
-	m *manifest.Manifest,
-	board string,
-	opts Options,
-	buildCacheDir string,
-	pkgNames []string,
-	libsDir string,
-) error {
-	flashBin := opts.FlashBinary
-	if flashBin == "" {
-		flashBin = "tsuki-flash"
-	}
-
-	// Build the --include list from installed tsuki packages.
-	var includeArgs []string
+// compileSketch runs the backend's compile behind a spinner and turns a
+// failure into located diagnostics.
+func compileSketch(result *Result, b backend.Backend, req backend.CompileRequest) error {
+	sp := ui.NewSpinner(fmt.Sprintf("%s compile  [board: %s]", b.Name(), req.Board))
+	sp.Start()
+
+	out, err := b.Compile(req)
+	if err != nil {
+		sp.Stop(false, "compilation failed")
+		if len(out) == 0 {
+			// The backend never ran (unknown board, missing binary).
+			return err
+		}
+		compileFailed(result, string(out), b.Name())
+		return fmt.Errorf("%s compile failed", b.Name())
+	}
+
+	sp.Stop(true, fmt.Sprintf("firmware written to %s", req.BuildDir))
+	if req.Verbose && len(out) > 0 {
+		fmt.Print(string(out))
+	}
+	return nil
+}
+
+// packageIncludes returns the include directory of each installed tsuki
+// package: its versioned subdirectory, or the package root.
+func packageIncludes(libsDir string, pkgNames []string) []string {
+	var dirs []string
 	for _, pkg := range pkgNames {
 		pkgDir := filepath.Join(libsDir, pkg)
 		// Walk to find the versioned subdirectory.
@@ -210,100 +441,34 @@ func compileTsukiFlash(
 		if err == nil {
 			for _, e := range entries {
 				if e.IsDir() {
-					includeArgs = append(includeArgs, filepath.Join(pkgDir, e.Name()))
+					dirs = append(dirs, filepath.Join(pkgDir, e.Name()))
 					break
 				}
 			}
 		} else {
 			// Fall back to the package root itself.
-			includeArgs = append(includeArgs, pkgDir)
+			dirs = append(dirs, pkgDir)
 		}
 	}
-
-	cppStd := m.Build.CppStd
-	if cppStd == "" {
-		cppStd = "c++11"
-	}
-
-	args := []string{
-		"compile",
-		"--board", board,
-		"--sketch", result.SketchDir,
-		"--build-dir", buildCacheDir,
-		"--name", sanitizeSketchName(m.Name),
-		"--cpp-std", cppStd,
-	}
-	for _, inc := range includeArgs {
-		args = append(args, "--include", inc)
-	}
-	if opts.Verbose {
-		args = append(args, "--verbose")
-	}
-
-	sp := ui.NewSpinner(fmt.Sprintf("tsuki-flash compile --board %s", board))
-	sp.Start()
-
-	cmd := exec.Command(flashBin, args...)
-	out, cmdErr := cmd.CombinedOutput()
-	if cmdErr != nil {
-		sp.Stop(false, "compilation failed")
-		renderTsukiFlashError(string(out))
-		return fmt.Errorf("tsuki-flash compile failed")
-	}
-
-	sp.Stop(true, fmt.Sprintf("firmware written to %s", buildCacheDir))
-	if opts.Verbose && len(out) > 0 {
-		fmt.Print(string(out))
-	}
-	return nil
+	return dirs
 }
 
-// ─────────────────────────────────────────────────────────────────────────────
-//  Backend: arduino-cli
-// ─────────────────────────────────────────────────────────────────────────────
-
-func compileArduinoCLI(
-	result *Result,
-	board string,
-	opts Options,
-	sketchDir string,
-	buildCacheDir string,
-) error {
-	fqbn, err := boardFQBN(board)
+// SourceFiles lists the Go sources in srcDir: the sketch's, and apart from
+// them its tests (*_test.go), which only tsuki test compiles.
+func SourceFiles(srcDir string) (sources, tests []string, err error) {
+	files, err := filepath.Glob(filepath.Join(srcDir, "*.go"))
 	if err != nil {
-		return fmt.Errorf("unknown board %q — run `tsuki boards list`", board)
-	}
-
-	arduinoCLI := opts.ArduinoCLI
-	if arduinoCLI == "" {
-		arduinoCLI = "arduino-cli"
-	}
-
-	args := []string{
-		"compile",
-		"--fqbn", fqbn,
-		"--build-path", buildCacheDir,
-		"--warnings", "all",
-	}
-	if opts.Verbose {
-		args = append(args, "--verbose")
+		return nil, nil, err
 	}
-	args = append(args, sketchDir)
-
-	sp := ui.NewSpinner(fmt.Sprintf("arduino-cli compile --fqbn %s", fqbn))
-	sp.Start()
-
-	cmd := exec.Command(arduinoCLI, args...)
-	cmd.Dir = sketchDir
-	out, cmdErr := cmd.CombinedOutput()
-	if cmdErr != nil {
-		sp.Stop(false, "compilation failed")
-		renderArduinoError(string(out))
-		return fmt.Errorf("arduino-cli compile failed")
+	for _, f := range files {
+		if host.IsTestFile(f) {
+			tests = append(tests, f)
+		} else {
+			sources = append(sources, f)
+		}
 	}
-
-	sp.Stop(true, fmt.Sprintf("firmware written to %s", buildCacheDir))
-	return nil
+	return sources, tests, nil
+}
 
 // writeInoStub creates <sketchDir>/<sketchName>.ino — the required entry
 // point for arduino-cli. The stub must NOT #include the generated .cpp files:
@@ -316,6 +481,16 @@ func writeInoStub(sketchDir, sketchName string, _ []string) error {
 	return os.WriteFile(filepath.Join(sketchDir, sketchName+".ino"), []byte(stub), 0644)
 }
 
+// SketchName is the name a project builds under: its name reduced to a
+// valid Arduino sketch name, or "sketch" when nothing of it is left. It is
+// also safe as a file name.
+func SketchName(name string) string {
+	if s := sanitizeSketchName(name); s != "" {
+		return s
+	}
+	return "sketch"
+}
+
 // sanitizeSketchName converts a project name to a valid Arduino sketch name:
 // only letters, digits, underscores; cannot start with a digit.
 func sanitizeSketchName(name string) string {
@@ -337,144 +512,101 @@ func sanitizeSketchName(name string) string {
 	return sb.String()
 }
 
-func newBuildCmd() *cobra.Command {
-	var board string
-	var output string
-	var compile bool
-	var verbose bool
-
-	cmd := &cobra.Command{
-		Use:   "build",
-		Short: "Transpile and optionally compile the project",
-		Example: `  tsuki build
-  tsuki build --board esp32
-  tsuki build --compile`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			dir := projectDir()
-			m, err := manifest.Load(dir)
-			if err != nil {
-				return err
-			}
-
-			opts := Options{
-				Board:       board,
-				Compile:     compile,
-				OutputDir:   output,
-				Verbose:     verbose,
-				CoreBin:     cfg.CoreBinary,
-				ArduinoCLI:  cfg.ArduinoCLI,
-				FlashBinary: cfg.FlashBinary,
-				Backend:     cfg.Backend,
-				SourceMap:   m.Build.SourceMap,
-			}
-
-			res, err := Run(dir, m, opts)
-			if err != nil {
-				return err
-			}
-			if res.SketchDir != "" {
-				ui.Info(fmt.Sprintf("Sketch: %s", res.SketchDir))
-			}
-			ui.Success("Build finished!")
-			return nil
-		},
-	}
-
-	cmd.Flags().StringVarP(&board, "board", "b", "", "target board (default from manifest)")
-	cmd.Flags().StringVarP(&output, "out", "o", "", "output directory")
-	cmd.Flags().BoolVarP(&compile, "compile", "c", false, "compile to firmware after transpile")
-	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
-	return cmd
-}
-
-func renderTsukiFlashError(output string) {
-	lines := strings.Split(output, "\n")
-	var frames []ui.Frame
-	var errMsg string
-
-	for _, line := range lines {
+// renderToolError prints the error lines of a backend's output that carries
+// no source location, or its first lines when none look like an error. With
+// no location there is no code frame: the tool's words are the message.
+func renderToolError(tool, output string, isError func(string) bool, message func(string) string) {
+	var errLines, firstLines []string
+	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
-		// tsuki-flash error format: "✗ <message>" or "error: <message>"
-		if strings.HasPrefix(line, "✗") || strings.Contains(line, "error:") || strings.Contains(line, "Error:") {
-			if errMsg == "" {
-				errMsg = strings.TrimPrefix(strings.TrimPrefix(line, "✗ "), "error: ")
+		if len(firstLines) < 5 {
+			firstLines = append(firstLines, line)
+		}
+		if isError(line) {
+			if len(errLines) == 0 {
+				line = message(line)
 			}
-			frames = append(frames, ui.Frame{
-				File: "tsuki-flash", Func: "compile",
-				Code: []ui.CodeLine{{Number: 0, Text: line, IsPointer: true}},
-			})
+			errLines = append(errLines, line)
 		}
 	}
 
-	if len(frames) == 0 {
-		errMsg = strings.TrimSpace(output)
-		frames = []ui.Frame{{
-			File: "tsuki-flash", Func: "compile",
-			Code: []ui.CodeLine{{Number: 0, Text: errMsg, IsPointer: true}},
-		}}
+	errMsg := strings.Join(errLines, "\n")
+	if errMsg == "" {
+		errMsg = strings.Join(firstLines, "\n")
 	}
-	ui.Traceback("CompileError", errMsg, frames)
+	if errMsg == "" {
+		errMsg = tool + " failed without output"
+	}
+	ui.Traceback("CompileError", errMsg, nil)
+}
+
+// compileFailed records the compiler errors in output on result — mapped
+// back to the Go source when the sketch has #line directives — and renders
+// them.
+func compileFailed(result *Result, output string, backendName string) {
+	var diags []core.Diagnostic
+	var renderUnlocated func()
+	switch {
+	case strings.HasPrefix(backendName, "tsuki-flash"):
+		diags = parseTsukiFlashErrors(output, result.SketchDir)
+		renderUnlocated = func() { renderToolError("tsuki-flash", output, isTsukiFlashError, tsukiFlashMessage) }
+	case backendName == "platformio":
+		diags = parsePlatformIOErrors(output, result.SketchDir)
+		renderUnlocated = func() { renderToolError("platformio", output, isPlatformIOError, platformIOMessage) }
+	default:
+		diags = parseCompilerErrors(output, result.SketchDir)
+	}
+	diags = mapToGo(diags, result.CppFiles)
+	result.Diagnostics = append(result.Diagnostics, diags...)
+
+	if renderUnlocated != nil && !located(diags) {
+		renderUnlocated()
+		return
+	}
+	renderArduinoError(diags, output)
+}
+
+// located reports whether any diagnostic has a source location.
+func located(diags []core.Diagnostic) bool {
+	for _, d := range diags {
+		if d.Range != nil {
+			return true
+		}
+	}
+	return false
 }
 
-func renderArduinoError(output string) {
-	lines := strings.Split(output, "\n")
+// renderArduinoError prints the compiler errors found by parseCompilerErrors,
+// or the raw output when none could be located. Frames show the source
+// around each located error; notes (such as the generated C++ line) follow.
+func renderArduinoError(diags []core.Diagnostic, output string) {
 	var frames []ui.Frame
 	var errMsg string
 
-	for _, line := range lines {
-		if strings.Contains(line, ": error:") {
-			parts := strings.SplitN(line, ": error:", 2)
-			loc := parts[0]
-			msg := ""
-			if len(parts) > 1 {
-				msg = strings.TrimSpace(parts[1])
-			}
-			locParts := strings.Split(loc, ":")
-			frame := ui.Frame{Func: "compile"}
-			if len(locParts) >= 1 {
-				frame.File = locParts[0]
-			}
-			if len(locParts) >= 2 {
-				fmt.Sscanf(locParts[1], "%d", &frame.Line)
-			}
-			frame.Code = []ui.CodeLine{{Number: frame.Line, Text: msg, IsPointer: true}}
-			frames = append(frames, frame)
-			if errMsg == "" {
-				errMsg = msg
-			}
+	for _, d := range diags {
+		if ref := d.SourceRef(); ref.Located() {
+			frames = append(frames, ui.SourceFrame(ref, "compile"))
+		}
+		if errMsg == "" {
+			errMsg = d.Message
 		}
 	}
 
+	// Nothing located: the compiler's output is the message.
 	if len(frames) == 0 {
-		frames = []ui.Frame{{
-			File: "sketch", Func: "compile",
-			Code: []ui.CodeLine{{Number: 0, Text: strings.TrimSpace(output), IsPointer: true}},
-		}}
-		errMsg = "compilation failed"
+		errMsg = strings.TrimSpace(output)
+		if errMsg == "" {
+			errMsg = "compilation failed"
+		}
 	}
 	ui.Traceback("CompileError", errMsg, frames)
-}
 
-func boardFQBN(id string) (string, error) {
-	table := map[string]string{
-		"uno":      "arduino:avr:uno",
-		"nano":     "arduino:avr:nano",
-		"mega":     "arduino:avr:mega",
-		"leonardo": "arduino:avr:leonardo",
-		"micro":    "arduino:avr:micro",
-		"due":      "arduino:sam:arduino_due_x",
-		"mkr1000":  "arduino:samd:mkr1000",
-		"esp32":    "esp32:esp32:esp32",
-		"esp8266":  "esp8266:esp8266:generic",
-		"pico":     "rp2040:rp2040:rpipico",
-		"teensy40": "teensy:avr:teensy40",
-	}
-	fqbn, ok := table[strings.ToLower(id)]
-	if !ok {
-		return "", fmt.Errorf("unknown board")
-	}
-	return fqbn, nil
-}
\ No newline at end of file
+	for _, d := range diags {
+		for _, n := range d.Notes {
+			ui.Info("note: " + n.Message)
+		}
+	}
+}
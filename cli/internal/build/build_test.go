@@ -0,0 +1,19 @@
+package build
+
+import "testing"
+
+func TestSketchName(t *testing.T) {
+	for in, want := range map[string]string{
+		"blink":          "blink",
+		"my-robot v2":    "my_robot_v2",
+		"2fast":          "fast",
+		"../../etc/evil": "etc_evil",
+		"a/b":            "a_b",
+		"..":             "sketch",
+		"":               "sketch",
+	} {
+		if got := SketchName(in); got != want {
+			t.Errorf("SketchName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package build
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/vcs"
+)
+
+// BuildinfoPackage is the virtual package every build generates. Go code
+// imports it like any tsukilib package:
+//
+//	import "buildinfo"
+//	arduino.Serial.Println(buildinfo.Version)
+//
+// Besides the fixed constants below it holds the manifest's build.defines
+// and any `--define NAME=VALUE` given on the command line, as strings.
+const BuildinfoPackage = "buildinfo"
+
+// Metadata is the build-time information compiled into the firmware.
+type Metadata struct {
+	Project string
+	Version string
+	Commit  string // full git hash, "" outside a repository
+	Dirty   bool   // uncommitted changes at build time
+	Board   string
+	Profile string
+	BuiltAt time.Time
+}
+
+// ProjectMetadata collects the metadata of a build of m in projectDir.
+func ProjectMetadata(projectDir string, m *manifest.Manifest, board, profile string) Metadata {
+	if profile == "" {
+		profile = "release"
+	}
+	return Metadata{
+		Project: m.Name,
+		Version: m.Version,
+		Commit:  vcs.Commit(projectDir),
+		Dirty:   vcs.Dirty(projectDir),
+		Board:   board,
+		Profile: profile,
+		BuiltAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// buildinfoNames are the constants tsuki itself defines; user defines may
+// not shadow them.
+var buildinfoNames = map[string]bool{
+	"Project": true, "Version": true, "Commit": true, "Dirty": true,
+	"Board": true, "Profile": true, "BuildTime": true, "BuildUnix": true,
+}
+
+// libConstant is one [[constant]] entry of a generated tsukilib.toml.
+type libConstant struct {
+	Go  string
+	Cpp string
+}
+
+// buildinfoConstants returns the constants of the buildinfo package.
+// defines are added in name order so the generated file is stable.
+func buildinfoConstants(meta Metadata, defines map[string]string) []libConstant {
+	dirty := "false"
+	if meta.Dirty {
+		dirty = "true"
+	}
+	consts := []libConstant{
+		{Go: "Project", Cpp: cppString(meta.Project)},
+		{Go: "Version", Cpp: cppString(meta.Version)},
+		{Go: "Commit", Cpp: cppString(meta.Commit)},
+		{Go: "Dirty", Cpp: dirty},
+		{Go: "Board", Cpp: cppString(meta.Board)},
+		{Go: "Profile", Cpp: cppString(meta.Profile)},
+		{Go: "BuildTime", Cpp: cppString(meta.BuiltAt.Format(time.RFC3339))},
+		{Go: "BuildUnix", Cpp: fmt.Sprintf("%dUL", meta.BuiltAt.Unix())},
+	}
+
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		consts = append(consts, libConstant{Go: name, Cpp: cppString(defines[name])})
+	}
+	return consts
+}
+
+// MergeDefines layers command-line `NAME=VALUE` overrides on top of the
+// manifest's build.defines and validates every name.
+func MergeDefines(base map[string]string, overrides []string) (map[string]string, error) {
+	out := make(map[string]string, len(base)+len(overrides))
+	for name, value := range base {
+		out[name] = value
+	}
+	for _, kv := range overrides {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid define %q (expected NAME=VALUE)", kv)
+		}
+		out[strings.TrimSpace(name)] = value
+	}
+	for name := range out {
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("invalid define name %q: must be a Go identifier", name)
+		}
+		if buildinfoNames[name] {
+			return nil, fmt.Errorf("define %q would shadow %s.%s, which tsuki sets itself", name, BuildinfoPackage, name)
+		}
+	}
+	return out, nil
+}
+
+// CoreLibs writes the libs directory tsuki-core should see for a build into
+// <outDir>/.cache/libs and returns it together with the --packages list.
+// Both `tsuki build` and `tsuki check` go through it so that code importing
+// buildinfo checks exactly as it builds.
+func CoreLibs(outDir string, meta Metadata, defines map[string]string, libsDir string, pkgNames []string) (string, []string, error) {
+	dir := filepath.Join(outDir, ".cache", "libs")
+	names, err := prepareLibs(dir, libsDir, pkgNames, buildinfoConstants(meta, defines))
+	if err != nil {
+		return "", nil, fmt.Errorf("generating %s package: %w", BuildinfoPackage, err)
+	}
+	return dir, names, nil
+}
+
+// prepareLibs builds the libs directory handed to tsuki-core: a copy of the
+// installed package descriptors plus the generated buildinfo package.
+// tsuki-core only reads tsukilib.toml files, so copying those is enough;
+// C++ headers are still resolved from the real libs dir at compile time.
+//
+// When pkgNames is empty the core loads every library it finds, buildinfo
+// included; otherwise buildinfo is appended to the selection.
+func prepareLibs(overlayDir, libsDir string, pkgNames []string, consts []libConstant) ([]string, error) {
+	if err := os.RemoveAll(overlayDir); err != nil {
+		return nil, err
+	}
+
+	descriptors, _ := filepath.Glob(filepath.Join(libsDir, "*", "*", "tsukilib.toml"))
+	for _, src := range descriptors {
+		rel, _ := filepath.Rel(libsDir, src)
+		if strings.HasPrefix(rel, BuildinfoPackage+string(filepath.Separator)) {
+			continue // the generated package shadows an installed one
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		dst := filepath.Join(overlayDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Join(overlayDir, BuildinfoPackage, "0.0.0")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tsukilib.toml"), virtualPackage(BuildinfoPackage, consts), 0644); err != nil {
+		return nil, err
+	}
+
+	if len(pkgNames) == 0 {
+		return nil, nil
+	}
+	return append(append([]string(nil), pkgNames...), BuildinfoPackage), nil
+}
+
+// virtualPackage renders a tsukilib.toml made only of constants.
+func virtualPackage(name string, consts []libConstant) []byte {
+	var b strings.Builder
+	b.WriteString("# Generated by tsuki build — do not edit.\n\n")
+	b.WriteString("[package]\n")
+	fmt.Fprintf(&b, "name        = %s\n", strconv.Quote(name))
+	b.WriteString("version     = \"0.0.0\"\n")
+	b.WriteString("description = \"Build metadata generated by tsuki\"\n")
+	for _, c := range consts {
+		b.WriteString("\n[[constant]]\n")
+		fmt.Fprintf(&b, "go  = %s\n", strconv.Quote(c.Go))
+		fmt.Fprintf(&b, "cpp = %s\n", strconv.Quote(c.Cpp))
+	}
+	return []byte(b.String())
+}
+
+// cppString returns s as a C++ string literal. Go's quoting rules produce
+// escapes C++ accepts for the printable text that ends up here.
+func cppString(s string) string {
+	return strconv.Quote(s)
+}
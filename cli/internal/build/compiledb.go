@@ -0,0 +1,31 @@
+package build
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/tsuki/cli/internal/backend"
+)
+
+// WriteCompileDB writes the compilation database of the compile req ran
+// into the sketch dir, where clangd finds it next to the generated sources,
+// and returns its path. It returns "" when b cannot report its commands,
+// after removing any database an earlier build left, which would be stale.
+func WriteCompileDB(b backend.Backend, req backend.CompileRequest) (string, error) {
+	path := filepath.Join(req.SketchDir, backend.CompileDBFile)
+	cd, ok := b.(backend.CompileDatabase)
+	if !ok {
+		os.Remove(path)
+		return "", nil
+	}
+	cmds, err := cd.CompileCommands(req)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(cmds, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,31 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/manifest"
+)
+
+// CheckCore returns the core board compiles against, or an error when it is
+// not installed or not at the version tc pins. Backends that cannot report
+// their cores return a zero Core.
+func CheckCore(b backend.Backend, board string, tc *manifest.Toolchain) (backend.Core, error) {
+	cm, ok := b.(backend.CoreManager)
+	if !ok {
+		return backend.Core{}, nil
+	}
+	c, err := cm.Core(board)
+	if err != nil {
+		return backend.Core{}, err
+	}
+	if c.Version == "" {
+		return c, fmt.Errorf("the %s core for board %q is not installed in %s\n  Run: tsuki core install", c.ID, board, b.Name())
+	}
+	if tc != nil {
+		if pin := tc.Cores[c.ID]; pin != "" && pin != c.Version {
+			return c, fmt.Errorf("the %s core is at %s, but %s pins %s\n  Run: tsuki toolchain install", c.ID, c.Version, manifest.FileName, pin)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,178 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tsuki/cli/internal/core"
+)
+
+// Compiler output is turned into core.Diagnostic values so that compile
+// errors travel with the transpiler's through Result and into CI reports.
+
+// Diagnostic codes for errors raised after transpilation.
+const (
+	codeCompile    = "compile"     // located gcc/g++ error
+	codeTsukiFlash = "tsuki-flash" // tsuki-flash error without a location
+	codePlatformIO = "platformio"  // PlatformIO error without a location
+)
+
+// parseCompilerErrors extracts gcc-style "file:line[:col]: error: msg" lines,
+// and "fatal error:" ones such as a missing header. Paths outside sketchDir
+// that name one of its files (arduino-cli and PlatformIO compile a copy of
+// the sketch) are mapped back to the sketch dir.
+func parseCompilerErrors(output, sketchDir string) []core.Diagnostic {
+	var diags []core.Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		loc, msg, ok := strings.Cut(line, ": error:")
+		if !ok {
+			loc, msg, ok = strings.Cut(line, ": fatal error:")
+		}
+		if !ok {
+			continue
+		}
+		d := core.Diagnostic{
+			Severity: core.SeverityError,
+			Code:     codeCompile,
+			Message:  strings.TrimSpace(msg),
+			Notes:    []core.Note{},
+			Fixes:    []core.Fix{},
+		}
+		d.File, d.Range = splitCompilerLocation(loc)
+		d.File = sketchPath(d.File, sketchDir)
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// splitCompilerLocation splits "file:line:col" or "file:line". The file may
+// itself contain colons (Windows drive letters).
+func splitCompilerLocation(loc string) (string, *core.Range) {
+	parts := strings.Split(loc, ":")
+	nums := 0
+	for i := len(parts) - 1; i > 0 && nums < 2; i-- {
+		if _, err := strconv.Atoi(parts[i]); err != nil {
+			break
+		}
+		nums++
+	}
+	if nums == 0 {
+		return loc, nil
+	}
+	file := strings.Join(parts[:len(parts)-nums], ":")
+	line, _ := strconv.Atoi(parts[len(parts)-nums])
+	col := 0
+	if nums == 2 {
+		col, _ = strconv.Atoi(parts[len(parts)-1])
+	}
+	return file, &core.Range{
+		Start: core.Position{Line: line, Column: col},
+		End:   core.Position{Line: line, Column: col + 1},
+	}
+}
+
+// sketchPath maps a compiler path to the same file in sketchDir, if any.
+func sketchPath(file, sketchDir string) string {
+	if file == "" || sketchDir == "" {
+		return file
+	}
+	if rel, err := filepath.Rel(sketchDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return file
+	}
+	candidate := filepath.Join(sketchDir, filepath.Base(file))
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+	return file
+}
+
+// parseTsukiFlashErrors returns tsuki-flash's compiler errors when it relays
+// gcc output, and otherwise one location-less diagnostic per error line.
+func parseTsukiFlashErrors(output, sketchDir string) []core.Diagnostic {
+	if diags := parseCompilerErrors(output, sketchDir); len(diags) > 0 {
+		return diags
+	}
+	var diags []core.Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || !isTsukiFlashError(line) {
+			continue
+		}
+		diags = append(diags, core.Diagnostic{
+			Severity: core.SeverityError,
+			Code:     codeTsukiFlash,
+			Message:  tsukiFlashMessage(line),
+			Notes:    []core.Note{},
+			Fixes:    []core.Fix{},
+		})
+	}
+	return diags
+}
+
+// isTsukiFlashError reports whether a line of tsuki-flash output is an error.
+func isTsukiFlashError(line string) bool {
+	return strings.HasPrefix(line, "✗") ||
+		strings.Contains(line, "error:") ||
+		strings.Contains(line, "Error:") ||
+		strings.Contains(line, "failed:") ||
+		strings.Contains(line, "Failed:") ||
+		strings.Contains(line, "failed —") ||
+		strings.Contains(line, "AVR SDK") ||
+		strings.Contains(line, "SDK install") ||
+		strings.Contains(line, "Some downloads")
+}
+
+// tsukiFlashMessage strips the status mark and "error:" prefix from a line.
+func tsukiFlashMessage(line string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(
+		line, "✗ "), "error: "), "Error: ")
+}
+
+// parsePlatformIOErrors returns the compiler errors from `pio run`, and
+// otherwise one location-less diagnostic per PlatformIO error — an unknown
+// board, a lib_deps entry the registry does not have.
+func parsePlatformIOErrors(output, sketchDir string) []core.Diagnostic {
+	if diags := parseCompilerErrors(output, sketchDir); len(diags) > 0 {
+		return diags
+	}
+	var diags []core.Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !isPlatformIOError(line) {
+			continue
+		}
+		diags = append(diags, core.Diagnostic{
+			Severity: core.SeverityError,
+			Code:     codePlatformIO,
+			Message:  platformIOMessage(line),
+			Notes:    []core.Note{},
+			Fixes:    []core.Fix{},
+		})
+	}
+	return diags
+}
+
+// platformIOErrorRe matches PlatformIO's exception lines, e.g.
+// "UnknownPackageError: Could not find the package …".
+var platformIOErrorRe = regexp.MustCompile(`^[A-Z][A-Za-z]*Error: `)
+
+// isPlatformIOError reports whether a line of `pio run` output is an error.
+// SCons' "*** [target] Error 1" summary only repeats an earlier error.
+func isPlatformIOError(line string) bool {
+	if strings.HasPrefix(line, "***") {
+		return false
+	}
+	return strings.HasPrefix(line, "Error: ") || platformIOErrorRe.MatchString(line)
+}
+
+// platformIOMessage strips the "Error:" or exception name from a line.
+func platformIOMessage(line string) string {
+	if loc := platformIOErrorRe.FindStringIndex(line); loc != nil {
+		return line[loc[1]:]
+	}
+	return strings.TrimPrefix(line, "Error: ")
+}
@@ -0,0 +1,100 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tsuki/cli/internal/core"
+)
+
+// pioCompileError is `pio run` failing on the copy of the sketch in the
+// generated project.
+const pioCompileError = `Processing uno (platform: atmelavr; board: uno; framework: arduino)
+--------------------------------------------------------------------------------
+Verbose mode can be enabled via ` + "`-v, --verbose`" + ` option
+CONFIGURATION: https://docs.platformio.org/page/boards/atmelavr/uno.html
+PLATFORM: Atmel AVR (5.0.0) > Arduino Uno
+Compiling .pio/build/uno/src/main.cpp.o
+%s/platformio/src/main.cpp: In function 'void loop()':
+%s/platformio/src/main.cpp:12:5: error: 'foo' was not declared in this scope
+     foo();
+     ^~~
+*** [.pio/build/uno/src/main.cpp.o] Error 1
+========================= [FAILED] Took 0.71 seconds =========================
+`
+
+const pioUnknownLibrary = `Processing uno (platform: atmelavr; board: uno; framework: arduino)
+--------------------------------------------------------------------------------
+Library Manager: Installing Adafruit NeoPixl
+UnknownPackageError: Could not find the package with 'Adafruit NeoPixl' requirements for your system 'linux_x86_64'
+`
+
+const pioUnknownBoard = `Error: Unknown board ID 'nanoo'
+`
+
+func TestParsePlatformIOCompileError(t *testing.T) {
+	tmp := t.TempDir()
+	sketchDir := filepath.Join(tmp, "blink")
+	if err := os.MkdirAll(sketchDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sketchDir, "main.cpp"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := filepath.Join(tmp, ".cache")
+	output := fmt.Sprintf(pioCompileError, cacheDir, cacheDir)
+
+	diags := parsePlatformIOErrors(output, sketchDir)
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+	}
+	d := diags[0]
+	// The project's copy of the sketch maps back to the sketch dir.
+	if want := filepath.Join(sketchDir, "main.cpp"); d.File != want {
+		t.Errorf("File = %q, want %q", d.File, want)
+	}
+	if d.Line() != 12 || d.Column() != 5 {
+		t.Errorf("location = %d:%d, want 12:5", d.Line(), d.Column())
+	}
+	if d.Code != codeCompile || d.Severity != core.SeverityError {
+		t.Errorf("code/severity = %s/%s, want %s/error", d.Code, d.Severity, codeCompile)
+	}
+	if want := "'foo' was not declared in this scope"; d.Message != want {
+		t.Errorf("Message = %q, want %q", d.Message, want)
+	}
+}
+
+func TestParsePlatformIOErrorsWithoutLocation(t *testing.T) {
+	tests := []struct {
+		name, output, message string
+	}{
+		{"unknown library", pioUnknownLibrary,
+			"Could not find the package with 'Adafruit NeoPixl' requirements for your system 'linux_x86_64'"},
+		{"unknown board", pioUnknownBoard, "Unknown board ID 'nanoo'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := parsePlatformIOErrors(tt.output, t.TempDir())
+			if len(diags) != 1 {
+				t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+			}
+			d := diags[0]
+			if d.Code != codePlatformIO || d.Range != nil {
+				t.Errorf("got code %s, range %v; want %s without a location", d.Code, d.Range, codePlatformIO)
+			}
+			if d.Message != tt.message {
+				t.Errorf("Message = %q, want %q", d.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestParsePlatformIOErrorsIgnoresSummary(t *testing.T) {
+	output := "*** [.pio/build/uno/firmware.elf] Error 1\n" +
+		"========================= [FAILED] Took 0.71 seconds =========================\n"
+	if diags := parsePlatformIOErrors(output, t.TempDir()); len(diags) != 0 {
+		t.Errorf("got %+v from the SCons summary, want nothing", diags)
+	}
+}
@@ -0,0 +1,65 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/tsuki/cli/internal/host"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// Build targets.
+const (
+	TargetBoard = "board"
+	TargetHost  = "host"
+)
+
+// compileHost compiles the transpiled sketch into an executable for the
+// build machine (see the host package), written to dir; with opts.Tests,
+// the sketch and its tests into a test executable.
+func compileHost(result *Result, m *manifest.Manifest, dir, sketchName string, includes []string, opts Options) error {
+	ui.SectionTitle("Compiling  [target: host]")
+
+	compiler := opts.HostCompiler
+	if compiler == "" {
+		compiler = host.DefaultCompiler
+	}
+	bin := host.Executable(dir, sketchName)
+	sources, flags := result.CppFiles, m.Build.ExtraFlags
+	if opts.Tests {
+		bin = host.Executable(dir, sketchName+"_test")
+		main := filepath.Join(dir, host.TestMainFile)
+		if err := host.WriteTestMain(main, result.CppFiles, result.Tests); err != nil {
+			return fmt.Errorf("writing %s: %w", host.TestMainFile, err)
+		}
+		sources = []string{main}
+		flags = append(append([]string(nil), flags...), host.NoMainFlag)
+	}
+
+	sp := ui.NewSpinner(fmt.Sprintf("%s compile  [target: host]", filepath.Base(compiler)))
+	sp.Start()
+	out, err := host.Compile(host.CompileRequest{
+		Compiler:   compiler,
+		Sources:    sources,
+		RuntimeDir: filepath.Join(dir, "runtime"),
+		Includes:   includes,
+		CppStd:     m.Build.CppStd,
+		ExtraFlags: flags,
+		Out:        bin,
+	})
+	if err != nil {
+		sp.Stop(false, "compilation failed")
+		if len(out) == 0 {
+			return err
+		}
+		compileFailed(result, string(out), "host")
+		return fmt.Errorf("%s compile failed", filepath.Base(compiler))
+	}
+	sp.Stop(true, fmt.Sprintf("host executable written to %s", bin))
+	if opts.Verbose && len(out) > 0 {
+		fmt.Print(string(out))
+	}
+	result.Host = bin
+	return nil
+}
@@ -0,0 +1,117 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/pkgmgr"
+)
+
+// RequiredLibraries returns the Arduino libraries a project needs: those in
+// its manifest, plus the arduino_lib of each declared package that the
+// manifest does not list yet (packages added before `tsuki lib` existed).
+func RequiredLibraries(m *manifest.Manifest) []manifest.Library {
+	libs := append([]manifest.Library(nil), m.Libraries...)
+	if len(m.Packages) == 0 {
+		return libs
+	}
+
+	installed, _ := pkgmgr.ListInstalled()
+	for _, p := range installed {
+		if p.ArduinoLib == "" || !m.HasPackage(p.Name) {
+			continue
+		}
+		if i := libraryIndex(libs, p.ArduinoLib); i >= 0 {
+			if !contains(libs[i].Packages, p.Name) {
+				libs[i].Packages = append(append([]string(nil), libs[i].Packages...), p.Name)
+			}
+			continue
+		}
+		libs = append(libs, manifest.Library{Name: p.ArduinoLib, Packages: []string{p.Name}})
+	}
+	return libs
+}
+
+// CheckLibraries reports the required libraries that b has not installed,
+// or installed at a version other than the pinned one. Backends that fetch
+// libraries per build, or cannot list them, are not checked.
+func CheckLibraries(b backend.Backend, required []manifest.Library) ([]manifest.Library, error) {
+	if f, ok := b.(backend.LibraryFetcher); ok && f.FetchesLibraries() {
+		return nil, nil
+	}
+	lm, ok := b.(backend.LibraryManager)
+	if !ok || len(required) == 0 {
+		return nil, nil
+	}
+	installed, err := lm.Libraries()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []manifest.Library
+	for _, req := range required {
+		ok := false
+		for _, lib := range installed {
+			if strings.EqualFold(lib.Name, req.Name) && (req.Version == "" || lib.Version == req.Version) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			missing = append(missing, req)
+		}
+	}
+	return missing, nil
+}
+
+// backendLibraries converts the manifest's libraries for a CompileRequest.
+func backendLibraries(libs []manifest.Library) []backend.Library {
+	out := make([]backend.Library, 0, len(libs))
+	for _, l := range libs {
+		out = append(out, backend.Library{Name: l.Name, Version: l.Version})
+	}
+	return out
+}
+
+// missingLibrariesError lists the libraries CheckLibraries found missing.
+func missingLibrariesError(missing []manifest.Library) error {
+	var b strings.Builder
+	b.WriteString("Arduino libraries required by tsuki_package.json are missing or not at the pinned version:\n")
+	for _, l := range missing {
+		b.WriteString("    " + LibraryLabel(l))
+		if len(l.Packages) > 0 {
+			fmt.Fprintf(&b, "  (needed by %s)", strings.Join(l.Packages, ", "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("  Run: tsuki lib install")
+	return fmt.Errorf("%s", b.String())
+}
+
+// LibraryLabel is "Name" or "Name@version".
+func LibraryLabel(l manifest.Library) string {
+	if l.Version == "" {
+		return l.Name
+	}
+	return l.Name + "@" + l.Version
+}
+
+func libraryIndex(libs []manifest.Library, name string) int {
+	for i, l := range libs {
+		if strings.EqualFold(l.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/manifest"
+)
+
+// installPackage puts a tsukilib package with the given arduino_lib into
+// the libs dir the test points tsuki at.
+func installPackage(t *testing.T, libs, name, arduinoLib string) {
+	t.Helper()
+	dir := filepath.Join(libs, name, "1.0.0")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	toml := "[package]\nname = \"" + name + "\"\nversion = \"1.0.0\"\n"
+	if arduinoLib != "" {
+		toml += "arduino_lib = \"" + arduinoLib + "\"\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tsukilib.toml"), []byte(toml), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The libraries a backend is asked to fetch — PlatformIO's lib_deps —
+// include the arduino_lib of each declared package.
+func TestRequiredLibrariesFromArduinoLib(t *testing.T) {
+	tmp := t.TempDir()
+	libs := filepath.Join(tmp, "libs")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	t.Setenv("tsuki_LIBS", libs)
+	installPackage(t, libs, "ws2812", "Adafruit NeoPixel")
+	installPackage(t, libs, "dht", "DHT sensor library")
+	installPackage(t, libs, "servo", "")
+	installPackage(t, libs, "lcd", "LiquidCrystal") // installed, not declared
+
+	m := manifest.Default("blink", "uno")
+	m.Packages = []manifest.Package{{Name: "ws2812"}, {Name: "dht"}, {Name: "servo"}}
+	// Pinned by `tsuki lib install`, already credited to another package.
+	m.Libraries = []manifest.Library{{Name: "DHT sensor library", Version: "1.4.6", Packages: []string{"am2302"}}}
+
+	got := RequiredLibraries(m)
+	want := []manifest.Library{
+		{Name: "DHT sensor library", Version: "1.4.6", Packages: []string{"am2302", "dht"}},
+		{Name: "Adafruit NeoPixel", Packages: []string{"ws2812"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RequiredLibraries = %+v, want %+v", got, want)
+	}
+	if m.Libraries[0].Packages[len(m.Libraries[0].Packages)-1] != "am2302" {
+		t.Error("RequiredLibraries modified the manifest")
+	}
+
+	wantDeps := []backend.Library{
+		{Name: "DHT sensor library", Version: "1.4.6"},
+		{Name: "Adafruit NeoPixel"},
+	}
+	if deps := backendLibraries(got); !reflect.DeepEqual(deps, wantDeps) {
+		t.Errorf("backendLibraries = %+v, want %+v", deps, wantDeps)
+	}
+}
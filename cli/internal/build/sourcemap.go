@@ -0,0 +1,185 @@
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tsuki/cli/internal/core"
+)
+
+// With build.source_map (or --source-map) tsuki-core prefixes each Go
+// statement in the generated C++ with a `#line N "src/main.go"` directive.
+// A SourceMap reads them back, so compiler errors can be reported against
+// the Go source with the generated C++ as a note — whichever of the two
+// files the compiler named.
+
+// Location is a line in a source file.
+type Location struct {
+	File string
+	Line int
+}
+
+// SourceMap maps the lines of one generated .cpp file to Go source lines.
+type SourceMap struct {
+	CppFile string
+	text    []string   // C++ lines, index = line-1
+	origin  []Location // Go origin of each C++ line; zero when unmapped
+}
+
+// LoadSourceMap reads the #line directives in a generated .cpp file.
+// It returns nil when the file has none (source maps were disabled).
+func LoadSourceMap(cppFile string) (*SourceMap, error) {
+	f, err := os.Open(cppFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sm := &SourceMap{CppFile: cppFile}
+	var cur Location // origin of the next line; zero before the first directive
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		sm.text = append(sm.text, line)
+		if loc, ok := parseLineDirective(line); ok {
+			sm.origin = append(sm.origin, Location{})
+			cur = loc
+			continue
+		}
+		sm.origin = append(sm.origin, cur)
+		if cur.Line > 0 {
+			cur.Line++
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for _, o := range sm.origin {
+		if o.Line > 0 {
+			return sm, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseLineDirective parses `#line N "file"`.
+func parseLineDirective(line string) (Location, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "#line ")
+	if !ok {
+		return Location{}, false
+	}
+	num, file, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return Location{}, false
+	}
+	file, err = strconv.Unquote(strings.TrimSpace(file))
+	if err != nil {
+		return Location{}, false
+	}
+	return Location{File: file, Line: n}, true
+}
+
+// GoLocation returns the Go line that produced C++ line n.
+func (sm *SourceMap) GoLocation(n int) (Location, bool) {
+	if n < 1 || n > len(sm.origin) || sm.origin[n-1].Line == 0 {
+		return Location{}, false
+	}
+	return sm.origin[n-1], true
+}
+
+// CppLine returns the first C++ line generated from a Go line, or 0.
+func (sm *SourceMap) CppLine(goFile string, goLine int) int {
+	for i, o := range sm.origin {
+		if o.Line == goLine && sameFile(o.File, goFile) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Text returns C++ line n, or "".
+func (sm *SourceMap) Text(n int) string {
+	if n < 1 || n > len(sm.text) {
+		return ""
+	}
+	return sm.text[n-1]
+}
+
+// mapToGo rewrites compile diagnostics that point at generated C++ so that
+// they point at the Go source, and attaches the C++ line as a note. The
+// column is dropped: the compiler counts it in the C++ text.
+func mapToGo(diags []core.Diagnostic, cppFiles []string) []core.Diagnostic {
+	var maps []*SourceMap
+	for _, f := range cppFiles {
+		if sm, err := LoadSourceMap(f); err == nil && sm != nil {
+			maps = append(maps, sm)
+		}
+	}
+	if len(maps) == 0 {
+		return diags
+	}
+
+	for i := range diags {
+		d := &diags[i]
+		if d.Range == nil {
+			continue
+		}
+		var sm *SourceMap
+		var cppLine int
+		var goLoc Location
+		if strings.EqualFold(filepath.Ext(d.File), ".go") {
+			// The compiler followed the #line directives already.
+			for _, m := range maps {
+				if n := m.CppLine(d.File, d.Line()); n > 0 {
+					sm, cppLine = m, n
+					break
+				}
+			}
+			goLoc = Location{File: d.File, Line: d.Line()}
+		} else {
+			for _, m := range maps {
+				if sameFile(m.CppFile, d.File) {
+					if loc, ok := m.GoLocation(d.Line()); ok {
+						sm, cppLine, goLoc = m, d.Line(), loc
+					}
+					break
+				}
+			}
+		}
+		if sm == nil {
+			continue
+		}
+
+		d.File = goLoc.File
+		d.Range = &core.Range{
+			Start: core.Position{Line: goLoc.Line},
+			End:   core.Position{Line: goLoc.Line},
+		}
+		d.Notes = append(d.Notes, core.Note{
+			Message: fmt.Sprintf("generated C++ %s:%d: %s",
+				filepath.Base(sm.CppFile), cppLine, strings.TrimSpace(sm.Text(cppLine))),
+			File: sm.CppFile,
+			Range: &core.Range{
+				Start: core.Position{Line: cppLine, Column: 1},
+				End:   core.Position{Line: cppLine + 1, Column: 1},
+			},
+		})
+	}
+	return diags
+}
+
+// sameFile compares paths as written, then as absolute paths.
+func sameFile(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,109 @@
+package build
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tsuki/cli/internal/core"
+)
+
+// sourceMapCpp is generated C++ with #line directives: C++ lines 5, 7-8
+// and 10-12 come from Go lines 3, 4-5 and 7-9 of src/main.go.
+var sourceMapCpp = filepath.Join("testdata", "sourcemap", "main.cpp")
+
+func TestLoadSourceMap(t *testing.T) {
+	sm, err := LoadSourceMap(sourceMapCpp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm == nil {
+		t.Fatal("no source map read")
+	}
+
+	for cpp, want := range map[int]Location{
+		5:  {"src/main.go", 3},
+		7:  {"src/main.go", 4},
+		8:  {"src/main.go", 5},
+		10: {"src/main.go", 7},
+		11: {"src/main.go", 8},
+	} {
+		if got, ok := sm.GoLocation(cpp); !ok || got != want {
+			t.Errorf("GoLocation(%d) = %v, %t; want %v", cpp, got, ok, want)
+		}
+	}
+	// Before the first directive, the directives themselves, out of range.
+	for _, cpp := range []int{0, 2, 4, 6, 13} {
+		if got, ok := sm.GoLocation(cpp); ok {
+			t.Errorf("GoLocation(%d) = %v, want no location", cpp, got)
+		}
+	}
+
+	if n := sm.CppLine("src/main.go", 8); n != 11 {
+		t.Errorf("CppLine(main.go:8) = %d, want 11", n)
+	}
+	if n := sm.CppLine("src/other.go", 8); n != 0 {
+		t.Errorf("CppLine(other.go:8) = %d, want 0", n)
+	}
+	if got := sm.Text(11); got != "  foo();" {
+		t.Errorf("Text(11) = %q", got)
+	}
+}
+
+func TestLoadSourceMapWithoutDirectives(t *testing.T) {
+	sm, err := LoadSourceMap(filepath.Join("testdata", "sourcemap", "nomap.cpp"))
+	if err != nil || sm != nil {
+		t.Errorf("LoadSourceMap = %v, %v; want nil, nil", sm, err)
+	}
+}
+
+func at(line, col int) *core.Range {
+	return &core.Range{Start: core.Position{Line: line, Column: col}, End: core.Position{Line: line, Column: col}}
+}
+
+func TestMapToGo(t *testing.T) {
+	wantNote := core.Note{
+		Message: "generated C++ main.cpp:11: foo();",
+		File:    sourceMapCpp,
+		Range: &core.Range{
+			Start: core.Position{Line: 11, Column: 1},
+			End:   core.Position{Line: 12, Column: 1},
+		},
+	}
+	tests := []struct {
+		name string
+		in   core.Diagnostic
+	}{
+		// The compiler reports the C++ location...
+		{"cpp", core.Diagnostic{File: sourceMapCpp, Range: at(11, 3), Message: "'foo' was not declared"}},
+		// ...or, having followed #line, the Go one with a C++ column.
+		{"go", core.Diagnostic{File: "src/main.go", Range: at(8, 3), Message: "'foo' was not declared"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mapToGo([]core.Diagnostic{tt.in}, []string{sourceMapCpp})[0]
+			want := core.Diagnostic{
+				File:    "src/main.go",
+				Range:   at(8, 0),
+				Message: "'foo' was not declared",
+				Notes:   []core.Note{wantNote},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("mapped to %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMapToGoLeavesUnmapped(t *testing.T) {
+	in := []core.Diagnostic{
+		{File: sourceMapCpp, Range: at(2, 10), Message: "in the preamble"},
+		{File: "src/other.go", Range: at(8, 1), Message: "another file"},
+		{File: sourceMapCpp, Message: "no location"},
+	}
+	want := make([]core.Diagnostic, len(in))
+	copy(want, in)
+	if got := mapToGo(in, []string{sourceMapCpp}); !reflect.DeepEqual(got, want) {
+		t.Errorf("mapToGo changed unmapped diagnostics: %+v", got)
+	}
+}
@@ -0,0 +1,377 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ── Go-side analysis ──────────────────────────────────────────────────────────
+//
+// Analyze runs before tsuki-core and catches what users most often write
+// but the core cannot translate: goroutines, channels, maps, interfaces,
+// closures, unmapped stdlib calls, misspelled arduino.* names, imports the
+// manifest does not declare, and a missing setup or loop.
+//
+// go/types checks the project's own code only. Every import is left
+// opaque — the board APIs are not Go packages, and real stdlib signatures
+// differ from what the core maps them to — so expressions involving them
+// are never reported as type errors. The names used on built-in packages
+// are checked against builtinAPI instead.
+
+// Issue codes produced by Analyze.
+const (
+	codeParse      = "parse"
+	codeType       = "type"
+	codeEntryPoint = "entry-point"
+	codeImport     = "import"
+	codeUndefined  = "undefined"
+	codeStdlib     = "stdlib"
+	codeGoroutine  = "goroutine"
+	codeChannel    = "channel"
+	codeMap        = "map"
+	codeInterface  = "interface"
+	codeClosure    = "closure"
+	codeDefer      = "defer"
+)
+
+// Analyze checks the Go sources of one project. declared are the packages
+// listed in the manifest, installed the ones in the libs dir.
+func Analyze(files, declared, installed []string) []Issue {
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	var issues []Issue
+
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.AllErrors|parser.ParseComments)
+		if err != nil {
+			var list scanner.ErrorList
+			if errors.As(err, &list) {
+				for _, e := range list {
+					issues = append(issues, Issue{
+						File: e.Pos.Filename, Line: e.Pos.Line, Column: e.Pos.Column,
+						Code: codeParse, Message: e.Msg, IsError: true,
+					})
+				}
+			} else {
+				issues = append(issues, Issue{File: file, Code: codeParse, Message: err.Error(), IsError: true})
+			}
+			continue
+		}
+		parsed = append(parsed, f)
+	}
+	// The rest needs a complete syntax tree; the core would stop here too.
+	if len(issues) > 0 {
+		return issues
+	}
+
+	a := &analyzer{
+		fset:      fset,
+		declared:  names(declared...),
+		installed: names(installed...),
+		info: &types.Info{
+			Uses: map[*ast.Ident]types.Object{},
+		},
+		typeRefs: map[*ast.SelectorExpr]bool{},
+	}
+
+	conf := types.Config{
+		Importer: opaqueImporter{},
+		Error:    a.typeError,
+	}
+	_, _ = conf.Check("main", fset, parsed, a.info)
+
+	for _, f := range parsed {
+		a.imports(f)
+		ast.Inspect(f, a.collectTypeRefs)
+		ast.Inspect(f, a.visit)
+	}
+	a.entryPoints(parsed)
+
+	issues = a.issues
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues
+}
+
+// opaqueImporter refuses every import. go/types then gives the package a
+// placeholder and silently accepts anything selected from it.
+type opaqueImporter struct{}
+
+func (opaqueImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("%s is resolved by tsuki-core", path)
+}
+
+type analyzer struct {
+	fset      *token.FileSet
+	info      *types.Info
+	declared  map[string]bool
+	installed map[string]bool
+	// typeRefs are selectors used as types, e.g. Servo.Servo: the core
+	// maps those by name, so they are not looked up in builtinAPI.
+	typeRefs map[*ast.SelectorExpr]bool
+	issues   []Issue
+}
+
+// report adds an issue spanning node.
+func (a *analyzer) report(node ast.Node, isError bool, code, msg, hint string) {
+	start := a.fset.Position(node.Pos())
+	end := a.fset.Position(node.End())
+	issue := Issue{
+		File:    start.Filename,
+		Line:    start.Line,
+		Column:  start.Column,
+		Code:    code,
+		Message: msg,
+		Hint:    hint,
+		IsError: isError,
+	}
+	if end.Line == start.Line && end.Column > start.Column {
+		issue.EndColumn = end.Column
+	}
+	a.issues = append(a.issues, issue)
+}
+
+// typeError keeps the hard errors of the project's own code. Soft errors
+// (unused variables and imports) do not stop the core, and failed imports
+// are expected: see opaqueImporter.
+func (a *analyzer) typeError(err error) {
+	var terr types.Error
+	if !errors.As(err, &terr) || terr.Soft || strings.HasPrefix(terr.Msg, "could not import") {
+		return
+	}
+	pos := a.fset.Position(terr.Pos)
+	a.issues = append(a.issues, Issue{
+		File: pos.Filename, Line: pos.Line, Column: pos.Column,
+		Code: codeType, Message: terr.Msg, IsError: true,
+	})
+}
+
+// imports checks every import resolves to something the core can load.
+func (a *analyzer) imports(f *ast.File) {
+	for _, spec := range f.Imports {
+		p := strings.Trim(spec.Path.Value, "\"`")
+		switch {
+		case builtinAPI[p] != nil, generatedPackages[p], a.declared[p]:
+		case a.installed[p]:
+			a.report(spec, true, codeImport,
+				fmt.Sprintf("package %q is installed but not declared in the manifest", p),
+				"run: tsuki pkg add "+p)
+		case goStdlibRoots[strings.SplitN(p, "/", 2)[0]]:
+			a.report(spec, true, codeStdlib,
+				fmt.Sprintf("Go standard library package %q is not available on the board", p),
+				"supported standard packages: fmt, time, math, strconv")
+		default:
+			a.report(spec, true, codeImport,
+				fmt.Sprintf("package %q is not installed or declared in the manifest", p),
+				fmt.Sprintf("run: tsuki pkg install %s && tsuki pkg add %s", p, p))
+		}
+	}
+}
+
+// visit flags unsupported constructs and unknown built-in names.
+func (a *analyzer) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.GoStmt:
+		a.report(n, true, codeGoroutine, "goroutines are not supported on the board",
+			"do the work from loop() and pace it with arduino.Millis()")
+	case *ast.ChanType:
+		a.report(n, true, codeChannel, "channels are not supported on the board",
+			"share state through package-level variables")
+	case *ast.SelectStmt:
+		a.report(n, true, codeChannel, "select is not supported on the board",
+			"poll each source in turn from loop()")
+	case *ast.MapType:
+		a.report(n, true, codeMap, "maps are not supported on the board",
+			"use a fixed-size array of key/value structs")
+	case *ast.InterfaceType:
+		a.report(n, true, codeInterface, "interfaces are not supported on the board",
+			"use concrete types, or a struct with a kind field and a switch")
+	case *ast.TypeAssertExpr:
+		a.report(n, true, codeInterface, "type assertions are not supported on the board",
+			"use concrete types, or a struct with a kind field and a switch")
+	case *ast.FuncLit:
+		a.report(n, true, codeClosure, "function literals (closures) are not supported — tsuki-core drops their body",
+			"declare a top-level function and pass it by name")
+		// One report per closure, not one per construct inside it.
+		return false
+	case *ast.DeferStmt:
+		a.report(n, false, codeDefer, "defer runs the call immediately on the board, not when the function returns",
+			"call it explicitly before each return")
+	case *ast.Ident:
+		if obj, ok := a.info.Uses[n]; ok && obj == types.Universe.Lookup("any") {
+			a.report(n, true, codeInterface, "any is an interface, which is not supported on the board",
+				"use a concrete type")
+		}
+	case *ast.SelectorExpr:
+		return a.selector(n)
+	}
+	return true
+}
+
+// selector checks pkg.Name (and arduino.Sub.Name) against builtinAPI.
+func (a *analyzer) selector(sel *ast.SelectorExpr) bool {
+	// arduino.Serial.Begin: the core lowercases Serial to find the package.
+	if inner, ok := sel.X.(*ast.SelectorExpr); ok {
+		if p, ok := a.importPath(inner.X); ok && p == "arduino" {
+			sub := strings.ToLower(inner.Sel.Name)
+			api := builtinAPI[sub]
+			if api == nil {
+				a.report(inner, true, codeUndefined,
+					fmt.Sprintf("arduino.%s is not part of the tsuki Arduino API", inner.Sel.Name),
+					"sub-objects are Serial, Wire, SPI, Servo and LCD")
+				return false
+			}
+			if !api[sel.Sel.Name] {
+				a.report(sel.Sel, true, codeUndefined,
+					fmt.Sprintf("arduino.%s.%s is not part of the tsuki Arduino API", inner.Sel.Name, sel.Sel.Name),
+					didYouMean("arduino."+inner.Sel.Name+".", suggest(sel.Sel.Name, api)))
+			}
+			return false
+		}
+	}
+
+	p, ok := a.importPath(sel.X)
+	if !ok {
+		return true
+	}
+	if a.typeRefs[sel] {
+		return false
+	}
+	api := builtinAPI[p]
+	if api == nil || api[sel.Sel.Name] {
+		return false
+	}
+	// Let the chained form above handle arduino.Serial and friends.
+	if p == "arduino" && builtinAPI[strings.ToLower(sel.Sel.Name)] != nil {
+		return false
+	}
+
+	prefix := path.Base(p) + "."
+	if p == "arduino" {
+		a.report(sel.Sel, true, codeUndefined,
+			fmt.Sprintf("arduino.%s is not part of the tsuki Arduino API", sel.Sel.Name),
+			didYouMean(prefix, suggest(sel.Sel.Name, api)))
+	} else {
+		hint := didYouMean(prefix, suggest(sel.Sel.Name, api))
+		if hint == "" {
+			hint = fmt.Sprintf("supported in %s: %s", p, strings.Join(sortedNames(api), ", "))
+		}
+		a.report(sel.Sel, true, codeStdlib,
+			fmt.Sprintf("%s%s is not supported on the board", prefix, sel.Sel.Name), hint)
+	}
+	return false
+}
+
+// collectTypeRefs records the selectors that appear where a type is expected.
+func (a *analyzer) collectTypeRefs(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.Field:
+		a.typeRef(n.Type)
+	case *ast.ValueSpec:
+		a.typeRef(n.Type)
+	case *ast.TypeSpec:
+		a.typeRef(n.Type)
+	case *ast.CompositeLit:
+		a.typeRef(n.Type)
+	case *ast.CallExpr:
+		if id, ok := n.Fun.(*ast.Ident); ok && (id.Name == "new" || id.Name == "make") && len(n.Args) > 0 {
+			a.typeRef(n.Args[0])
+		}
+	}
+	return true
+}
+
+func (a *analyzer) typeRef(e ast.Expr) {
+	switch e := e.(type) {
+	case *ast.SelectorExpr:
+		a.typeRefs[e] = true
+	case *ast.StarExpr:
+		a.typeRef(e.X)
+	case *ast.ArrayType:
+		a.typeRef(e.Elt)
+	case *ast.Ellipsis:
+		a.typeRef(e.Elt)
+	case *ast.MapType:
+		a.typeRef(e.Key)
+		a.typeRef(e.Value)
+	case *ast.ChanType:
+		a.typeRef(e.Value)
+	}
+}
+
+// importPath reports the import path when expr names an imported package.
+func (a *analyzer) importPath(expr ast.Expr) (string, bool) {
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	pkg, ok := a.info.Uses[id].(*types.PkgName)
+	if !ok {
+		return "", false
+	}
+	return pkg.Imported().Path(), true
+}
+
+// entryPoints requires func setup() and func loop() somewhere in the package.
+func (a *analyzer) entryPoints(files []*ast.File) {
+	found := map[string]bool{}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil {
+				continue
+			}
+			name := fn.Name.Name
+			if name != "setup" && name != "loop" {
+				continue
+			}
+			found[name] = true
+			if fn.Type.Params.NumFields() > 0 || fn.Type.Results.NumFields() > 0 {
+				a.report(fn.Name, true, codeEntryPoint,
+					fmt.Sprintf("%s must take no arguments and return nothing", name),
+					fmt.Sprintf("declare it as: func %s() { … }", name))
+			}
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+	for _, name := range []string{"setup", "loop"} {
+		if !found[name] {
+			a.report(files[0].Name, true, codeEntryPoint,
+				fmt.Sprintf("no %s function — every sketch needs setup and loop", name),
+				fmt.Sprintf("add: func %s() { … }", name))
+		}
+	}
+}
+
+func didYouMean(prefix, name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf("did you mean %s%s?", prefix, name)
+}
+
+func sortedNames(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for n := range set {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
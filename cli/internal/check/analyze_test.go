@@ -0,0 +1,257 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// analyze runs Analyze on src as the project's only file, with "ws2812"
+// declared and "ws2812" and "dht" installed.
+func analyze(t *testing.T, src string) []Issue {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	issues := Analyze([]string{file}, []string{"ws2812"}, []string{"ws2812", "dht"})
+	for i := range issues {
+		if issues[i].File != file {
+			t.Errorf("issue %+v is not in %s", issues[i], file)
+		}
+		issues[i].File = ""
+	}
+	return issues
+}
+
+// pos is the part of an Issue the rule table checks.
+type pos struct {
+	Line, Column int
+	Code         string
+	IsError      bool
+}
+
+func positions(issues []Issue) []pos {
+	out := []pos{}
+	for _, i := range issues {
+		out = append(out, pos{i.Line, i.Column, i.Code, i.IsError})
+	}
+	return out
+}
+
+const entryPoints = "\nfunc setup() {}\n\nfunc loop() {}\n"
+
+func TestAnalyzeRules(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []pos
+	}{
+		{"goroutine", `package main
+
+func blink() {}
+
+func setup() {
+	go blink()
+}
+
+func loop() {}
+`, []pos{{6, 2, codeGoroutine, true}}},
+
+		{"channel", `package main
+
+var done chan bool
+
+func setup() {
+	select {}
+}
+
+func loop() {}
+`, []pos{{3, 10, codeChannel, true}, {6, 2, codeChannel, true}}},
+
+		{"map", `package main
+
+var pins = map[string]int{}
+` + entryPoints, []pos{{3, 12, codeMap, true}}},
+
+		{"interface", `package main
+
+type Shape interface{ Area() int }
+
+var v any
+
+func setup() {
+	_ = v.(int)
+}
+
+func loop() {}
+`, []pos{
+			{3, 12, codeInterface, true},
+			{5, 7, codeInterface, true},
+			{8, 6, codeInterface, true},
+		}},
+
+		{"closure", `package main
+
+func setup() {
+	f := func() { go setup() }
+	f()
+}
+
+func loop() {}
+`, []pos{{4, 7, codeClosure, true}}},
+
+		{"defer", `package main
+
+func setup() {
+	defer loop()
+}
+
+func loop() {}
+`, []pos{{4, 2, codeDefer, false}}},
+
+		{"missing loop", `package main
+
+func setup() {}
+`, []pos{{1, 9, codeEntryPoint, true}}},
+
+		{"missing setup and loop", `package main
+`, []pos{{1, 9, codeEntryPoint, true}, {1, 9, codeEntryPoint, true}}},
+
+		{"entry point signature", `package main
+
+func setup(pin int) {}
+
+func loop() {}
+`, []pos{{3, 6, codeEntryPoint, true}}},
+
+		{"unknown arduino name", `package main
+
+import "arduino"
+
+func setup() {
+	arduino.PinMod(13, arduino.OUTPUT)
+	arduino.Serial.Begn(9600)
+	arduino.Radio.Begin()
+}
+
+func loop() {}
+`, []pos{
+			{6, 10, codeUndefined, true},
+			{7, 17, codeUndefined, true},
+			{8, 2, codeUndefined, true},
+		}},
+
+		{"imports", `package main
+
+import (
+	"dht"
+	"net/http"
+	"servo2"
+	"ws2812"
+)
+
+var _ = dht.New
+var _ = http.Get
+var _ = servo2.New
+var _ = ws2812.New
+` + entryPoints, []pos{
+			{4, 2, codeImport, true},
+			{5, 2, codeStdlib, true},
+			{6, 2, codeImport, true},
+		}},
+
+		{"unsupported stdlib name", `package main
+
+import "strconv"
+
+var _ = strconv.Quote("x")
+` + entryPoints, []pos{{5, 17, codeStdlib, true}}},
+
+		{"type error", `package main
+
+func setup() {
+	var n int = "one"
+	_ = n
+}
+
+func loop() {}
+`, []pos{{4, 14, codeType, true}}},
+
+		{"parse error", `package main
+
+func setup() {
+	if {
+	}
+}
+
+func loop() {}
+`, []pos{{4, 5, codeParse, true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := positions(analyze(t, tt.src))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %+v\nwant     %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeCleanSketch(t *testing.T) {
+	issues := analyze(t, `package main
+
+import (
+	"arduino"
+	"buildinfo"
+	"fmt"
+	"time"
+	"ws2812"
+)
+
+type Strip struct {
+	leds  ws2812.Strip
+	count int
+}
+
+var strip = Strip{count: 8}
+
+func setup() {
+	arduino.PinMode(arduino.LED_BUILTIN, arduino.OUTPUT)
+	arduino.Serial.Begin(9600)
+	fmt.Println(buildinfo.Version)
+}
+
+func loop() {
+	for i := 0; i < strip.count; i++ {
+		arduino.DigitalWrite(arduino.LED_BUILTIN, arduino.HIGH)
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+`)
+	if len(issues) != 0 {
+		t.Errorf("clean sketch reported %+v", issues)
+	}
+}
+
+func TestAnalyzeHints(t *testing.T) {
+	issues := analyze(t, `package main
+
+import "arduino"
+
+func setup() {
+	arduino.PinMod(13, arduino.OUTPUT)
+}
+
+func loop() {}
+`)
+	want := []Issue{{
+		Line: 6, Column: 10, EndColumn: 16, Code: codeUndefined, IsError: true,
+		Message: "arduino.PinMod is not part of the tsuki Arduino API",
+		Hint:    "did you mean arduino.PinMode?",
+	}}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %+v\nwant     %+v", issues, want)
+	}
+}
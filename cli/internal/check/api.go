@@ -0,0 +1,164 @@
+package check
+
+import (
+	"sort"
+	"strings"
+)
+
+// ── Built-in API surface ──────────────────────────────────────────────────────
+//
+// The packages tsuki-core maps without any tsukilib package, and the names
+// each one exposes. Keep in step with src/runtime/mod.rs: a name missing
+// here is reported as unknown before the core ever sees it.
+
+func names(list ...string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, n := range list {
+		set[n] = true
+	}
+	return set
+}
+
+var (
+	apiFmt = names("Print", "Println", "Printf", "Fprintf", "Sprintf", "Errorf")
+
+	apiTime = names("Sleep", "Now", "Since", "Second", "Millisecond", "Microsecond")
+
+	apiMath = names(
+		"Abs", "Sqrt", "Cbrt", "Pow", "Pow10",
+		"Sin", "Cos", "Tan", "Asin", "Acos", "Atan", "Atan2",
+		"Sinh", "Cosh", "Tanh", "Exp", "Exp2", "Log", "Log2", "Log10",
+		"Floor", "Ceil", "Round", "Trunc", "Mod", "Remainder", "Hypot", "Min", "Max",
+		"Pi", "E", "Phi", "Sqrt2", "Ln2", "Log2E", "Log10E",
+		"MaxFloat64", "SmallestNonzeroFloat64", "Inf", "NaN", "IsNaN", "IsInf",
+	)
+
+	apiStrconv = names(
+		"Itoa", "Atoi", "FormatInt", "FormatFloat",
+		"ParseFloat", "ParseInt", "ParseBool", "FormatBool",
+	)
+
+	apiArduino = names(
+		"pinMode", "PinMode", "digitalWrite", "DigitalWrite", "digitalRead", "DigitalRead",
+		"analogRead", "AnalogRead", "analogWrite", "AnalogWrite", "analogReference", "AnalogReference",
+		"delay", "Delay", "delayMicroseconds", "DelayMicroseconds",
+		"millis", "Millis", "micros", "Micros",
+		"map", "Map", "constrain", "Constrain", "abs", "Abs", "min", "Min", "max", "Max",
+		"sqrt", "Sqrt", "pow", "Pow", "random", "Random", "randomSeed", "RandomSeed",
+		"tone", "Tone", "noTone", "NoTone", "pulseIn", "PulseIn", "pulseInLong", "PulseInLong",
+		"shiftOut", "ShiftOut", "shiftIn", "ShiftIn",
+		"attachInterrupt", "AttachInterrupt", "detachInterrupt", "DetachInterrupt",
+		"interrupts", "Interrupts", "noInterrupts", "NoInterrupts",
+		"SerialBegin", "serialBegin", "SerialEnd", "SerialPrint", "serialPrint",
+		"SerialPrintln", "serialPrintln", "SerialAvailable", "SerialRead",
+		"SerialReadString", "SerialFlush",
+		"HIGH", "LOW", "INPUT", "OUTPUT", "INPUT_PULLUP", "LED_BUILTIN", "LSBFIRST", "MSBFIRST",
+		"A0", "A1", "A2", "A3", "A4", "A5", "CHANGE", "RISING", "FALLING",
+	)
+
+	apiWire = names(
+		"Begin", "BeginTransmission", "EndTransmission", "RequestFrom", "Write",
+		"Read", "Available", "SetClock", "OnReceive", "OnRequest",
+	)
+
+	apiSPI = names(
+		"Begin", "End", "Transfer", "Transfer16", "BeginTransaction", "EndTransaction",
+		"SetBitOrder", "SetDataMode", "SetClockDivider",
+	)
+
+	apiSerial = names(
+		"Begin", "End", "Print", "Println", "Write", "Read", "Peek", "Available",
+		"Flush", "ParseInt", "ParseFloat", "ReadString", "Find",
+	)
+
+	apiServo = names("Attach", "Write", "WriteMicroseconds", "Read", "Attached", "Detach")
+
+	apiLiquidCrystal = names(
+		"Begin", "Clear", "Home", "Print", "SetCursor", "Blink", "NoBlink", "Cursor",
+		"NoCursor", "Display", "NoDisplay", "ScrollDisplayLeft", "ScrollDisplayRight",
+	)
+
+	// testing and sim are only compiled by tsuki test, on the host.
+	apiTesting = names("T")
+
+	apiSim = names(
+		"SetPin", "Pin", "PinMode", "SetMillis", "SetMicros", "Advance",
+		"SerialInput", "SerialOutput", "Reset",
+	)
+)
+
+// builtinAPI maps every import path the core knows to its names.
+var builtinAPI = map[string]map[string]bool{
+	"fmt":           apiFmt,
+	"time":          apiTime,
+	"math":          apiMath,
+	"strconv":       apiStrconv,
+	"arduino":       apiArduino,
+	"wire":          apiWire,
+	"Wire":          apiWire,
+	"spi":           apiSPI,
+	"SPI":           apiSPI,
+	"serial":        apiSerial,
+	"Serial":        apiSerial,
+	"servo":         apiServo,
+	"Servo":         apiServo,
+	"lcd":           apiLiquidCrystal,
+	"LiquidCrystal": apiLiquidCrystal,
+	"testing":       apiTesting,
+	"sim":           apiSim,
+}
+
+// generatedPackages are written by the CLI itself at build time.
+var generatedPackages = names("buildinfo")
+
+// goStdlibRoots are standard-library import roots a Go programmer reaches
+// for that have no mapping on the board.
+var goStdlibRoots = names(
+	"bufio", "bytes", "container", "context", "crypto", "encoding", "errors",
+	"flag", "hash", "io", "log", "net", "os", "path", "reflect", "regexp",
+	"runtime", "sort", "strings", "sync", "syscall", "unicode", "unsafe",
+)
+
+// suggest returns the name in api closest to name, or "" when none is close:
+// a case-insensitive match first, then the smallest edit distance up to 2.
+func suggest(name string, api map[string]bool) string {
+	candidates := make([]string, 0, len(api))
+	for n := range api {
+		candidates = append(candidates, n)
+	}
+	sort.Strings(candidates)
+
+	for _, c := range candidates {
+		if strings.EqualFold(c, name) {
+			return c
+		}
+	}
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(name, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
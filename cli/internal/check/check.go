@@ -5,109 +5,215 @@
 package check
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/tsuki/cli/internal/build"
 	"github.com/tsuki/cli/internal/core"
 	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/pkgmgr"
 	"github.com/tsuki/cli/internal/ui"
 )
 
 // Options controls the check command.
 type Options struct {
-	Board   string
-	Verbose bool
-	CoreBin string
+	Board     string
+	Verbose   bool
+	CoreBin   string
+	SourceMap bool
+	// Defines are extra buildinfo constants, merged with the manifest's
+	// build.defines (see build.MergeDefines). Nil means the manifest's only.
+	Defines map[string]string
+	// Transpiler validates the sources. Nil means core.New(CoreBin, Verbose);
+	// Run closes the ones it creates.
+	Transpiler core.Transpiler
+	// NoAnalyze skips the Go-side analysis and leaves everything to the core.
+	NoAnalyze bool
 }
 
 // Report holds the results of a check run.
 type Report struct {
-	Files    int
-	Warnings []Issue
-	Errors   []Issue
+	Files    int     `json:"files"`
+	Warnings []Issue `json:"warnings"`
+	Errors   []Issue `json:"errors"`
 }
 
 // Issue is a single warning or error found during check.
+// Line and Column are 1-based; 0 means the core gave no location.
+// EndColumn, when set, is the exclusive end of the span on Line.
 type Issue struct {
-	File    string
-	Line    int
-	Message string
-	IsError bool
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndColumn int    `json:"end_column,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+	// Hint suggests a fix; only the Go-side analysis sets it.
+	Hint    string `json:"hint,omitempty"`
+	IsError bool   `json:"is_error"`
 }
 
-// Run checks all .go files in the project src/ directory.
+// Run checks all .go files in the project src/ directory against the same
+// libs dir and packages a build would use.
 func Run(projectDir string, m *manifest.Manifest, opts Options) (*Report, error) {
 	board := opts.Board
 	if board == "" {
 		board = m.Board
 	}
 
-	transpiler := core.New(opts.CoreBin, opts.Verbose)
+	srcDir := filepath.Join(projectDir, "src")
+	goFiles, _, err := build.SourceFiles(srcDir)
+	if err != nil || len(goFiles) == 0 {
+		return nil, fmt.Errorf("no .go files found in %s", srcDir)
+	}
+
+	pkgNames := m.PackageNames()
+
+	// The Go-side analysis needs no core and explains the common mistakes
+	// better than a transpile error would; when it finds errors the core
+	// is not run at all.
+	var analysis []Issue
+	if !opts.NoAnalyze {
+		var installed []string
+		pkgs, _ := pkgmgr.ListInstalled()
+		for _, p := range pkgs {
+			installed = append(installed, p.Name)
+		}
+		analysis = Analyze(goFiles, pkgNames, installed)
+		for _, issue := range analysis {
+			if issue.IsError {
+				report := &Report{Files: len(goFiles), Warnings: []Issue{}, Errors: []Issue{}}
+				report.add(analysis...)
+				return report, nil
+			}
+		}
+	}
+
+	transpiler := opts.Transpiler
+	if transpiler == nil {
+		transpiler = core.New(opts.CoreBin, opts.Verbose)
+		defer transpiler.Close()
+	}
 	if !transpiler.Installed() {
 		return nil, fmt.Errorf(
 			"tsuki-core not found — install it or set core_binary in your config",
 		)
 	}
 
-	srcDir := filepath.Join(projectDir, "src")
-	goFiles, err := filepath.Glob(filepath.Join(srcDir, "*.go"))
-	if err != nil || len(goFiles) == 0 {
-		return nil, fmt.Errorf("no .go files found in %s", srcDir)
+	for _, name := range pkgNames {
+		if ok, _ := pkgmgr.IsInstalled(name); !ok {
+			return nil, fmt.Errorf(
+				"package %q declared in %s is not installed\n"+
+					"  Run: tsuki pkg install %s", name, manifest.FileName, name,
+			)
+		}
+	}
+
+	defines := opts.Defines
+	if defines == nil {
+		if defines, err = build.MergeDefines(m.Build.Defines, nil); err != nil {
+			return nil, err
+		}
+	}
+	meta := build.ProjectMetadata(projectDir, m, board, "")
+	libsDir, corePkgNames, err := build.CoreLibs(
+		filepath.Join(projectDir, m.Build.OutputDir), meta, defines, pkgmgr.LibsDir(), pkgNames,
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	ui.SectionTitle(fmt.Sprintf("Checking  [board: %s]", board))
 
-	report := &Report{Files: len(goFiles)}
+	report := &Report{Files: len(goFiles), Warnings: []Issue{}, Errors: []Issue{}}
+	report.add(analysis...)
 
+	reqs := make([]core.TranspileRequest, 0, len(goFiles))
 	for _, goFile := range goFiles {
 		ui.Info(fmt.Sprintf("Checking %s…", filepath.Base(goFile)))
+		reqs = append(reqs, core.TranspileRequest{
+			InputFile: goFile,
+			Board:     board,
+			SourceMap: opts.SourceMap || m.Build.SourceMap,
+			LibsDir:   libsDir,
+			PkgNames:  corePkgNames,
+		})
+	}
 
-		srcBytes, err := os.ReadFile(goFile)
-		if err != nil {
-			return nil, fmt.Errorf("cannot read %s: %w", goFile, err)
-		}
-
-		// Convert []manifest.Package -> []string
-		pkgNames := make([]string, 0, len(m.Packages))
-		for _, p := range m.Packages {
-			pkgNames = append(pkgNames, p.Name)
-		}
-
-		warnings, errors, err := transpiler.Check(
-			string(srcBytes),
-			filepath.Base(goFile),
-			board,
-			pkgNames,
-		)
+	// Per-file failures are in the results; only a core too old to check
+	// at all stops the run.
+	results, err := transpiler.Check(reqs)
+	var upgrade *core.UpgradeError
+	if errors.As(err, &upgrade) {
+		return nil, err
+	}
 
-		for _, w := range warnings {
-			report.Warnings = append(report.Warnings, Issue{
-				File:    goFile,
-				Message: w,
-				IsError: false,
-			})
+	for _, res := range results {
+		failed := false
+		for _, d := range res.Diagnostics {
+			issue := Issue{
+				File:    res.InputFile,
+				Line:    d.Line(),
+				Column:  d.Column(),
+				Code:    d.Code,
+				Message: d.Message,
+				IsError: d.IsError(),
+			}
+			if d.File != "" {
+				issue.File = d.File
+			}
+			if issue.IsError {
+				failed = true
+				report.Errors = append(report.Errors, issue)
+			} else {
+				report.Warnings = append(report.Warnings, issue)
+			}
 		}
 
-		for _, e := range errors {
+		// The core failed without saying why: still count it as an error.
+		if res.Err != nil && !failed {
 			report.Errors = append(report.Errors, Issue{
-				File:    goFile,
-				Message: e,
+				File:    res.InputFile,
+				Message: res.Err.Error(),
 				IsError: true,
 			})
 		}
+	}
 
-		if err != nil {
-			report.Errors = append(report.Errors, Issue{
-				File:    goFile,
-				Message: err.Error(),
-				IsError: true,
-			})
+	return report, nil
+}
+
+// add files each issue under Errors or Warnings.
+func (r *Report) add(issues ...Issue) {
+	for _, i := range issues {
+		if i.IsError {
+			r.Errors = append(r.Errors, i)
+		} else {
+			r.Warnings = append(r.Warnings, i)
 		}
 	}
+}
 
-	return report, nil
+// location renders "main.go:12:5", dropping the parts the core did not report.
+func (i Issue) location() string {
+	file := filepath.Base(i.File)
+	switch {
+	case i.Line > 0 && i.Column > 0:
+		return fmt.Sprintf("%s:%d:%d", file, i.Line, i.Column)
+	case i.Line > 0:
+		return fmt.Sprintf("%s:%d", file, i.Line)
+	}
+	return file
+}
+
+// printHint shows the suggested fix under the issue, if there is one.
+func (i Issue) printHint() {
+	if i.Hint != "" {
+		ui.ColorMuted.Printf("      ↳ %s\n", i.Hint)
+	}
 }
 
 // PrintReport renders the check report to stdout.
@@ -122,35 +228,25 @@ func PrintReport(report *Report) {
 	if len(report.Warnings) > 0 {
 		ui.SectionTitle(fmt.Sprintf("Warnings (%d)", len(report.Warnings)))
 		for _, w := range report.Warnings {
-			file := filepath.Base(w.File)
-			if w.Line > 0 {
-				ui.Warn(fmt.Sprintf("%s:%d  %s", file, w.Line, w.Message))
-			} else {
-				ui.Warn(fmt.Sprintf("%s  %s", file, w.Message))
-			}
+			ui.Warn(fmt.Sprintf("%s  %s", w.location(), w.Message))
+			w.printHint()
 		}
 	}
 
 	if len(report.Errors) > 0 {
 		ui.SectionTitle(fmt.Sprintf("Errors (%d)", len(report.Errors)))
 		for _, e := range report.Errors {
-			file := filepath.Base(e.File)
-			if e.Line > 0 {
-				ui.Fail(fmt.Sprintf("%s:%d  %s", file, e.Line, e.Message))
-			} else {
-				ui.Fail(fmt.Sprintf("%s  %s", file, e.Message))
-			}
+			ui.Fail(fmt.Sprintf("%s  %s", e.location(), e.Message))
+			e.printHint()
 		}
 
 		// Rich traceback for errors
 		frames := make([]ui.Frame, 0, len(report.Errors))
 		for _, e := range report.Errors {
-			frames = append(frames, ui.Frame{
-				File: e.File,
-				Line: e.Line,
-				Func: "check",
-				Code: []ui.CodeLine{{Number: e.Line, Text: e.Message, IsPointer: true}},
-			})
+			ref := ui.SourceRef{File: e.File, Line: e.Line, Column: e.Column, EndColumn: e.EndColumn}
+			if ref.Located() {
+				frames = append(frames, ui.SourceFrame(ref, "check"))
+			}
 		}
 		if len(frames) > 0 {
 			fmt.Fprintln(os.Stderr, "")
@@ -0,0 +1,163 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tsuki/cli/internal/manifest"
+)
+
+// fakeCore answers the capability probe and reports one error at 4:7 for
+// every file it checks, writing its arguments to $TSUKI_FAKE_CORE_ARGS.
+const fakeCore = `#!/bin/sh
+if [ "$1" = "--capabilities" ]; then
+  echo '{"version":"9.9.9","diagnostics":1,"capabilities":["check","source-map","libs-dir","packages","diagnostics-json"]}'
+  exit 0
+fi
+printf '%s\n' "$@" > "$TSUKI_FAKE_CORE_ARGS"
+echo '{"version":1,"diagnostics":[{"severity":"error","code":"E0425","file":"'"$1"'","range":{"start":{"line":4,"column":7},"end":{"line":4,"column":10}},"message":"undefined: foo","notes":[],"fixes":[]}]}' >&2
+exit 1
+`
+
+// setupProject writes a project with one source file and one declared
+// package, installs the package into a private libs dir and returns the
+// project dir, its manifest and the path of the fake core.
+func setupProject(t *testing.T) (string, *manifest.Manifest, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake core is a shell script")
+	}
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+	t.Setenv("tsuki_LIBS", filepath.Join(tmp, "libs"))
+	t.Setenv("TSUKI_FAKE_CORE_ARGS", filepath.Join(tmp, "args"))
+
+	writeFile(t, filepath.Join(tmp, "libs", "ws2812", "1.0.0", "tsukilib.toml"),
+		"[package]\nname = \"ws2812\"\nversion = \"1.0.0\"\n")
+
+	dir := filepath.Join(tmp, "proj")
+	writeFile(t, filepath.Join(dir, "src", "main.go"),
+		"package main\n\nfunc setup() {}\n\nfunc loop() {}\n")
+
+	bin := filepath.Join(tmp, "tsuki-core")
+	writeFile(t, bin, fakeCore)
+	if err := os.Chmod(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := manifest.Default("proj", "uno")
+	m.Packages = []manifest.Package{{Name: "ws2812", Version: "^1.0.0"}}
+	return dir, m, bin
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// coreArgs returns the arguments of the last fake core run, by flag.
+func coreArgs(t *testing.T) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(os.Getenv("TSUKI_FAKE_CORE_ARGS"))
+	if err != nil {
+		t.Fatalf("the core was not run: %v", err)
+	}
+	args := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	flags := map[string]string{"input": args[0]}
+	for i := 1; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			continue
+		}
+		if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			flags[args[i]] = args[i+1]
+			i++
+		} else {
+			flags[args[i]] = ""
+		}
+	}
+	return flags
+}
+
+func TestRunPassesBuildSettingsToCore(t *testing.T) {
+	dir, m, bin := setupProject(t)
+
+	if _, err := Run(dir, m, Options{CoreBin: bin, SourceMap: true, NoAnalyze: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	args := coreArgs(t)
+	if want := filepath.Join(dir, "src", "main.go"); args["input"] != want {
+		t.Errorf("input = %q, want %q", args["input"], want)
+	}
+	if args["--board"] != "uno" {
+		t.Errorf("--board = %q, want uno", args["--board"])
+	}
+	if _, ok := args["--check"]; !ok {
+		t.Error("--check not passed")
+	}
+	libs := filepath.Join(dir, "build", ".cache", "libs")
+	if args["--libs-dir"] != libs {
+		t.Errorf("--libs-dir = %q, want the build's libs dir %q", args["--libs-dir"], libs)
+	}
+	if _, err := os.Stat(filepath.Join(libs, "ws2812", "1.0.0", "tsukilib.toml")); err != nil {
+		t.Errorf("installed package missing from the libs dir: %v", err)
+	}
+	if args["--packages"] != "ws2812,buildinfo" {
+		t.Errorf("--packages = %q, want ws2812,buildinfo", args["--packages"])
+	}
+	if _, ok := args["--source-map"]; !ok {
+		t.Error("--source-map not passed")
+	}
+}
+
+func TestRunSourceMapFromManifest(t *testing.T) {
+	dir, m, bin := setupProject(t)
+
+	if _, err := Run(dir, m, Options{CoreBin: bin, NoAnalyze: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := coreArgs(t)["--source-map"]; ok {
+		t.Error("--source-map passed without source maps enabled")
+	}
+
+	m.Build.SourceMap = true
+	if _, err := Run(dir, m, Options{CoreBin: bin, NoAnalyze: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := coreArgs(t)["--source-map"]; !ok {
+		t.Error("--source-map not passed with build.source_map set")
+	}
+}
+
+func TestRunIssueLocations(t *testing.T) {
+	dir, m, bin := setupProject(t)
+
+	report, err := Run(dir, m, Options{CoreBin: bin, NoAnalyze: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 1 {
+		t.Fatalf("got %d errors, want 1: %+v", len(report.Errors), report.Errors)
+	}
+	got := report.Errors[0]
+	want := Issue{
+		File:    filepath.Join(dir, "src", "main.go"),
+		Line:    4,
+		Column:  7,
+		Code:    "E0425",
+		Message: "undefined: foo",
+		IsError: true,
+	}
+	if got != want {
+		t.Errorf("issue = %+v, want %+v", got, want)
+	}
+}
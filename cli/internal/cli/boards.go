@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/core"
 	"github.com/tsuki/cli/internal/manifest"
 	"github.com/tsuki/cli/internal/ui"
 )
@@ -13,11 +16,11 @@ import (
 // ── boards ────────────────────────────────────────────────────────────────────
 
 type boardInfo struct {
-	ID      string
-	Name    string
-	FlashKB int
-	RAMKB   int
-	FQBN    string
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	FlashKB int    `json:"flash_kb"`
+	RAMKB   int    `json:"ram_kb"`
+	FQBN    string `json:"fqbn"`
 }
 
 var boardCatalog = []boardInfo{
@@ -48,7 +51,11 @@ func newBoardsListCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "list",
 		Short: "List all supported boards",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if machineOutput() {
+				return emit(boardCatalog)
+			}
+
 			ui.SectionTitle("Supported Boards")
 			fmt.Println()
 
@@ -64,26 +71,61 @@ func newBoardsListCmd() *cobra.Command {
 				ui.ColorMuted.Printf("  %s\n", b.FQBN)
 			}
 			fmt.Println()
+			return nil
 		},
 	}
 }
 
 func newBoardsDetectCmd() *cobra.Command {
-	return &cobra.Command{
+	var backendName string
+
+	cmd := &cobra.Command{
 		Use:   "detect",
 		Short: "Detect boards connected via USB",
-		Run: func(cmd *cobra.Command, args []string) {
-			ui.SectionTitle("Detecting connected boards")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, m, _ := manifest.Find(projectDir())
+			b, err := backend.New(backend.Resolve(backendName, m, cfg), backend.Tools{
+				ArduinoCLI:  cfg.ArduinoCLI,
+				FlashBinary: cfg.FlashBinary,
+				PlatformIO:  cfg.PlatformIO,
+				ProjectDir:  projectDir(),
+			})
+			if err != nil {
+				return err
+			}
+
+			ui.SectionTitle(fmt.Sprintf("Detecting connected boards  [%s]", b.Name()))
 			sp := ui.NewSpinner("Scanning serial ports…")
 			sp.Start()
+			ports, err := b.DetectPorts()
+			if err != nil {
+				sp.Stop(false, "detection failed")
+				return err
+			}
+			sp.Stop(true, fmt.Sprintf("%d board(s) found", len(ports)))
 
-			// Shell out to arduino-cli board list
-			import_cmd := "arduino-cli board list"
-			_ = import_cmd
-			sp.Stop(false, "Use `arduino-cli board list` to detect boards")
-			ui.Info("Run: arduino-cli board list")
+			if machineOutput() {
+				if ports == nil {
+					ports = []backend.Port{}
+				}
+				return emit(ports)
+			}
+			if len(ports) == 0 {
+				ui.Info("Connect a board over USB and run this again")
+				return nil
+			}
+			fmt.Println()
+			for _, p := range ports {
+				ui.ColorKey.Printf("  %-16s", p.Address)
+				ui.ColorMuted.Printf("  %s\n", p.Board)
+			}
+			fmt.Println()
+			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "override backend (default from manifest, then config)")
+	return cmd
 }
 
 func hline(n int, ch string) string {
@@ -129,15 +171,45 @@ func newCleanCmd() *cobra.Command {
 
 const Version = "0.1.0"
 
+// versionInfo is `tsuki version` in JSON output modes.
+type versionInfo struct {
+	CLI  string     `json:"cli"`
+	Core *core.Info `json:"core"`
+}
+
 func newVersionCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print version information",
-		Run: func(cmd *cobra.Command, args []string) {
-			ui.PrintConfig("tsuki version", []ui.ConfigEntry{
-				{Key: "cli",  Value: Version,         Comment: "tsuki CLI"},
-				{Key: "core", Value: "(not detected)", Comment: "tsuki-core (Rust transpiler)"},
-			}, false)
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			coreInfo, coreErr := core.Detect(cfg.CoreBinary)
+			if machineOutput() {
+				return emit(versionInfo{CLI: Version, Core: coreInfo})
+			}
+
+			entries := []ui.ConfigEntry{
+				{Key: "cli", Value: Version, Comment: "tsuki CLI"},
+			}
+			if coreInfo == nil {
+				entries = append(entries, ui.ConfigEntry{
+					Key: "core", Value: "(not detected)", Comment: fmt.Sprintf("tsuki-core (Rust transpiler) — %v", coreErr),
+				})
+			} else {
+				caps := strings.Join(coreInfo.Capabilities, ", ")
+				capsComment := "supported features"
+				if coreInfo.Legacy {
+					capsComment = "assumed: core predates --capabilities — upgrade for serve and JSON diagnostics"
+				}
+				entries = append(entries,
+					ui.ConfigEntry{Key: "core", Value: coreInfo.Version, Comment: coreInfo.Path},
+					ui.ConfigEntry{Key: "core_capabilities", Value: caps, Comment: capsComment},
+				)
+			}
+			ui.PrintConfig("tsuki version", entries, false)
+			return nil
 		},
 	}
+	cmd.AddCommand(newVersionBumpCmd())
+	return cmd
 }
@@ -4,43 +4,88 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/tsuki/cli/internal/build"
 	"github.com/tsuki/cli/internal/check"
 	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/report"
 )
 
 func newCheckCmd() *cobra.Command {
 	var board string
+	var defines []string
+	var reports []string
+	var noAnalyze bool
 
 	cmd := &cobra.Command{
 		Use:   "check",
 		Short: "Validate source files for errors and warnings (no output produced)",
+		Long: `Validate the project's Go sources without producing any output.
+
+Before tsuki-core runs, the sources are analysed in Go: goroutines,
+channels, maps, interfaces, closures, unsupported standard-library calls,
+unknown arduino.* names, imports missing from the manifest and a missing
+setup or loop are reported with their position and a suggested fix. When
+that analysis finds errors, the core is not run.`,
 		Example: `  tsuki check
-  tsuki check --board esp32`,
+  tsuki check --board esp32
+  tsuki check --report sarif=tsuki.sarif --report junit=tsuki.xml`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			dir := projectDir()
-			_, m, err := manifest.Find(dir)
+			specs, err := report.ParseSpecs(reports)
+			if err != nil {
+				return err
+			}
+
+			dir, m, err := manifest.Find(projectDir())
+			if err != nil {
+				return err
+			}
+			merged, err := build.MergeDefines(m.Build.Defines, defines)
 			if err != nil {
 				return err
 			}
 
-			report, err := check.Run(dir, m, check.Options{
-				Board:   board,
-				Verbose: cfg.Verbose,
-				CoreBin: cfg.CoreBinary,
+			rep, err := check.Run(dir, m, check.Options{
+				Board:     board,
+				Verbose:   cfg.Verbose,
+				CoreBin:   cfg.CoreBinary,
+				SourceMap: m.Build.SourceMap,
+				Defines:   merged,
+				NoAnalyze: noAnalyze,
 			})
 			if err != nil {
 				return err
 			}
 
-			check.PrintReport(report)
+			if len(specs) > 0 {
+				doc := reportDocument("check", dir)
+				doc.Findings = checkFindings(rep)
+				if err := writeReports(specs, doc); err != nil {
+					return err
+				}
+			}
+
+			if machineOutput() {
+				if err := emit(rep); err != nil {
+					return err
+				}
+				if len(rep.Errors) > 0 {
+					return reportedError{fmt.Errorf("%d error(s) found", len(rep.Errors))}
+				}
+				return nil
+			}
+
+			check.PrintReport(rep)
 
-			if len(report.Errors) > 0 {
-				return fmt.Errorf("%d error(s) found", len(report.Errors))
+			if len(rep.Errors) > 0 {
+				return fmt.Errorf("%d error(s) found", len(rep.Errors))
 			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&board, "board", "b", "", "target board (overrides manifest)")
+	cmd.Flags().StringArrayVarP(&defines, "define", "D", nil, "set buildinfo.NAME to VALUE (NAME=VALUE, repeatable)")
+	cmd.Flags().BoolVar(&noAnalyze, "no-analyze", false, "skip the Go-side analysis and only run tsuki-core")
+	addReportFlag(cmd, &reports)
 	return cmd
 }
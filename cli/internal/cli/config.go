@@ -149,6 +149,9 @@ func newConfigShowCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if machineOutput() {
+				return emit(c)
+			}
 
 			entries := c.AllEntries()
 			uiEntries := make([]ui.ConfigEntry, len(entries))
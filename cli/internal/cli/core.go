@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+func newCoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "core",
+		Short: "Manage the board cores a project compiles against",
+		Long: `Install, update, remove and list board cores through the project's backend:
+arduino-cli platforms ("arduino:avr"), tsuki-modules cores ("avr") or
+PlatformIO platforms ("atmelavr").
+
+The core a project needs follows from the board in tsuki_package.json.
+'tsuki build --compile' checks it is installed and records its version in
+the firmware's build-info, so two builds can be told apart by core too.`,
+	}
+
+	cmd.AddCommand(
+		newCoreListCmd(),
+		newCoreInstallCmd(),
+		newCoreUpdateCmd(),
+		newCoreRemoveCmd(),
+	)
+	return cmd
+}
+
+// coreManager returns b's core management, or an error naming the backend.
+func coreManager(b backend.Backend) (backend.CoreManager, error) {
+	cm, ok := b.(backend.CoreManager)
+	if !ok {
+		return nil, fmt.Errorf("backend %s cannot list, update or remove cores — only install them", b.Name())
+	}
+	return cm, nil
+}
+
+// projectBoard is the --board flag, else the project's board, else the
+// configured default.
+func projectBoard(flag string, m *manifest.Manifest) string {
+	switch {
+	case flag != "":
+		return flag
+	case m != nil && m.Board != "":
+		return m.Board
+	}
+	return cfg.DefaultBoard
+}
+
+// resolveCore maps an argument — a board or a core ID — to a core. With no
+// argument it is the core of projectBoard.
+func resolveCore(cm backend.CoreManager, arg, boardFlag string, m *manifest.Manifest) (backend.Core, error) {
+	if arg == "" {
+		return cm.Core(projectBoard(boardFlag, m))
+	}
+	if c, err := cm.Core(arg); err == nil {
+		return c, nil
+	}
+	cores, err := cm.Cores()
+	if err != nil {
+		return backend.Core{}, err
+	}
+	for _, c := range cores {
+		if c.ID == arg {
+			return c, nil
+		}
+	}
+	return backend.Core{ID: arg}, nil
+}
+
+// ── core list ─────────────────────────────────────────────────────────────────
+
+// coreStatus is a core in `tsuki core list`.
+type coreStatus struct {
+	backend.Core
+	// Required marks the core of the project's board.
+	Required bool `json:"required"`
+}
+
+func newCoreListCmd() *cobra.Command {
+	var backendName, board string
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List installed cores and the one the project needs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, m, _ := manifest.Find(projectDir())
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+			cm, err := coreManager(b)
+			if err != nil {
+				return err
+			}
+			cores, err := cm.Cores()
+			if err != nil {
+				return err
+			}
+			needed, err := cm.Core(projectBoard(board, m))
+			if err != nil {
+				return err
+			}
+
+			statuses := []coreStatus{}
+			found := false
+			for _, c := range cores {
+				required := c.ID == needed.ID
+				found = found || required
+				statuses = append(statuses, coreStatus{Core: c, Required: required})
+			}
+			if !found {
+				statuses = append(statuses, coreStatus{Core: needed, Required: true})
+			}
+			if machineOutput() {
+				return emit(statuses)
+			}
+
+			ui.SectionTitle(fmt.Sprintf("Board cores  [%s]", b.Name()))
+			fmt.Println()
+			ui.ColorTitle.Printf("  %-24s  %-12s  %s\n", "CORE", "INSTALLED", "LATEST")
+			ui.ColorMuted.Println("  " + hline(66, "─"))
+			for _, s := range statuses {
+				ui.ColorKey.Printf("  %-24s", s.ID)
+				if s.Version == "" {
+					ui.ColorError.Printf("  %-12s", "missing")
+				} else {
+					ui.ColorNumber.Printf("  %-12s", s.Version)
+				}
+				if s.Latest != "" && s.Latest != s.Version {
+					ui.ColorWarn.Printf("  %-12s", s.Latest)
+				} else {
+					ui.ColorMuted.Printf("  %-12s", or(s.Latest, "—"))
+				}
+				if s.Required {
+					ui.ColorMuted.Printf("  ← board %s", projectBoard(board, m))
+				}
+				fmt.Println()
+			}
+			fmt.Println()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to ask (default: the project's)")
+	cmd.Flags().StringVarP(&board, "board", "b", "", "board whose core is marked (default: the project's)")
+	return cmd
+}
+
+// ── core install ──────────────────────────────────────────────────────────────
+
+func newCoreInstallCmd() *cobra.Command {
+	var backendName string
+
+	cmd := &cobra.Command{
+		Use:   "install [board...]",
+		Short: "Install the cores for boards (default: the project's board)",
+		Example: `  tsuki core install
+  tsuki core install esp32 pico`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, m, _ := manifest.Find(projectDir())
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+			boards := args
+			if len(boards) == 0 {
+				boards = []string{projectBoard("", m)}
+			}
+
+			cm, _ := b.(backend.CoreManager)
+			installed := []backend.Core{}
+			for _, board := range boards {
+				ui.Step("core", fmt.Sprintf("board %s via %s", board, b.Name()))
+				if err := b.InstallCore(board); err != nil {
+					return err
+				}
+				if cm == nil {
+					continue
+				}
+				c, err := cm.Core(board)
+				if err != nil {
+					return err
+				}
+				installed = append(installed, c)
+				if !machineOutput() {
+					ui.Success(fmt.Sprintf("%s  (board %s)", c, board))
+				}
+			}
+			if machineOutput() {
+				return emit(installed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to install through (default: the project's)")
+	return cmd
+}
+
+// ── core update ───────────────────────────────────────────────────────────────
+
+func newCoreUpdateCmd() *cobra.Command {
+	var backendName, board string
+
+	cmd := &cobra.Command{
+		Use:   "update [board|core]",
+		Short: "Update a core to its latest release (default: the project's)",
+		Example: `  tsuki core update
+  tsuki core update esp32
+  tsuki core update arduino:avr --backend arduino-cli`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, m, _ := manifest.Find(projectDir())
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+			cm, err := coreManager(b)
+			if err != nil {
+				return err
+			}
+			arg := ""
+			if len(args) > 0 {
+				arg = args[0]
+			}
+			before, err := resolveCore(cm, arg, board, m)
+			if err != nil {
+				return err
+			}
+
+			ui.SectionTitle(fmt.Sprintf("Updating %s  [%s]", before.ID, b.Name()))
+			if err := cm.UpdateCore(before.ID); err != nil {
+				return err
+			}
+			after, err := resolveCore(cm, before.ID, board, m)
+			if err != nil {
+				return err
+			}
+			if machineOutput() {
+				return emit(after)
+			}
+			switch {
+			case before.Version == "":
+				ui.Success(fmt.Sprintf("%s installed", after))
+			case before.Version == after.Version:
+				ui.Success(fmt.Sprintf("%s is up to date", after))
+			default:
+				ui.Success(fmt.Sprintf("%s  %s → %s", after.ID, before.Version, after.Version))
+				ui.Info("The next build records the new version in its build-info")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to update through (default: the project's)")
+	cmd.Flags().StringVarP(&board, "board", "b", "", "board whose core is updated (default: the project's)")
+	return cmd
+}
+
+// ── core remove ───────────────────────────────────────────────────────────────
+
+func newCoreRemoveCmd() *cobra.Command {
+	var backendName string
+
+	cmd := &cobra.Command{
+		Use:     "remove <board|core>",
+		Aliases: []string{"rm", "uninstall"},
+		Short:   "Uninstall a core",
+		Example: `  tsuki core remove esp32`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, m, _ := manifest.Find(projectDir())
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+			cm, err := coreManager(b)
+			if err != nil {
+				return err
+			}
+			c, err := resolveCore(cm, args[0], "", m)
+			if err != nil {
+				return err
+			}
+			if err := cm.RemoveCore(c.ID); err != nil {
+				return err
+			}
+			ui.Success(fmt.Sprintf("Removed %s", c))
+
+			if m != nil {
+				if needed, err := cm.Core(m.Board); err == nil && needed.ID == c.ID {
+					ui.Info(fmt.Sprintf("Board %s needs it — `tsuki build --compile` installs it again", m.Board))
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to remove through (default: the project's)")
+	return cmd
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/build"
+	"github.com/tsuki/cli/internal/dist"
+	"github.com/tsuki/cli/internal/firmware"
+	"github.com/tsuki/cli/internal/flash"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+func newDistCmd() *cobra.Command {
+	var (
+		board  string
+		format string
+		outDir string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dist",
+		Short: "Compile and package the firmware into a distributable archive",
+		Long: `Compile the project and bundle everything needed to flash it elsewhere:
+the firmware image, ELF, build-info, manifest, lock file and a README with
+upload instructions, into <name>-<version>.zip or .tar.gz.
+
+The archive can be flashed with ` + "`tsuki upload --from <archive>`" + `.`,
+		Example: `  tsuki dist
+  tsuki dist --format tar.gz
+  tsuki dist --board esp32 --out release/`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archiveFormat, err := dist.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			dir, m, err := manifest.Find(projectDir())
+			if err != nil {
+				return err
+			}
+			if m == nil {
+				return fmt.Errorf("no %s found — run `tsuki init` first", manifest.FileName)
+			}
+			// The version names the archive, like the project name: it must
+			// not be able to place it outside the output directory.
+			if _, err := manifest.ParseSemVer(m.Version); err != nil {
+				return fmt.Errorf("%s: %w", manifest.FileName, err)
+			}
+
+			res, err := build.Run(dir, m, build.Options{
+				Board:       board,
+				Compile:     true,
+				Verbose:     cfg.Verbose,
+				CoreBin:     cfg.CoreBinary,
+				ArduinoCLI:  cfg.ArduinoCLI,
+				FlashBinary: cfg.FlashBinary,
+				PlatformIO:  cfg.PlatformIO,
+				Backend:     backend.Resolve("", m, cfg),
+				SourceMap:   m.Build.SourceMap,
+			})
+			if err != nil {
+				return err
+			}
+			if res.Firmware == "" || res.BuildInfo == "" {
+				return fmt.Errorf("compile produced no firmware image — nothing to package")
+			}
+
+			info, err := firmware.ReadBuildInfo(res.BuildInfo)
+			if err != nil {
+				return err
+			}
+
+			if outDir == "" {
+				outDir = filepath.Join(dir, m.Build.OutputDir, "dist")
+			}
+			projectName := build.SketchName(m.Name)
+			// Sanitized as the build does, so the name cannot add path elements.
+			name := dist.ArchiveName(projectName, m.Version, archiveFormat)
+			root := fmt.Sprintf("%s-%s", projectName, m.Version)
+
+			backendCmd, err := flash.CommandLine(dir, info.Board, "<PORT>", ".", info.Image, flash.Options{
+				Backend:     info.Backend,
+				FlashBinary: cfg.FlashBinary,
+				PlatformIO:  cfg.PlatformIO,
+			})
+			if err != nil {
+				return err
+			}
+			readme := dist.Readme(dist.ReadmeInfo{
+				Name:       m.Name,
+				Version:    m.Version,
+				Board:      info.Board,
+				Backend:    info.Backend,
+				Image:      info.Image,
+				Archive:    name,
+				BackendCmd: backendCmd,
+				DirectCmd:  flash.DirectCommandLine(info.Board, "<PORT>", info.Image),
+			})
+
+			files := []dist.File{
+				{Name: filepath.Base(res.Firmware), Path: res.Firmware},
+				{Name: filepath.Base(res.BuildInfo), Path: res.BuildInfo},
+			}
+			if res.ELF != "" {
+				files = append(files, dist.File{Name: filepath.Base(res.ELF), Path: res.ELF})
+			}
+			files = append(files, dist.File{Name: manifest.FileName, Path: filepath.Join(dir, manifest.FileName)})
+			if lock := filepath.Join(dir, "tsuki.lock"); fileExists(lock) {
+				files = append(files, dist.File{Name: "tsuki.lock", Path: lock})
+			}
+			files = append(files, dist.File{Name: "README.md", Data: readme})
+
+			archive := filepath.Join(outDir, name)
+			if err := dist.Create(archive, root, archiveFormat, files); err != nil {
+				return fmt.Errorf("writing %s: %w", name, err)
+			}
+
+			for _, f := range files {
+				ui.Step("dist", f.Name)
+			}
+			ui.Success(fmt.Sprintf("Packaged %s", archive))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&board, "board", "b", "", "target board (default from manifest)")
+	cmd.Flags().StringVar(&format, "format", "zip", "archive format: zip | tar.gz")
+	cmd.Flags().StringVarP(&outDir, "out", "o", "", "directory for the archive (default build/dist)")
+	return cmd
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
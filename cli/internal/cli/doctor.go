@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tsuki/cli/internal/core"
+	"github.com/tsuki/cli/internal/doctor"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// ── doctor ────────────────────────────────────────────────────────────────────
+
+func newDoctorCmd() *cobra.Command {
+	var fix bool
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the toolchain and environment",
+		Long: `Check everything a build or upload depends on and print a fix for
+each problem:
+
+  • tsuki-core on PATH and its version
+  • arduino-cli and the core for the board, or tsuki-flash and its SDK
+  • read/write access to /dev/ttyUSB* and /dev/ttyACM* (Linux)
+  • the package store (libs_dir) and every configured registry
+  • packages declared in tsuki_package.json but not installed
+
+Run inside a project to check its board, backend and packages; elsewhere
+the configured defaults are used.
+
+With --fix, the safe fixes are applied — creating libs_dir, installing
+missing packages, cores and SDKs. Fixes that need root (group membership,
+system installs) are only printed.`,
+		Example: `  tsuki doctor
+  tsuki doctor --fix
+  tsuki doctor --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env := doctor.Env{Config: cfg}
+			if dir, m, err := manifest.Find(projectDir()); err == nil {
+				env.ProjectDir, env.Manifest = dir, m
+			} else if dir != "" {
+				ui.Warn(err.Error())
+			}
+
+			transpiler := core.New(cfg.CoreBinary, cfg.Verbose)
+			defer transpiler.Close()
+			env.Transpiler = transpiler
+
+			checks := runDoctor(env)
+
+			if fix {
+				fixable := 0
+				for _, c := range checks {
+					if c.Status != doctor.Pass && c.Fixable {
+						fixable++
+					}
+				}
+				if fixable > 0 {
+					ui.SectionTitle(fmt.Sprintf("Applying %d fix(es)", fixable))
+					for _, c := range checks {
+						if c.Status == doctor.Pass || !c.Fixable {
+							continue
+						}
+						ui.Step("fix", c.Fix)
+						if err := c.Apply(); err != nil {
+							ui.Fail(fmt.Sprintf("%s: %v", c.Name, err))
+						}
+					}
+					// Report the state after fixing, not before.
+					checks = runDoctor(env)
+				}
+			}
+
+			_, _, failed := doctor.Summary(checks)
+			if machineOutput() {
+				if err := emit(checks); err != nil {
+					return err
+				}
+				if failed > 0 {
+					return reportedError{fmt.Errorf("%d check(s) failed", failed)}
+				}
+				return nil
+			}
+
+			printDoctor(checks, fix)
+			if failed > 0 {
+				return fmt.Errorf("%d check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&fix, "fix", false, "apply the safe fixes (mkdir, package/core/SDK installs)")
+	return cmd
+}
+
+func runDoctor(env doctor.Env) []*doctor.Check {
+	sp := ui.NewSpinner("Running checks…")
+	sp.Start()
+	checks := doctor.Run(env)
+	sp.Stop(true, fmt.Sprintf("Ran %d check(s)", len(checks)))
+	return checks
+}
+
+// printDoctor renders the pass/warn/fail table with a fix under each problem.
+func printDoctor(checks []*doctor.Check, fixed bool) {
+	ui.SectionTitle("Doctor")
+	fmt.Println()
+
+	width := 10
+	for _, c := range checks {
+		if len(c.Name) > width {
+			width = len(c.Name)
+		}
+	}
+
+	ui.ColorTitle.Printf("  %-6s  %-*s  %s\n", "STATUS", width, "CHECK", "DETAIL")
+	ui.ColorMuted.Println("  " + hline(width+40, "─"))
+
+	canFix := 0
+	for _, c := range checks {
+		switch c.Status {
+		case doctor.Pass:
+			ui.ColorSuccess.Printf("  %-6s", "pass")
+		case doctor.Warn:
+			ui.ColorWarn.Printf("  %-6s", "warn")
+		default:
+			ui.ColorError.Printf("  %-6s", "fail")
+		}
+		ui.ColorKey.Printf("  %-*s", width, c.Name)
+		fmt.Printf("  %s\n", c.Detail)
+
+		if c.Status == doctor.Pass || c.Fix == "" {
+			continue
+		}
+		note := ""
+		if c.Fixable {
+			note = "   [--fix]"
+			canFix++
+		}
+		ui.ColorMuted.Printf("  %-6s  %-*s  ", "", width, "")
+		fmt.Printf("fix: %s", c.Fix)
+		ui.ColorMuted.Printf("%s\n", note)
+	}
+	fmt.Println()
+
+	pass, warn, fail := doctor.Summary(checks)
+	summary := fmt.Sprintf("%d passed, %d warning(s), %d failed", pass, warn, fail)
+	switch {
+	case fail > 0:
+		ui.Fail(summary)
+	case warn > 0:
+		ui.Warn(summary)
+	default:
+		ui.Success(summary)
+	}
+	if canFix > 0 && !fixed {
+		ui.Info(fmt.Sprintf("Run 'tsuki doctor --fix' to apply %d safe fix(es)", canFix))
+	}
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuki/cli/internal/firmware"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+func newFirmwareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "firmware",
+		Short: "Inspect compiled firmware images",
+	}
+	cmd.AddCommand(newFirmwareInfoCmd())
+	return cmd
+}
+
+// ── firmware info ─────────────────────────────────────────────────────────────
+
+func newFirmwareInfoCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "info [image]",
+		Short: "Show size, address ranges and SHA-256 of a .hex/.bin image",
+		Long: `Parse a firmware image, validate it and print what it contains.
+
+Without an argument the image in the current project's build/.cache is used.
+If a build-info file sits next to the image, its metadata is shown too.`,
+		Example: `  tsuki firmware info
+  tsuki firmware info build/.cache/blink.hex`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var path string
+			if len(args) > 0 {
+				path = args[0]
+			} else {
+				dir, m, err := manifest.Find(projectDir())
+				if err != nil {
+					return err
+				}
+				cacheDir := filepath.Join(dir, m.Build.OutputDir, ".cache")
+				path = firmware.Find(cacheDir)
+				if path == "" {
+					return fmt.Errorf("no firmware found in %s — run `tsuki build --compile` first", cacheDir)
+				}
+			}
+
+			img, err := firmware.Load(path)
+			if err != nil {
+				return err
+			}
+
+			ranges := make([]string, 0, len(img.Segments))
+			for _, r := range img.Ranges() {
+				ranges = append(ranges, fmt.Sprintf("%s (%d B)", r, r.End-r.Start))
+			}
+			entries := []ui.ConfigEntry{
+				{Key: "path", Value: img.Path},
+				{Key: "format", Value: string(img.Format)},
+				{Key: "size", Value: img.Size(), Comment: "bytes programmed"},
+				{Key: "ranges", Value: strings.Join(ranges, ", ")},
+				{Key: "sha256", Value: img.SHA256()},
+			}
+			if img.HasEntry {
+				entries = append(entries, ui.ConfigEntry{Key: "entry", Value: fmt.Sprintf("0x%08X", img.Entry)})
+			}
+			ui.PrintConfig("Firmware", entries, false)
+
+			infoPath := firmware.BuildInfoPath(path)
+			info, err := firmware.ReadBuildInfo(infoPath)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			ui.PrintConfig("Build info", []ui.ConfigEntry{
+				{Key: "project", Value: info.Project},
+				{Key: "version", Value: info.Version},
+				{Key: "board", Value: info.Board},
+				{Key: "backend", Value: info.Backend},
+				{Key: "core", Value: info.Core},
+				{Key: "profile", Value: info.Profile},
+				{Key: "git_commit", Value: info.GitCommit},
+				{Key: "git_dirty", Value: info.GitDirty},
+				{Key: "built_at", Value: info.BuiltAt.Format("2006-01-02 15:04:05 MST")},
+			}, false)
+			if info.SHA256 != img.SHA256() {
+				ui.Warn("image hash does not match its build-info — the image was modified after the build")
+			}
+			return nil
+		},
+	}
+}
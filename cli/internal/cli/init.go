@@ -538,7 +538,8 @@ func printIntro() {
 	wCyan.Print("Arduino")
 	wBold.Println(".")
 	wDim.Println("  Use ↑ ↓ arrows to navigate, Enter to confirm.")
-	wDim.Println("  Press Ctrl+C at any time to cancel.\n")
+	wDim.Println("  Press Ctrl+C at any time to cancel.")
+	fmt.Println()
 	printLine()
 	fmt.Println()
 }
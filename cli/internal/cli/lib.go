@@ -0,0 +1,488 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/build"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+func newLibCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lib",
+		Short: "Manage the Arduino libraries a project links against",
+		Long: `Install, remove, list and search Arduino libraries through the project's
+backend (arduino-cli, tsuki-flash, platformio…), the same way for each.
+
+The libraries a project needs are recorded in tsuki_package.json under
+"libraries", with the exact version installed and the tsuki packages that
+pulled them in (through their arduino_lib). 'tsuki build --compile' checks
+they are installed before compiling.`,
+	}
+
+	cmd.AddCommand(
+		newLibInstallCmd(),
+		newLibRemoveCmd(),
+		newLibListCmd(),
+		newLibSearchCmd(),
+		newLibOutdatedCmd(),
+	)
+	return cmd
+}
+
+// projectBackend creates the backend that library and core commands go
+// through: the flag's, or else the project's.
+func projectBackend(flag string, m *manifest.Manifest) (backend.Backend, error) {
+	return backend.New(backend.Resolve(flag, m, cfg), backend.Tools{
+		ArduinoCLI:  cfg.ArduinoCLI,
+		FlashBinary: cfg.FlashBinary,
+		PlatformIO:  cfg.PlatformIO,
+		ProjectDir:  projectDir(),
+		Verbose:     cfg.Verbose,
+	})
+}
+
+// libManager returns b's library listing, or an error naming the backend.
+func libManager(b backend.Backend) (backend.LibraryManager, error) {
+	lm, ok := b.(backend.LibraryManager)
+	if !ok {
+		return nil, fmt.Errorf("backend %s cannot list, search or remove libraries — only install them", b.Name())
+	}
+	return lm, nil
+}
+
+// installLibrary installs one library through b and returns the version
+// that was installed, or "" when the backend cannot tell.
+func installLibrary(b backend.Backend, name, version string) (string, error) {
+	ui.Step("lib", fmt.Sprintf("installing %s via %s", build.LibraryLabel(manifest.Library{Name: name, Version: version}), b.Name()))
+	if err := b.InstallLibrary(name, version); err != nil {
+		return "", err
+	}
+	if version != "" {
+		return version, nil
+	}
+	lm, ok := b.(backend.LibraryManager)
+	if !ok {
+		return "", nil
+	}
+	installed, err := lm.Libraries()
+	if err != nil {
+		return "", nil
+	}
+	return installedVersion(installed, name), nil
+}
+
+// installedVersion returns the version of name in installed, or "".
+func installedVersion(installed []backend.Library, name string) string {
+	for _, l := range installed {
+		if strings.EqualFold(l.Name, name) {
+			return l.Version
+		}
+	}
+	return ""
+}
+
+// splitLibrarySpec splits "Name@1.2.3". Library names may contain spaces
+// but not "@".
+func splitLibrarySpec(spec string) (string, string) {
+	if i := strings.LastIndex(spec, "@"); i > 0 {
+		return spec[:i], spec[i+1:]
+	}
+	return spec, ""
+}
+
+// ── lib install ───────────────────────────────────────────────────────────────
+
+func newLibInstallCmd() *cobra.Command {
+	var backendName string
+
+	cmd := &cobra.Command{
+		Use:   "install [name[@version]...]",
+		Short: "Install Arduino libraries and pin them in the manifest",
+		Long: `Without arguments, install every library the project needs — those in
+tsuki_package.json and the arduino_lib of each declared package — at the
+pinned versions, and pin the versions installed for the others.
+
+With arguments, install those libraries (the latest release unless a
+version is given) and, inside a project, add them to tsuki_package.json.`,
+		Example: `  tsuki lib install
+  tsuki lib install "Adafruit NeoPixel"
+  tsuki lib install "DHT sensor library@1.4.6"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projDir, m, _ := manifest.Find(projectDir())
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+
+			var wanted []manifest.Library
+			if len(args) == 0 {
+				if m == nil {
+					return fmt.Errorf("no %s found — name the libraries to install: tsuki lib install <name>[@version]", manifest.FileName)
+				}
+				wanted = build.RequiredLibraries(m)
+				if len(wanted) == 0 {
+					ui.Info(fmt.Sprintf("No Arduino libraries needed by %s", manifest.FileName))
+					return nil
+				}
+			} else {
+				for _, arg := range args {
+					name, version := splitLibrarySpec(arg)
+					wanted = append(wanted, manifest.Library{Name: name, Version: version})
+				}
+			}
+
+			ui.SectionTitle(fmt.Sprintf("Installing Arduino libraries  [%s]", b.Name()))
+			installed := make([]backend.Library, 0, len(wanted))
+			for _, lib := range wanted {
+				version, err := installLibrary(b, lib.Name, lib.Version)
+				if err != nil {
+					return err
+				}
+				installed = append(installed, backend.Library{Name: lib.Name, Version: version})
+				if m == nil {
+					continue
+				}
+				m.AddLibrary(lib.Name, version, "")
+				for _, pkg := range lib.Packages {
+					m.AddLibrary(lib.Name, "", pkg)
+				}
+			}
+
+			if m != nil {
+				if err := m.Save(projDir); err != nil {
+					return fmt.Errorf("saving manifest: %w", err)
+				}
+			}
+			if machineOutput() {
+				return emit(installed)
+			}
+			for _, lib := range installed {
+				ui.Success(fmt.Sprintf("%s %s", lib.Name, lib.Version))
+			}
+			if m != nil {
+				ui.Info(fmt.Sprintf("Pinned in %s", manifest.FileName))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to install through (default: the project's)")
+	return cmd
+}
+
+// ── lib remove ────────────────────────────────────────────────────────────────
+
+func newLibRemoveCmd() *cobra.Command {
+	var backendName string
+
+	cmd := &cobra.Command{
+		Use:     "remove <name>",
+		Aliases: []string{"rm", "uninstall"},
+		Short:   "Uninstall an Arduino library and drop it from the manifest",
+		Example: `  tsuki lib remove "Adafruit NeoPixel"`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			projDir, m, _ := manifest.Find(projectDir())
+
+			if m != nil {
+				for _, lib := range build.RequiredLibraries(m) {
+					if strings.EqualFold(lib.Name, name) && len(lib.Packages) > 0 {
+						return fmt.Errorf("%s is needed by package %s\n"+
+							"  Run: tsuki pkg remove %s --manifest", lib.Name, strings.Join(lib.Packages, ", "), lib.Packages[0])
+					}
+				}
+			}
+
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+			lm, err := libManager(b)
+			if err != nil {
+				return err
+			}
+			if err := lm.RemoveLibrary(name); err != nil {
+				return err
+			}
+			ui.Success(fmt.Sprintf("Removed %s", name))
+
+			if m != nil && m.RemoveLibrary(name) {
+				if err := m.Save(projDir); err != nil {
+					return fmt.Errorf("saving manifest: %w", err)
+				}
+				ui.Info(fmt.Sprintf("Removed %s from %s", name, manifest.FileName))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to remove through (default: the project's)")
+	return cmd
+}
+
+// ── lib list ──────────────────────────────────────────────────────────────────
+
+// libraryStatus is a project library in `tsuki lib list`.
+type libraryStatus struct {
+	Name      string   `json:"name"`
+	Pinned    string   `json:"pinned,omitempty"`
+	Installed string   `json:"installed,omitempty"`
+	Packages  []string `json:"packages"`
+}
+
+func newLibListCmd() *cobra.Command {
+	var backendName string
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the project's Arduino libraries",
+		Long: `Inside a project, list the Arduino libraries it needs, with the pinned and
+installed versions and the packages that need them. Outside a project, or
+with --all, list every library the backend has installed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, m, _ := manifest.Find(projectDir())
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+			lm, err := libManager(b)
+			if err != nil {
+				return err
+			}
+			installed, err := lm.Libraries()
+			if err != nil {
+				return err
+			}
+
+			if m == nil || all {
+				if machineOutput() {
+					if installed == nil {
+						installed = []backend.Library{}
+					}
+					return emit(installed)
+				}
+				printLibraries(fmt.Sprintf("Installed Arduino libraries  [%s]", b.Name()), installed)
+				return nil
+			}
+
+			statuses := []libraryStatus{}
+			for _, lib := range build.RequiredLibraries(m) {
+				pkgs := lib.Packages
+				if pkgs == nil {
+					pkgs = []string{}
+				}
+				statuses = append(statuses, libraryStatus{
+					Name:      lib.Name,
+					Pinned:    lib.Version,
+					Installed: installedVersion(installed, lib.Name),
+					Packages:  pkgs,
+				})
+			}
+			if machineOutput() {
+				return emit(statuses)
+			}
+			if len(statuses) == 0 {
+				ui.Info(fmt.Sprintf("No Arduino libraries needed by %s — add one with `tsuki lib install <name>`", manifest.FileName))
+				return nil
+			}
+
+			ui.SectionTitle(fmt.Sprintf("Arduino libraries (%d)  [%s]", len(statuses), b.Name()))
+			fmt.Println()
+			ui.ColorTitle.Printf("  %-28s  %-10s  %-10s  %s\n", "NAME", "PINNED", "INSTALLED", "NEEDED BY")
+			ui.ColorMuted.Println("  " + hline(76, "─"))
+			for _, s := range statuses {
+				ui.ColorKey.Printf("  %-28s", s.Name)
+				ui.ColorNumber.Printf("  %-10s", or(s.Pinned, "—"))
+				switch {
+				case s.Installed == "":
+					ui.ColorError.Printf("  %-10s", "missing")
+				case s.Pinned != "" && s.Installed != s.Pinned:
+					ui.ColorWarn.Printf("  %-10s", s.Installed)
+				default:
+					ui.ColorNumber.Printf("  %-10s", s.Installed)
+				}
+				ui.ColorMuted.Printf("  %s\n", or(strings.Join(s.Packages, ", "), "(direct)"))
+			}
+			fmt.Println()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to ask (default: the project's)")
+	cmd.Flags().BoolVar(&all, "all", false, "list every installed library, not just the project's")
+	return cmd
+}
+
+// printLibraries prints a NAME / VERSION / DESCRIPTION table.
+func printLibraries(title string, libs []backend.Library) {
+	if len(libs) == 0 {
+		ui.Info("No libraries found")
+		return
+	}
+	ui.SectionTitle(title)
+	fmt.Println()
+	ui.ColorTitle.Printf("  %-28s  %-10s  %s\n", "NAME", "VERSION", "DESCRIPTION")
+	ui.ColorMuted.Println("  " + hline(88, "─"))
+	for _, l := range libs {
+		desc := l.Description
+		if len(desc) > 46 {
+			desc = desc[:43] + "..."
+		}
+		ui.ColorKey.Printf("  %-28s", l.Name)
+		ui.ColorNumber.Printf("  %-10s", l.Version)
+		fmt.Printf("  %s\n", desc)
+	}
+	fmt.Println()
+}
+
+// or returns s, or def when s is empty.
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// ── lib search ────────────────────────────────────────────────────────────────
+
+func newLibSearchCmd() *cobra.Command {
+	var backendName string
+
+	cmd := &cobra.Command{
+		Use:     "search <query>",
+		Short:   "Search the Arduino library registry",
+		Example: `  tsuki lib search neopixel`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, m, _ := manifest.Find(projectDir())
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+			lm, err := libManager(b)
+			if err != nil {
+				return err
+			}
+
+			sp := ui.NewSpinner(fmt.Sprintf("Searching libraries via %s…", b.Name()))
+			sp.Start()
+			libs, err := lm.SearchLibraries(args[0])
+			if err != nil {
+				sp.Stop(false, "search failed")
+				return err
+			}
+			sp.Stop(true, fmt.Sprintf("%d libraries found", len(libs)))
+
+			if machineOutput() {
+				if libs == nil {
+					libs = []backend.Library{}
+				}
+				return emit(libs)
+			}
+			printLibraries("Arduino libraries", libs)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to search through (default: the project's)")
+	return cmd
+}
+
+// ── lib outdated ──────────────────────────────────────────────────────────────
+
+// outdatedLibrary is a library with a newer release.
+type outdatedLibrary struct {
+	Name      string `json:"name"`
+	Installed string `json:"installed"`
+	Pinned    string `json:"pinned,omitempty"`
+	Latest    string `json:"latest"`
+}
+
+func newLibOutdatedCmd() *cobra.Command {
+	var backendName string
+
+	cmd := &cobra.Command{
+		Use:   "outdated",
+		Short: "List Arduino libraries with a newer release",
+		Long: `Compare the installed version of each library the project needs (or, outside
+a project, of every installed library) with the latest release.
+Update a pin with: tsuki lib install <name>@<version>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, m, _ := manifest.Find(projectDir())
+			b, err := projectBackend(backendName, m)
+			if err != nil {
+				return err
+			}
+			lm, err := libManager(b)
+			if err != nil {
+				return err
+			}
+			installed, err := lm.Libraries()
+			if err != nil {
+				return err
+			}
+
+			// The project's libraries, or everything installed.
+			var check []manifest.Library
+			if m != nil {
+				check = build.RequiredLibraries(m)
+			} else {
+				for _, l := range installed {
+					check = append(check, manifest.Library{Name: l.Name})
+				}
+			}
+
+			sp := ui.NewSpinner(fmt.Sprintf("Checking %d libraries via %s…", len(check), b.Name()))
+			sp.Start()
+			outdated := []outdatedLibrary{}
+			for _, lib := range check {
+				current := installedVersion(installed, lib.Name)
+				if current == "" {
+					continue
+				}
+				hits, err := lm.SearchLibraries(lib.Name)
+				if err != nil {
+					sp.Stop(false, "check failed")
+					return err
+				}
+				latest := installedVersion(hits, lib.Name)
+				if latest != "" && latest != current {
+					outdated = append(outdated, outdatedLibrary{Name: lib.Name, Installed: current, Pinned: lib.Version, Latest: latest})
+				}
+			}
+			sp.Stop(true, fmt.Sprintf("%d outdated", len(outdated)))
+
+			if machineOutput() {
+				return emit(outdated)
+			}
+			if len(outdated) == 0 {
+				ui.Success("All libraries are up to date")
+				return nil
+			}
+			fmt.Println()
+			ui.ColorTitle.Printf("  %-28s  %-10s  %-10s  %s\n", "NAME", "INSTALLED", "PINNED", "LATEST")
+			ui.ColorMuted.Println("  " + hline(64, "─"))
+			for _, o := range outdated {
+				ui.ColorKey.Printf("  %-28s", o.Name)
+				ui.ColorNumber.Printf("  %-10s", o.Installed)
+				ui.ColorMuted.Printf("  %-10s", or(o.Pinned, "—"))
+				ui.ColorSuccess.Printf("  %s\n", o.Latest)
+			}
+			fmt.Println()
+			ui.Info(fmt.Sprintf("Update with: tsuki lib install \"%s@%s\"", outdated[0].Name, outdated[0].Latest))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to ask (default: the project's)")
+	return cmd
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/fatih/color"
+)
+
+// ── Machine-readable output ───────────────────────────────────────────────────
+//
+// With --output json|ndjson, stdout carries nothing but JSON documents: the
+// human UI that normally goes there (spinners, tables) is discarded — or sent
+// to stderr with --verbose — and failures become {"error": {...}} objects.
+// Stderr is left alone, so tracebacks still reach CI logs.
+
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+var (
+	globalOutput = outputText
+
+	// jsonOut is the real stdout, kept aside while the human UI is redirected.
+	jsonOut io.Writer = os.Stdout
+)
+
+// machineOutput reports whether commands should emit JSON instead of UI.
+func machineOutput() bool {
+	return globalOutput != outputText
+}
+
+// setupOutput validates --output and, for JSON modes, moves the human UI off
+// stdout so that it cannot corrupt the documents.
+func setupOutput() error {
+	switch globalOutput {
+	case outputText:
+		return nil
+	case outputJSON, outputNDJSON:
+	default:
+		bad := globalOutput
+		globalOutput = outputText
+		return fmt.Errorf("invalid --output %q (use text, json or ndjson)", bad)
+	}
+
+	jsonOut = os.Stdout
+	sink := os.Stderr
+	if !globalVerbose {
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		sink = devNull
+	}
+	os.Stdout = sink
+	color.NoColor = true
+	color.Output = sink
+	return nil
+}
+
+// emit writes v as a JSON document. In ndjson mode slices are written one
+// element per line, everything else as a single line.
+func emit(v any) error {
+	if globalOutput == outputNDJSON {
+		enc := json.NewEncoder(jsonOut)
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice {
+			for i := 0; i < rv.Len(); i++ {
+				if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return enc.Encode(v)
+	}
+	enc := json.NewEncoder(jsonOut)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// errorDocument is what a failed command prints in JSON modes.
+type errorDocument struct {
+	Error struct {
+		Message string `json:"message"`
+		Command string `json:"command,omitempty"`
+	} `json:"error"`
+}
+
+// emitError reports err as a JSON error object.
+func emitError(command string, err error) {
+	var doc errorDocument
+	doc.Error.Message = err.Error()
+	doc.Error.Command = command
+	_ = emit(doc)
+}
+
+// reportedError is returned by a command that has already emitted a result
+// describing the failure (e.g. a check report with errors). Execute exits
+// non-zero without printing it again.
+type reportedError struct{ error }
+
+func isReported(err error) bool {
+	var r reportedError
+	return errors.As(err, &r)
+}
@@ -2,12 +2,11 @@ package cli
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/tsuki/cli/internal/build"
 	"github.com/tsuki/cli/internal/manifest"
 	"github.com/tsuki/cli/internal/pkgmgr"
 	"github.com/tsuki/cli/internal/ui"
@@ -98,49 +97,30 @@ func newPkgInstallCmd() *cobra.Command {
 			fmt.Println()
 			ui.Info(fmt.Sprintf("Add to your project: tsuki pkg add %s", pkg.Name))
 
-			// If arduino_lib is set, auto-install it via tsuki-flash or arduino-cli.
+			// A package wrapping an Arduino library needs it installed, at the
+			// project's pin when there is one.
 			if pkg.ArduinoLib != "" {
 				fmt.Println()
-				ui.Warn(fmt.Sprintf("This package requires the '%s' Arduino library.", pkg.ArduinoLib))
-
-				flashBin := cfg.FlashBinary
-				if flashBin == "" {
-					flashBin = "tsuki-flash"
+				projDir, m, _ := manifest.Find(projectDir())
+				b, err := projectBackend("", m)
+				if err != nil {
+					return err
 				}
-
-				// Use tsuki-flash when: backend is explicitly set, OR the binary is on PATH.
-				useTsukiFlash := cfg.Backend == "tsuki-flash"
-				if !useTsukiFlash {
-					if _, err := exec.LookPath(flashBin); err == nil {
-						useTsukiFlash = true
+				pin := ""
+				if m != nil {
+					if lib := m.Library(pkg.ArduinoLib); lib != nil {
+						pin = lib.Version
 					}
 				}
-
-				if useTsukiFlash {
-					ui.Info(fmt.Sprintf("Installing '%s' via tsuki-flash lib install…", pkg.ArduinoLib))
-					libCmd := exec.Command(flashBin, "lib", "install", pkg.ArduinoLib)
-					libCmd.Stdout = os.Stdout
-					libCmd.Stderr = os.Stderr
-					if libErr := libCmd.Run(); libErr != nil {
-						ui.Warn("Auto-install failed. Run manually:")
-						ui.Info(fmt.Sprintf("  tsuki-flash lib install \"%s\"", pkg.ArduinoLib))
-					} else {
-						ui.Success(fmt.Sprintf("'%s' installed successfully.", pkg.ArduinoLib))
-					}
-				} else {
-					arduinoCLI := cfg.ArduinoCLI
-					if arduinoCLI == "" {
-						arduinoCLI = "arduino-cli"
-					}
-					ui.Info(fmt.Sprintf("Installing '%s' via arduino-cli…", pkg.ArduinoLib))
-					libCmd := exec.Command(arduinoCLI, "lib", "install", pkg.ArduinoLib)
-					libCmd.Stdout = os.Stdout
-					libCmd.Stderr = os.Stderr
-					if libErr := libCmd.Run(); libErr != nil {
-						ui.Warn("Auto-install failed. Run manually:")
-						ui.Info(fmt.Sprintf("  arduino-cli lib install \"%s\"", pkg.ArduinoLib))
-					} else {
-						ui.Success(fmt.Sprintf("'%s' installed successfully.", pkg.ArduinoLib))
+				installed, err := installLibrary(b, pkg.ArduinoLib, pin)
+				if err != nil {
+					return fmt.Errorf("installing the '%s' Arduino library for %s: %w", pkg.ArduinoLib, pkg.Name, err)
+				}
+				ui.Success(fmt.Sprintf("%s installed", build.LibraryLabel(manifest.Library{Name: pkg.ArduinoLib, Version: installed})))
+				if m != nil && m.HasPackage(pkg.Name) {
+					m.AddLibrary(pkg.ArduinoLib, installed, pkg.Name)
+					if err := m.Save(projDir); err != nil {
+						return fmt.Errorf("saving manifest: %w", err)
 					}
 				}
 			}
@@ -193,12 +173,19 @@ This records the dependency so 'tsuki build' loads it automatically.`,
 				ui.Warn(fmt.Sprintf("Package %q is already declared in %s", name, manifest.FileName))
 				return nil
 			}
+			lib := installedArduinoLib(name)
+			if lib != "" {
+				m.AddLibrary(lib, "", name)
+			}
 
 			if err := m.Save(projDir); err != nil {
 				return fmt.Errorf("saving manifest: %w", err)
 			}
 
 			ui.Success(fmt.Sprintf("Added %s@%s to goduino.json", name, ver))
+			if lib != "" {
+				ui.Info(fmt.Sprintf("It needs the '%s' Arduino library — install and pin it with 'tsuki lib install'", lib))
+			}
 			ui.Info("Run 'tsuki build' to transpile with this package")
 			return nil
 		},
@@ -208,6 +195,17 @@ This records the dependency so 'tsuki build' loads it automatically.`,
 	return cmd
 }
 
+// installedArduinoLib returns the arduino_lib of an installed package.
+func installedArduinoLib(name string) string {
+	pkgs, _ := pkgmgr.ListInstalled()
+	for _, p := range pkgs {
+		if p.Name == name {
+			return p.ArduinoLib
+		}
+	}
+	return ""
+}
+
 // ── pkg remove ────────────────────────────────────────────────────────────────
 
 func newPkgRemoveCmd() *cobra.Command {
@@ -253,6 +251,7 @@ func newPkgRemoveCmd() *cobra.Command {
 				projDir, m, err := manifest.Find(dir)
 				if err == nil {
 					if m.RemovePackage(name) {
+						m.DropPackageLibraries(name)
 						if err := m.Save(projDir); err == nil {
 							ui.Info(fmt.Sprintf("Removed %s from goduino.json", name))
 						}
@@ -280,6 +279,12 @@ func newPkgListCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if machineOutput() {
+				if pkgs == nil {
+					pkgs = []pkgmgr.InstalledPackage{}
+				}
+				return emit(pkgs)
+			}
 			pkgmgr.PrintList(pkgs)
 			ui.Info(fmt.Sprintf("Packages directory: %s", pkgmgr.LibsDir()))
 			return nil
@@ -312,6 +317,12 @@ func newPkgSearchCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if machineOutput() {
+				if entries == nil {
+					entries = []pkgmgr.RegistryEntry{}
+				}
+				return emit(entries)
+			}
 
 			ui.SectionTitle("Package registry")
 			fmt.Println()
@@ -337,6 +348,9 @@ func newPkgInfoCmd() *cobra.Command {
 			}
 			for _, p := range pkgs {
 				if p.Name == name {
+					if machineOutput() {
+						return emit(p)
+					}
 					ui.PrintConfig(fmt.Sprintf("Package: %s", p.Name), []ui.ConfigEntry{
 						{Key: "name",        Value: p.Name},
 						{Key: "version",     Value: p.Version},
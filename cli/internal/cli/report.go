@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuki/cli/internal/build"
+	"github.com/tsuki/cli/internal/check"
+	"github.com/tsuki/cli/internal/core"
+	"github.com/tsuki/cli/internal/report"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// addReportFlag registers the repeatable --report kind=<file> flag.
+func addReportFlag(cmd *cobra.Command, values *[]string) {
+	cmd.Flags().StringArrayVar(values, "report", nil,
+		"write a CI report: sarif=<file> or junit=<file> (repeatable)")
+}
+
+// writeReports writes doc in every requested format. Reports are written
+// even for failed runs — that is when CI needs them most.
+func writeReports(specs []report.Spec, doc report.Document) error {
+	for _, spec := range specs {
+		if err := report.Write(spec, doc); err != nil {
+			return err
+		}
+		ui.Step("report", spec.Path)
+	}
+	return nil
+}
+
+// reportDocument starts a report for command run in projectDir, listing the
+// Go sources it processed.
+func reportDocument(command, projectDir string) report.Document {
+	files, _, _ := build.SourceFiles(filepath.Join(projectDir, "src"))
+	return report.Document{
+		Command: command,
+		Version: Version,
+		Root:    projectDir,
+		Files:   files,
+	}
+}
+
+// checkFindings converts a check report.
+func checkFindings(r *check.Report) []report.Finding {
+	var out []report.Finding
+	for _, i := range append(append([]check.Issue{}, r.Errors...), r.Warnings...) {
+		level := report.LevelWarning
+		if i.IsError {
+			level = report.LevelError
+		}
+		msg := i.Message
+		if i.Hint != "" {
+			msg += " — " + i.Hint
+		}
+		out = append(out, report.Finding{
+			File:    i.File,
+			Line:    i.Line,
+			Column:  i.Column,
+			Rule:    i.Code,
+			Level:   level,
+			Message: msg,
+		})
+	}
+	return out
+}
+
+// diagnosticFindings converts core and compiler diagnostics.
+func diagnosticFindings(diags []core.Diagnostic) []report.Finding {
+	var out []report.Finding
+	for _, d := range diags {
+		level := report.LevelNote
+		switch d.Severity {
+		case core.SeverityError:
+			level = report.LevelError
+		case core.SeverityWarning:
+			level = report.LevelWarning
+		}
+		out = append(out, report.Finding{
+			File:    d.File,
+			Line:    d.Line(),
+			Column:  d.Column(),
+			Rule:    d.Code,
+			Level:   level,
+			Message: d.Message,
+		})
+	}
+	return out
+}
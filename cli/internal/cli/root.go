@@ -35,6 +35,9 @@ Run 'tsuki <command> --help' for details on each command.
 	SilenceErrors: true,
 	SilenceUsage:  true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupOutput(); err != nil {
+			return err
+		}
 		if globalNoColor {
 			color.NoColor = true
 		}
@@ -53,8 +56,15 @@ Run 'tsuki <command> --help' for details on each command.
 
 // Execute is the entry point called from main().
 func Execute() error {
-	if err := rootCmd.Execute(); err != nil {
-		ui.Fail(err.Error())
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil {
+		switch {
+		case isReported(err):
+		case machineOutput():
+			emitError(cmd.CommandPath(), err)
+		default:
+			ui.Fail(err.Error())
+		}
 		return err
 	}
 	return nil
@@ -63,17 +73,26 @@ func Execute() error {
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&globalVerbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().BoolVar(&globalNoColor, "no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().StringVar(&globalOutput, "output", outputText, "output format: text | json | ndjson")
 
 	rootCmd.AddCommand(
 		newInitCmd(),
 		newBuildCmd(),
 		newUploadCmd(),
+		newSimCmd(),
+		newTestCmd(),
 		newCheckCmd(),
 		newConfigCmd(),
 		newBoardsCmd(),
 		newCleanCmd(),
 		newVersionCmd(),
 		newPkgCmd(),
+		newLibCmd(),
+		newCoreCmd(),
+		newToolchainCmd(),
+		newFirmwareCmd(),
+		newDistCmd(),
+		newDoctorCmd(),
 	)
 }
 
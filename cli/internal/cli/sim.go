@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tsuki/cli/internal/build"
+	"github.com/tsuki/cli/internal/host"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+func newSimCmd() *cobra.Command {
+	var (
+		board     string
+		loops     int
+		duration  time.Duration
+		timeout   time.Duration
+		pinInputs []string
+		serialIn  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sim",
+		Short: "Run the sketch on this machine against a simulated board",
+		Long: `Build the project with --target host and run it: setup() once, then loop()
+for a number of iterations or a span of simulated time, printing a trace of
+pin changes, Serial output, servo and LCD writes.
+
+Time is simulated — delay(1000) returns at once, one second later on the
+trace. Inputs are fixed for the run: --pin sets what digitalRead and
+analogRead return, --serial-in what the sketch reads from Serial.`,
+		Example: `  tsuki sim
+  tsuki sim --loops 100
+  tsuki sim --time 10s
+  tsuki sim --pin 2=HIGH --pin A0=512
+  tsuki sim --serial-in 'on\n'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("loops") && duration > 0 {
+				loops = 0 // bounded by --time alone
+			}
+			if loops <= 0 && duration <= 0 {
+				return fmt.Errorf("--loops or --time must be positive")
+			}
+			pins, err := parsePinInputs(pinInputs)
+			if err != nil {
+				return err
+			}
+			input, err := strconv.Unquote(`"` + strings.ReplaceAll(serialIn, `"`, `\"`) + `"`)
+			if err != nil {
+				return fmt.Errorf("invalid --serial-in %q: %w", serialIn, err)
+			}
+
+			dir, m, err := manifest.Find(projectDir())
+			if err != nil {
+				return err
+			}
+			if m == nil {
+				return fmt.Errorf("no %s found — run `tsuki init` first", manifest.FileName)
+			}
+			res, err := build.Run(dir, m, build.Options{
+				Board:        board,
+				Verbose:      cfg.Verbose,
+				CoreBin:      cfg.CoreBinary,
+				SourceMap:    m.Build.SourceMap,
+				Target:       build.TargetHost,
+				HostCompiler: cfg.HostCompiler,
+			})
+			if err != nil {
+				return err
+			}
+
+			trace, err := host.Run(res.Host, host.RunOptions{
+				Loops:       loops,
+				Duration:    duration,
+				Timeout:     timeout,
+				Pins:        pins,
+				SerialInput: input,
+			})
+			if trace != nil && !machineOutput() {
+				printTrace(trace)
+			}
+			if err != nil {
+				return err
+			}
+			if machineOutput() {
+				return emit(trace)
+			}
+			ui.Success(fmt.Sprintf("%d loop(s) in %s of simulated time", trace.Loops, simTime(trace.Micros)))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&board, "board", "b", "", "target board (default from manifest)")
+	cmd.Flags().IntVarP(&loops, "loops", "n", 10, "stop after this many loop() calls")
+	cmd.Flags().DurationVar(&duration, "time", 0, "stop after this much simulated time, e.g. 5s")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "give up after this much real time")
+	cmd.Flags().StringArrayVar(&pinInputs, "pin", nil, "input pin value, PIN=VALUE (HIGH, LOW or 0–1023; repeatable)")
+	cmd.Flags().StringVar(&serialIn, "serial-in", "", `text the sketch reads from Serial (\n and other escapes allowed)`)
+	return cmd
+}
+
+// parsePinInputs parses --pin values: "2=HIGH", "A0=512".
+func parsePinInputs(specs []string) (map[int]int, error) {
+	pins := map[int]int{}
+	for _, spec := range specs {
+		name, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --pin %q (want PIN=VALUE)", spec)
+		}
+		pin, err := host.PinNumber(name)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToUpper(value) {
+		case "HIGH":
+			pins[pin] = 1
+		case "LOW":
+			pins[pin] = 0
+		default:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --pin %q: value must be HIGH, LOW or a number", spec)
+			}
+			pins[pin] = n
+		}
+	}
+	return pins, nil
+}
+
+// printTrace prints a host run's events, one per line.
+func printTrace(trace *host.Trace) {
+	ui.SectionTitle("Trace")
+	fmt.Println()
+	for _, e := range trace.Events {
+		ui.ColorNumber.Printf("  %12s", simTime(e.Micros))
+		ui.ColorKey.Printf("  %-10s", traceSubject(e))
+		fmt.Println("  " + traceValue(e))
+	}
+	if len(trace.Events) == 0 {
+		ui.ColorMuted.Println("  (no pin changes or output)")
+	}
+	fmt.Println()
+}
+
+// simTime formats simulated microseconds as milliseconds.
+func simTime(us int64) string {
+	return fmt.Sprintf("%.3f ms", float64(us)/1000)
+}
+
+func traceSubject(e host.Event) string {
+	switch e.Kind {
+	case "serial", "lcd-clear":
+		return strings.TrimSuffix(e.Kind, "-clear")
+	case "lcd":
+		return fmt.Sprintf("lcd %d:%d", e.Pin, e.Value)
+	}
+	return fmt.Sprintf("%s %d", e.Kind, e.Pin)
+}
+
+func traceValue(e host.Event) string {
+	switch e.Kind {
+	case "pin":
+		if e.Value != 0 {
+			return "HIGH"
+		}
+		return "LOW"
+	case "mode":
+		return map[int64]string{0: "INPUT", 1: "OUTPUT", 2: "INPUT_PULLUP"}[e.Value]
+	case "tone":
+		if e.Value == 0 {
+			return "off"
+		}
+		return fmt.Sprintf("%d Hz", e.Value)
+	case "shift":
+		return fmt.Sprintf("0x%02X", e.Value)
+	case "servo":
+		return fmt.Sprintf("%d°", e.Value)
+	case "serial", "lcd":
+		return strconv.Quote(e.Text)
+	case "lcd-clear":
+		return "clear"
+	}
+	return strconv.FormatInt(e.Value, 10)
+}
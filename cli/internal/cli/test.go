@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tsuki/cli/internal/build"
+	"github.com/tsuki/cli/internal/host"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/report"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// testRun is the JSON document of tsuki test.
+type testRun struct {
+	Tests   []host.TestResult `json:"tests"`
+	Passed  int               `json:"passed"`
+	Failed  int               `json:"failed"`
+	Skipped int               `json:"skipped"`
+	// Error says why the run stopped early, when it did.
+	Error string `json:"error,omitempty"`
+}
+
+func newTestCmd() *cobra.Command {
+	var (
+		board   string
+		run     string
+		timeout time.Duration
+		reports []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Run the sketch's tests on this machine",
+		Long: `Build src/*_test.go together with the sketch for the host (see tsuki sim),
+run every func TestXxx(t *testing.T) and report the results as go test does.
+
+Tests share the sketch's functions and variables and drive it like the
+board would: call setup() and loop(), set inputs and read outputs through
+the sim package. Each test starts on a freshly reset board — pins low,
+clock at zero, Serial empty — but the sketch's variables keep their values.
+
+  import (
+      "arduino"
+      "sim"
+      "testing"
+  )
+
+  func TestButtonLightsLED(t *testing.T) {
+      setup()
+      sim.SetPin(2, arduino.HIGH)
+      loop()
+      if sim.Pin(13) != arduino.HIGH {
+          t.Errorf("LED is %d, want HIGH", sim.Pin(13))
+      }
+  }
+
+sim: SetPin(pin, v), Pin(pin), PinMode(pin), SetMillis(ms), SetMicros(us),
+Advance(ms), SerialInput(s), SerialOutput(), Reset().
+testing.T: Error, Errorf, Fatal, Fatalf, Log, Logf, Skip, Skipf, Fail,
+FailNow, SkipNow, Failed, Skipped, Name, Helper. Formats are printf ones.`,
+		Example: `  tsuki test
+  tsuki test -v
+  tsuki test --run 'Button|Serial'
+  tsuki test --report junit=build/tests.xml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			specs, err := report.ParseSpecs(reports)
+			if err != nil {
+				return err
+			}
+			var filter *regexp.Regexp
+			if run != "" {
+				if filter, err = regexp.Compile(run); err != nil {
+					return fmt.Errorf("invalid --run %q: %w", run, err)
+				}
+			}
+
+			dir, m, err := manifest.Find(projectDir())
+			if err != nil {
+				return err
+			}
+			if m == nil {
+				return fmt.Errorf("no %s found — run `tsuki init` first", manifest.FileName)
+			}
+			res, err := build.Run(dir, m, build.Options{
+				Board:        board,
+				Verbose:      cfg.Verbose,
+				CoreBin:      cfg.CoreBinary,
+				Target:       build.TargetHost,
+				HostCompiler: cfg.HostCompiler,
+				Tests:        true,
+			})
+			if err != nil {
+				if res != nil && len(specs) > 0 {
+					doc := testReport(dir, res, nil)
+					doc.Findings = diagnosticFindings(res.Diagnostics)
+					_ = writeReports(specs, doc)
+				}
+				return err
+			}
+
+			if len(res.Tests) == 0 {
+				if len(specs) > 0 {
+					if err := writeReports(specs, testReport(dir, res, nil)); err != nil {
+						return err
+					}
+				}
+				if machineOutput() {
+					return emit(testRun{Tests: []host.TestResult{}})
+				}
+				fmt.Printf("?   \t%s\t[no test files]\n", m.Name)
+				return nil
+			}
+
+			var tests []host.TestFunc
+			for _, t := range res.Tests {
+				if filter == nil || filter.MatchString(t.Name) {
+					tests = append(tests, t)
+				}
+			}
+			if len(tests) == 0 {
+				ui.Warn(fmt.Sprintf("no tests match --run %q", run))
+				return nil
+			}
+
+			if !machineOutput() {
+				ui.SectionTitle("Testing")
+				fmt.Println()
+			}
+			start := time.Now()
+			results, runErr := host.RunTests(res.Host, tests, timeout)
+			elapsed := time.Since(start)
+
+			summary := testRun{Tests: results}
+			for _, r := range results {
+				switch r.Status {
+				case host.TestPass:
+					summary.Passed++
+				case host.TestFail:
+					summary.Failed++
+				case host.TestSkip:
+					summary.Skipped++
+				}
+			}
+			if machineOutput() {
+				if runErr != nil {
+					summary.Error = runErr.Error()
+					runErr = reportedError{runErr}
+				}
+				if err := emit(summary); err != nil {
+					return err
+				}
+			} else {
+				printTestResults(results, cfg.Verbose)
+				status := "ok  "
+				if summary.Failed > 0 || runErr != nil {
+					status = "FAIL"
+					fmt.Println("FAIL")
+				}
+				fmt.Printf("%s\t%s\t%.3fs\n", status, m.Name, elapsed.Seconds())
+				fmt.Println()
+			}
+			if len(specs) > 0 {
+				if err := writeReports(specs, testReport(dir, res, results)); err != nil {
+					return err
+				}
+			}
+			switch {
+			case runErr != nil:
+				return runErr
+			case summary.Failed > 0:
+				return reportedError{fmt.Errorf("%d of %d test(s) failed", summary.Failed, len(results))}
+			}
+			if !machineOutput() {
+				ui.Success(fmt.Sprintf("%d test(s) passed", summary.Passed))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&board, "board", "b", "", "board whose name the sketch is transpiled for (default from manifest)")
+	cmd.Flags().StringVar(&run, "run", "", "run only the tests whose names match this regular expression")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "give up after this much real time")
+	addReportFlag(cmd, &reports)
+	return cmd
+}
+
+// printTestResults prints results as go test does: with verbose, every
+// test and its log; otherwise only the failures.
+func printTestResults(results []host.TestResult, verbose bool) {
+	for _, r := range results {
+		if !verbose && r.Status != host.TestFail {
+			continue
+		}
+		if verbose {
+			fmt.Printf("=== RUN   %s\n", r.Name)
+		}
+		label, color := "PASS", ui.ColorNumber
+		switch r.Status {
+		case host.TestFail:
+			label, color = "FAIL", ui.ColorError
+		case host.TestSkip:
+			label, color = "SKIP", ui.ColorWarn
+		}
+		if !verbose {
+			color.Printf("--- %s: %s (%.2fs)\n", label, r.Name, r.Duration.Seconds())
+		}
+		for _, line := range r.Output {
+			fmt.Println("    " + strings.ReplaceAll(line, "\n", "\n        "))
+		}
+		if verbose {
+			color.Printf("--- %s: %s (%.2fs)\n", label, r.Name, r.Duration.Seconds())
+		}
+	}
+}
+
+// testReport is the CI report of a test run: one suite per test file.
+func testReport(dir string, res *build.Result, results []host.TestResult) report.Document {
+	doc := report.Document{Command: "test", Version: Version, Root: dir}
+	seen := map[string]bool{}
+	for _, t := range res.Tests {
+		if !seen[t.File] {
+			seen[t.File] = true
+			doc.Files = append(doc.Files, t.File)
+		}
+	}
+	for _, r := range results {
+		doc.Tests = append(doc.Tests, report.Test{
+			Name:    r.Name,
+			File:    r.File,
+			Line:    r.Line,
+			Status:  r.Status,
+			Seconds: r.Duration.Seconds(),
+			Output:  strings.Join(r.Output, "\n"),
+		})
+	}
+	return doc
+}
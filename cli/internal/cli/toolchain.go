@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/toolchain"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+func newToolchainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "toolchain",
+		Short: "Install the tool versions pinned in tsuki_package.json",
+		Long: `A "toolchain" section in tsuki_package.json pins the exact versions of
+tsuki-core, tsuki-flash, arduino-cli and the board cores a project builds
+with:
+
+  "toolchain": {
+    "tsuki_core": "3.1.0",
+    "tsuki_flash": "0.6.2",
+    "arduino_cli": "1.0.4",
+    "cores": { "arduino:avr": "1.8.6" }
+  }
+
+'tsuki toolchain install' downloads the pinned tools into a per-version
+directory of the tsuki data dir, and 'tsuki build' and 'tsuki upload' use
+them instead of core_binary, flash_binary and arduino_cli from the config.`,
+	}
+
+	cmd.AddCommand(
+		newToolchainInstallCmd(),
+		newToolchainListCmd(),
+	)
+	return cmd
+}
+
+// pinnedProject loads the project manifest, which must pin a toolchain.
+func pinnedProject() (*manifest.Manifest, error) {
+	_, m, err := manifest.Find(projectDir())
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("no %s found — run `tsuki init` first", manifest.FileName)
+	}
+	if m.Toolchain == nil {
+		return nil, fmt.Errorf("%s pins no toolchain — add a \"toolchain\" section first (see tsuki toolchain --help)", manifest.FileName)
+	}
+	return m, nil
+}
+
+// pinnedBackend creates the project's backend with the pinned binaries.
+func pinnedBackend(backendName string, m *manifest.Manifest) (backend.Backend, error) {
+	bins, err := toolchain.Resolve(m.Toolchain)
+	if err != nil {
+		return nil, err
+	}
+	flashBin, arduinoCLI := cfg.FlashBinary, cfg.ArduinoCLI
+	bins.Apply(nil, &flashBin, &arduinoCLI)
+	return backend.New(backend.Resolve(backendName, m, cfg), backend.Tools{
+		ArduinoCLI:  arduinoCLI,
+		FlashBinary: flashBin,
+		PlatformIO:  cfg.PlatformIO,
+		ProjectDir:  projectDir(),
+		Verbose:     cfg.Verbose,
+	})
+}
+
+// coreIDs returns the keys of the pinned cores, sorted.
+func coreIDs(cores map[string]string) []string {
+	ids := make([]string, 0, len(cores))
+	for id := range cores {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ── toolchain install ─────────────────────────────────────────────────────────
+
+func newToolchainInstallCmd() *cobra.Command {
+	var mirror, backendName string
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Download the pinned tools and install the pinned cores",
+		Example: `  tsuki toolchain install
+  tsuki toolchain install --mirror https://mirror.example.com/tsuki
+  tsuki toolchain install --mirror file:///srv/tsuki-mirror`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := pinnedProject()
+			if err != nil {
+				return err
+			}
+			mirror = toolchain.Mirror(mirror, m.Toolchain)
+
+			pins, err := toolchain.Pins(m.Toolchain)
+			if err != nil {
+				return err
+			}
+
+			ui.SectionTitle("Installing toolchain")
+			for _, p := range pins {
+				if p.Installed {
+					ui.Step("toolchain", fmt.Sprintf("%s %s already installed", p.Tool, p.Version))
+					continue
+				}
+				sp := ui.NewSpinner(fmt.Sprintf("Downloading %s %s…", p.Tool, p.Version))
+				sp.Start()
+				path, err := toolchain.Install(p, mirror)
+				if err != nil {
+					sp.Stop(false, fmt.Sprintf("%s %s not installed", p.Tool, p.Version))
+					return err
+				}
+				sp.Stop(true, fmt.Sprintf("%s %s  →  %s", p.Tool, p.Version, path))
+			}
+
+			if len(m.Toolchain.Cores) > 0 {
+				b, err := pinnedBackend(backendName, m)
+				if err != nil {
+					return err
+				}
+				cm, err := coreManager(b)
+				if err != nil {
+					return err
+				}
+				for _, id := range coreIDs(m.Toolchain.Cores) {
+					version := m.Toolchain.Cores[id]
+					c, err := resolveCore(cm, id, "", m)
+					if err != nil {
+						return err
+					}
+					if c.Version == version {
+						ui.Step("toolchain", fmt.Sprintf("%s already installed", c))
+						continue
+					}
+					ui.Step("toolchain", fmt.Sprintf("installing %s@%s via %s", id, version, b.Name()))
+					if err := cm.InstallCoreVersion(id, version); err != nil {
+						return err
+					}
+				}
+			}
+
+			if machineOutput() {
+				pins, err := toolchain.Pins(m.Toolchain)
+				if err != nil {
+					return err
+				}
+				return emit(pins)
+			}
+			ui.Success(fmt.Sprintf("Toolchain pinned in %s installed", manifest.FileName))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&mirror, "mirror", "", "download from this mirror (<mirror>/<tool>/<version>/<file>; file:// works)")
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend to install the cores through (default: the project's)")
+	return cmd
+}
+
+// ── toolchain list ────────────────────────────────────────────────────────────
+
+// pinnedCore is a pinned board core in `tsuki toolchain list`.
+type pinnedCore struct {
+	ID        string `json:"id"`
+	Pinned    string `json:"pinned"`
+	Installed string `json:"installed,omitempty"`
+}
+
+func newToolchainListCmd() *cobra.Command {
+	var backendName string
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Show the pinned tools and whether they are installed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := pinnedProject()
+			if err != nil {
+				return err
+			}
+			pins, err := toolchain.Pins(m.Toolchain)
+			if err != nil {
+				return err
+			}
+			if pins == nil {
+				pins = []toolchain.Pin{}
+			}
+
+			cores := []pinnedCore{}
+			if len(m.Toolchain.Cores) > 0 {
+				var cm backend.CoreManager
+				if b, err := pinnedBackend(backendName, m); err == nil {
+					cm, _ = b.(backend.CoreManager)
+				}
+				for _, id := range coreIDs(m.Toolchain.Cores) {
+					pc := pinnedCore{ID: id, Pinned: m.Toolchain.Cores[id]}
+					if cm != nil {
+						if c, err := resolveCore(cm, id, "", m); err == nil {
+							pc.Installed = c.Version
+						}
+					}
+					cores = append(cores, pc)
+				}
+			}
+
+			if machineOutput() {
+				return emit(struct {
+					Tools []toolchain.Pin `json:"tools"`
+					Cores []pinnedCore    `json:"cores"`
+				}{pins, cores})
+			}
+
+			ui.SectionTitle("Pinned toolchain")
+			fmt.Println()
+			ui.ColorTitle.Printf("  %-20s  %-10s  %s\n", "TOOL", "PINNED", "INSTALLED")
+			ui.ColorMuted.Println("  " + hline(76, "─"))
+			for _, p := range pins {
+				ui.ColorKey.Printf("  %-20s", p.Tool)
+				ui.ColorNumber.Printf("  %-10s", p.Version)
+				if p.Installed {
+					ui.ColorMuted.Printf("  %s\n", p.Path)
+				} else {
+					ui.ColorError.Println("  missing")
+				}
+			}
+			for _, c := range cores {
+				ui.ColorKey.Printf("  %-20s", c.ID)
+				ui.ColorNumber.Printf("  %-10s", c.Pinned)
+				switch c.Installed {
+				case c.Pinned:
+					ui.ColorMuted.Println("  installed")
+				case "":
+					ui.ColorError.Println("  missing")
+				default:
+					ui.ColorWarn.Printf("  %s installed\n", c.Installed)
+				}
+			}
+			fmt.Println()
+			if !allInstalled(pins, cores) {
+				ui.Info("Run: tsuki toolchain install")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendName, "backend", "", "backend whose cores are checked (default: the project's)")
+	return cmd
+}
+
+func allInstalled(pins []toolchain.Pin, cores []pinnedCore) bool {
+	for _, p := range pins {
+		if !p.Installed {
+			return false
+		}
+	}
+	for _, c := range cores {
+		if c.Installed != c.Pinned {
+			return false
+		}
+	}
+	return true
+}
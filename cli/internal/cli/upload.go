@@ -1,7 +1,15 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/dist"
+	"github.com/tsuki/cli/internal/firmware"
 	"github.com/tsuki/cli/internal/flash"
 	"github.com/tsuki/cli/internal/manifest"
 	"github.com/tsuki/cli/internal/ui"
@@ -11,8 +19,13 @@ func newUploadCmd() *cobra.Command {
 	var (
 		port     string
 		board    string
-		buildDir string
-		backend  string
+		buildDir    string
+		backendName string
+		retries  int
+		delay    time.Duration
+		verify   bool
+		from     string
+		baud     int
 	)
 
 	cmd := &cobra.Command{
@@ -20,41 +33,106 @@ func newUploadCmd() *cobra.Command {
 		Short: "Upload compiled firmware to a connected board",
 		Example: `  tsuki upload
   tsuki upload --port /dev/ttyUSB0
-  tsuki upload --port COM3 --board uno`,
+  tsuki upload --port COM3 --board uno
+  tsuki upload --retries 4 --verify
+  tsuki upload --baud 57600
+  tsuki upload --from build/dist/blink-1.0.0.zip --port /dev/ttyUSB0`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			dir := projectDir()
-			_, m, err := manifest.Find(dir)
-			if err != nil {
+			var m *manifest.Manifest
+			var err error
+			if from != "" {
+				// Flash straight from a `tsuki dist` archive: the unpacked
+				// directory stands in for both the project and the build dir.
+				tmp, err := os.MkdirTemp("", "tsuki-upload-")
+				if err != nil {
+					return err
+				}
+				defer os.RemoveAll(tmp)
+				if dir, err = dist.Extract(from, tmp); err != nil {
+					return err
+				}
+				if m, err = manifest.Load(dir); err != nil {
+					return err
+				}
+				buildDir = dir
+				// The build-info records what the image was actually built
+				// for; it beats the manifest defaults.
+				if info := archiveBuildInfo(dir); info != nil {
+					if board == "" {
+						board = info.Board
+					}
+					if backendName == "" {
+						backendName = info.Backend
+					}
+				}
+				ui.Info(fmt.Sprintf("Flashing %s %s from %s", m.Name, m.Version, filepath.Base(from)))
+			} else if _, m, err = manifest.Find(dir); err != nil {
 				return err
 			}
 
-			// Resolve effective backend: flag > manifest > config.
-			effectiveBackend := backend
-			if effectiveBackend == "" {
-				effectiveBackend = m.Backend
+			effectiveBackend := backend.Resolve(backendName, m, cfg)
+
+			// Flags win over the config defaults only when given explicitly.
+			if !cmd.Flags().Changed("retries") {
+				retries = cfg.UploadRetries
 			}
-			if effectiveBackend == "" {
-				effectiveBackend = cfg.Backend
+			if !cmd.Flags().Changed("retry-delay") {
+				delay = time.Duration(cfg.UploadRetryDelayMs) * time.Millisecond
+			}
+			if !cmd.Flags().Changed("verify") {
+				verify = cfg.UploadVerify
 			}
 
 			// Show the backend badge before uploading.
 			ui.FlashBadge(effectiveBackend)
 
-			return flash.Run(dir, m, flash.Options{
+			res, err := flash.Run(dir, m, flash.Options{
 				Port:        port,
 				Board:       board,
 				BuildDir:    buildDir,
 				ArduinoCLI:  cfg.ArduinoCLI,
 				FlashBinary: cfg.FlashBinary,
+				PlatformIO:  cfg.PlatformIO,
 				Backend:     effectiveBackend,
 				Verbose:     cfg.Verbose,
+				Retries:     retries,
+				RetryDelay:  delay,
+				Verify:      verify,
+				Baud:        baud,
 			})
+			if err != nil {
+				return err
+			}
+			if machineOutput() {
+				return emit(res)
+			}
+			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&port, "port", "p", "", "serial port (auto-detect if omitted)")
 	cmd.Flags().StringVarP(&board, "board", "b", "", "target board (overrides manifest)")
 	cmd.Flags().StringVar(&buildDir, "build-dir", "", "directory with compiled firmware")
-	cmd.Flags().StringVar(&backend, "backend", "", "override backend: tsuki-flash | tsuki-flash+cores | arduino-cli")
+	cmd.Flags().StringVar(&backendName, "backend", "", "override backend (default from manifest, then config)")
+	cmd.Flags().IntVar(&retries, "retries", 2, "extra attempts after a transient failure (default from config)")
+	cmd.Flags().DurationVar(&delay, "retry-delay", 500*time.Millisecond, "wait before the first retry, doubled each attempt")
+	cmd.Flags().BoolVar(&verify, "verify", false, "read the flash back and compare it with the built image")
+	cmd.Flags().IntVar(&baud, "baud", 0, "upload speed (default: the board's)")
+	cmd.Flags().StringVar(&from, "from", "", "flash a `tsuki dist` archive (.zip / .tar.gz) instead of the project build")
 	return cmd
+}
+
+// archiveBuildInfo returns the build-info of the image in an unpacked dist
+// archive, or nil when there is none.
+func archiveBuildInfo(dir string) *firmware.BuildInfo {
+	image := firmware.Find(dir)
+	if image == "" {
+		return nil
+	}
+	info, err := firmware.ReadBuildInfo(firmware.BuildInfoPath(image))
+	if err != nil {
+		return nil
+	}
+	return info
 }
\ No newline at end of file
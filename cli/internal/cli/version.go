@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/ui"
+	"github.com/tsuki/cli/internal/vcs"
+)
+
+// ── version bump ──────────────────────────────────────────────────────────────
+
+func newVersionBumpCmd() *cobra.Command {
+	var (
+		tag    bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bump <major|minor|patch|VERSION>",
+		Short: "Bump the project version in tsuki_package.json",
+		Long: `Bump the semantic version of the current project.
+
+The new version is written to tsuki_package.json and compiled into the
+firmware as buildinfo.Version:
+
+  import "buildinfo"
+  arduino.Serial.Println(buildinfo.Version)
+
+With --tag the manifest change is committed and tagged v<version> in git.`,
+		Example: `  tsuki version bump patch
+  tsuki version bump minor --tag
+  tsuki version bump 2.0.0-rc.1`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, m, err := manifest.Find(projectDir())
+			if err != nil {
+				return err
+			}
+
+			next, err := manifest.BumpVersion(m.Version, args[0])
+			if err != nil {
+				return err
+			}
+			tagName := "v" + next
+
+			if tag {
+				if !vcs.IsRepo(dir) {
+					return fmt.Errorf("--tag needs a git repository, but %s is not one", dir)
+				}
+				if vcs.TagExists(dir, tagName) {
+					return fmt.Errorf("git tag %s already exists", tagName)
+				}
+			}
+
+			if dryRun {
+				ui.Info(fmt.Sprintf("Would bump %s: %s → %s", m.Name, m.Version, next))
+				return nil
+			}
+
+			prev := m.Version
+			m.Version = next
+			if err := m.Save(dir); err != nil {
+				return err
+			}
+			ui.Success(fmt.Sprintf("Bumped %s: %s → %s", m.Name, prev, next))
+
+			if tag {
+				if err := vcs.CommitFiles(dir, tagName, manifest.FileName); err != nil {
+					return err
+				}
+				if err := vcs.Tag(dir, tagName, fmt.Sprintf("%s %s", m.Name, next)); err != nil {
+					return err
+				}
+				ui.Success(fmt.Sprintf("Committed and tagged %s", tagName))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&tag, "tag", false, "commit the manifest and create git tag v<version>")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the new version without writing it")
+	return cmd
+}
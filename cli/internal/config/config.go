@@ -19,21 +19,33 @@ import (
 	"strings"
 )
 
+// ProjectURL is tsuki's home page, where its releases are published.
+const ProjectURL = "https://github.com/s7lver2/tsuki"
+
 const defaultRegistryURL = "https://raw.githubusercontent.com/s7lver2/tsuki/refs/heads/main/pkg/packages.json"
 const defaultKeysIndexURL = "https://raw.githubusercontent.com/s7lver2/tsuki/refs/heads/main/pkg/keys/index.json"
 
 // Config holds all persistent user-level settings.
 type Config struct {
 	// ── Core tools ──────────────────────────────────────────────────────────
-	CoreBinary  string `json:"core_binary"  comment:"path to tsuki-core binary"`
-	ArduinoCLI  string `json:"arduino_cli"  comment:"path to arduino-cli binary"`
-	FlashBinary string `json:"flash_binary" comment:"path to tsuki-flash binary (used when backend=tsuki-flash)"`
-	// Backend selects the compile+upload toolchain: "tsuki-flash" or "arduino-cli".
+	CoreBinary   string `json:"core_binary"   comment:"path to tsuki-core binary"`
+	ArduinoCLI   string `json:"arduino_cli"   comment:"path to arduino-cli binary"`
+	FlashBinary  string `json:"flash_binary"  comment:"path to tsuki-flash binary (used when backend=tsuki-flash)"`
+	PlatformIO   string `json:"platformio"    comment:"path to the pio binary (used when backend=platformio)"`
+	HostCompiler string `json:"host_compiler" comment:"C++ compiler for --target host builds (tsuki sim)"`
+	// Backend selects the compile+upload toolchain when the project names none:
+	// "arduino-cli", "platformio", "tsuki-flash", "tsuki-flash+cores" or an
+	// external template.
 	// Set with: tsuki config set backend tsuki-flash
-	Backend      string `json:"backend"       comment:"compiler backend: tsuki-flash or arduino-cli (default: arduino-cli)"`
+	Backend      string `json:"backend"       comment:"compiler backend: arduino-cli, platformio, tsuki-flash, tsuki-flash+cores or a template name"`
 	DefaultBoard string `json:"default_board" comment:"default target board"`
 	DefaultBaud  int    `json:"default_baud"  comment:"default serial baud rate"`
 
+	// ── Upload ──────────────────────────────────────────────────────────────
+	UploadRetries      int  `json:"upload_retries"        comment:"extra upload attempts after a transient failure"`
+	UploadRetryDelayMs int  `json:"upload_retry_delay_ms" comment:"wait before the first upload retry (doubles each attempt)"`
+	UploadVerify       bool `json:"upload_verify"         comment:"read the flash back after uploading and compare it"`
+
 	// ── Output ──────────────────────────────────────────────────────────────
 	Color      bool `json:"color"       comment:"enable colored output"`
 	Verbose    bool `json:"verbose"     comment:"verbose command output"`
@@ -68,21 +80,26 @@ type Config struct {
 // Default returns a Config with sensible defaults.
 func Default() *Config {
 	return &Config{
-		CoreBinary:       "",
-		ArduinoCLI:       "arduino-cli",
-		FlashBinary:      "tsuki-flash",
-		Backend:          "arduino-cli",
-		DefaultBoard:     "uno",
-		DefaultBaud:      9600,
-		Color:            true,
-		Verbose:          false,
-		AutoDetect:       true,
-		LibsDir:          "",
-		RegistryURL:      "",
-		RegistryURLs:     []string{}, // empty: falls through to registry_url or env var
-		KeysDir:          "",
-		KeysIndexURL:     defaultKeysIndexURL,
-		VerifySignatures: false,
+		CoreBinary:         "",
+		ArduinoCLI:         "arduino-cli",
+		FlashBinary:        "tsuki-flash",
+		PlatformIO:         "pio",
+		HostCompiler:       "g++",
+		Backend:            "arduino-cli",
+		DefaultBoard:       "uno",
+		DefaultBaud:        9600,
+		UploadRetries:      2,
+		UploadRetryDelayMs: 500,
+		UploadVerify:       false,
+		Color:              true,
+		Verbose:            false,
+		AutoDetect:         true,
+		LibsDir:            "",
+		RegistryURL:        "",
+		RegistryURLs:       []string{}, // empty: falls through to registry_url or env var
+		KeysDir:            "",
+		KeysIndexURL:       defaultKeysIndexURL,
+		VerifySignatures:   false,
 	}
 }
 
@@ -187,6 +204,23 @@ func defaultKeysDir() string {
 	return filepath.Join(home, ".local", "share", "tsuki", "keys")
 }
 
+// DataDir is where tsuki keeps downloaded tools: $XDG_DATA_HOME/tsuki,
+// ~/.local/share/tsuki, or %APPDATA%\tsuki on Windows.
+func DataDir() string {
+	if runtime.GOOS == "windows" {
+		base := os.Getenv("APPDATA")
+		if base == "" {
+			base = filepath.Join(os.Getenv("USERPROFILE"), "AppData", "Roaming")
+		}
+		return filepath.Join(base, "tsuki")
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "tsuki")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "tsuki")
+}
+
 // ── Config file I/O ───────────────────────────────────────────────────────────
 
 func configPath() (string, error) {
@@ -329,4 +363,4 @@ func (c *Config) AllEntries() []Entry {
 
 func Path() (string, error) {
 	return configPath()
-}
\ No newline at end of file
+}
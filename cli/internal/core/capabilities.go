@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ── Version & capability negotiation ──────────────────────────────────────────
+//
+// `tsuki-core --capabilities` prints
+//
+//	{"version":"3.0.0","diagnostics":1,"capabilities":["check","libs-dir",…]}
+//
+// The answer is cached per binary (keyed by path, size and mtime) in the
+// user cache dir, so only the first run after installing a core pays for
+// the probe. Cores that predate the flag only answer --version and are
+// assumed to have legacyCapabilities.
+
+// Capability names reported by the core.
+const (
+	CapCheck           = "check"            // --check
+	CapSourceMap       = "source-map"       // --source-map
+	CapLibsDir         = "libs-dir"         // --libs-dir
+	CapPackages        = "packages"         // --packages
+	CapDiagnosticsJSON = "diagnostics-json" // --diagnostics=json
+	CapServe           = "serve"            // --serve
+	CapTesting         = "testing"          // the testing and sim packages (tsuki test)
+)
+
+// legacyCapabilities are the flags every core without --capabilities has.
+var legacyCapabilities = []string{CapCheck, CapSourceMap, CapLibsDir, CapPackages}
+
+// Info describes an installed core.
+type Info struct {
+	Path         string   `json:"path"`
+	Version      string   `json:"version"`
+	Diagnostics  int      `json:"diagnostics"` // newest diagnostics document it emits; 0 = none
+	Capabilities []string `json:"capabilities"`
+	// Legacy is set for cores that predate --capabilities; Capabilities
+	// then holds what such cores are assumed to support.
+	Legacy bool `json:"legacy"`
+}
+
+// Has reports whether the core supports capability c.
+func (i *Info) Has(c string) bool {
+	for _, have := range i.Capabilities {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Require returns an upgrade error when the core lacks capability c.
+// feature describes what needs it, e.g. "external packages".
+func (i *Info) Require(c, feature string) error {
+	if i.Has(c) {
+		return nil
+	}
+	return &UpgradeError{Version: i.Version, Capability: c, Feature: feature}
+}
+
+// UpgradeError reports a core too old for a requested feature.
+type UpgradeError struct {
+	Version    string
+	Capability string
+	Feature    string
+}
+
+func (e *UpgradeError) Error() string {
+	return fmt.Sprintf(
+		"tsuki-core %s does not support %s (needs %q)\n"+
+			"  Upgrade tsuki-core, or point core_binary at a newer one:\n"+
+			"  tsuki config set core_binary /path/to/tsuki-core",
+		e.Version, e.Feature, e.Capability,
+	)
+}
+
+// detected is the in-process cache: a command probes a binary at most once.
+var detected = map[string]*Info{}
+
+// Detect returns the version and capabilities of binary ("" means
+// tsuki-core on PATH), from the cache when the binary has not changed.
+func Detect(binary string) (*Info, error) {
+	if binary == "" {
+		binary = defaultBinary
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found", binary)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if info, ok := detected[path]; ok {
+		return info, nil
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s|%d|%d", path, st.Size(), st.ModTime().UnixNano())
+
+	cache := loadCapabilityCache()
+	if info, ok := cache[key]; ok {
+		detected[path] = info
+		return info, nil
+	}
+
+	info, err := probe(path)
+	if err != nil {
+		return nil, err
+	}
+	detected[path] = info
+	// Drop stale entries for this binary before saving the new one.
+	for k := range cache {
+		if strings.HasPrefix(k, path+"|") {
+			delete(cache, k)
+		}
+	}
+	cache[key] = info
+	saveCapabilityCache(cache)
+	return info, nil
+}
+
+// probe asks the binary itself.
+func probe(path string) (*Info, error) {
+	if out, err := runProbe(path, "--capabilities"); err == nil {
+		var info Info
+		if json.Unmarshal([]byte(strings.TrimSpace(out)), &info) == nil && info.Version != "" {
+			info.Path = path
+			return &info, nil
+		}
+	}
+
+	out, err := runProbe(path, "--version")
+	if err != nil {
+		return nil, fmt.Errorf("cannot run %s: %w", path, err)
+	}
+	version := strings.TrimSpace(out)
+	if f := strings.Fields(version); len(f) > 0 {
+		version = f[len(f)-1]
+	}
+	return &Info{
+		Path:         path,
+		Version:      version,
+		Capabilities: append([]string(nil), legacyCapabilities...),
+		Legacy:       true,
+	}, nil
+}
+
+// runProbe runs the binary with one flag, giving up after a few seconds.
+func runProbe(path, flag string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, flag)
+	// A killed script can leave children holding stdout open.
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%s %s timed out", path, flag)
+	}
+	return string(out), err
+}
+
+func capabilityCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "tsuki", "core-capabilities.json")
+}
+
+func loadCapabilityCache() map[string]*Info {
+	cache := map[string]*Info{}
+	path := capabilityCachePath()
+	if path == "" {
+		return cache
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// saveCapabilityCache is best-effort: a read-only cache only costs a probe.
+func saveCapabilityCache(cache map[string]*Info) {
+	path := capabilityCachePath()
+	if path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+		_ = os.WriteFile(path, data, 0644)
+	}
+}
@@ -1,6 +1,10 @@
 // ─────────────────────────────────────────────────────────────────────────────
 //  tsuki :: core  (updated)
-//  Shell-out to tsuki-core with --libs-dir and --packages support.
+//  Shell-out to tsuki-core with --libs-dir and --packages support, one
+//  process per file. This is the fallback Transpiler; see serve.go for the
+//  long-running one.
+//  Diagnostics are read from the --diagnostics=json document when the core
+//  supports it, or scraped from its text output otherwise (see diagnostics.go).
 // ─────────────────────────────────────────────────────────────────────────────
 
 package core
@@ -15,22 +19,26 @@ import (
 	"github.com/tsuki/cli/internal/ui"
 )
 
-const defaultBinary = "tsuki-core"
-
-// Transpiler wraps the tsuki-core binary.
-type Transpiler struct {
+// ExecTranspiler runs the tsuki-core binary once per file.
+type ExecTranspiler struct {
 	binary  string
 	verbose bool
+	// info is what the core supports; nil when it could not be detected,
+	// in which case every flag is passed as before negotiation existed.
+	info   *Info
+	warned map[string]bool
 }
 
-func New(binary string, verbose bool) *Transpiler {
+// NewExec returns a Transpiler that forks binary for every file.
+func NewExec(binary string, verbose bool) *ExecTranspiler {
 	if binary == "" {
 		binary = defaultBinary
 	}
-	return &Transpiler{binary: binary, verbose: verbose}
+	info, _ := Detect(binary)
+	return &ExecTranspiler{binary: binary, verbose: verbose, info: info, warned: map[string]bool{}}
 }
 
-// TranspileRequest bundles all parameters for a single transpilation run.
+// TranspileRequest bundles all parameters for transpiling one file.
 type TranspileRequest struct {
 	InputFile  string
 	OutputFile string
@@ -44,27 +52,36 @@ type TranspileRequest struct {
 	PkgNames []string
 }
 
-// TranspileResult holds the output of a transpilation run.
+// TranspileResult holds the outcome for one file. OutputFile is empty when
+// the file failed or was only checked.
 type TranspileResult struct {
-	OutputFile string
-	Warnings   []string
+	InputFile   string
+	OutputFile  string
+	Warnings    []string
+	Diagnostics []Diagnostic
+	// Err is set when this file failed.
+	Err error
 }
 
-// Transpile transpiles a single .go file to C++.
-func (t *Transpiler) Transpile(req TranspileRequest) (*TranspileResult, error) {
-	args := []string{req.InputFile, req.OutputFile, "--board", req.Board}
-
-	if req.SourceMap {
-		args = append(args, "--source-map")
-	}
+// diagnosticsFlag asks tsuki-core for a JSON diagnostics document on stderr.
+// Cores that predate it ignore unknown flags and keep printing text.
+const diagnosticsFlag = "--diagnostics=json"
 
-	// Pass library info to core
-	if req.LibsDir != "" {
-		args = append(args, "--libs-dir", req.LibsDir)
+// Transpile transpiles each file to C++ in its own process.
+func (t *ExecTranspiler) Transpile(reqs []TranspileRequest) ([]*TranspileResult, error) {
+	if err := t.supports(reqs); err != nil {
+		return failAll(reqs, err), err
 	}
-	if len(req.PkgNames) > 0 {
-		args = append(args, "--packages", strings.Join(req.PkgNames, ","))
+	results := make([]*TranspileResult, 0, len(reqs))
+	for _, req := range reqs {
+		results = append(results, t.transpile(req))
 	}
+	return results, batchError("transpilation", results)
+}
+
+func (t *ExecTranspiler) transpile(req TranspileRequest) *TranspileResult {
+	args := []string{req.InputFile, req.OutputFile, "--board", req.Board}
+	args = append(args, t.flags(req)...)
 
 	cmd := exec.Command(t.binary, args...)
 	var stdout, stderr bytes.Buffer
@@ -75,28 +92,52 @@ func (t *Transpiler) Transpile(req TranspileRequest) (*TranspileResult, error) {
 		ui.Step("core", strings.Join(append([]string{t.binary}, args...), " "))
 	}
 
+	res := &TranspileResult{InputFile: req.InputFile}
 	if err := cmd.Run(); err != nil {
 		errOutput := stderr.String()
-		if errOutput != "" {
+		res.Diagnostics = diagnosticsOf(errOutput, req.InputFile)
+		if !renderErrors(res.Diagnostics) && errOutput != "" {
 			renderCoreError(errOutput, req.InputFile)
 		}
-		return nil, fmt.Errorf("transpilation failed: %w", err)
+		res.Err = fmt.Errorf("transpilation failed: %w", err)
+		return res
 	}
 
-	return &TranspileResult{
-		OutputFile: req.OutputFile,
-		Warnings:   parseWarnings(stderr.String()),
-	}, nil
+	res.OutputFile = req.OutputFile
+	res.Diagnostics = diagnosticsOf(stderr.String(), req.InputFile)
+	res.Warnings = warningsOf(res.Diagnostics)
+	return res
+}
+
+// Check validates each file without producing output and returns what the
+// core reported about it. OutputFile is ignored.
+func (t *ExecTranspiler) Check(reqs []TranspileRequest) ([]*TranspileResult, error) {
+	if err := t.supports(reqs); err != nil {
+		return failAll(reqs, err), err
+	}
+	results := make([]*TranspileResult, 0, len(reqs))
+	for _, req := range reqs {
+		results = append(results, t.check(req))
+	}
+	return results, batchError("check", results)
 }
 
-// Check validates a .go source file without producing output.
-func (t *Transpiler) Check(inputFile, board, libsDir string, pkgNames []string) ([]string, []string, error) {
-	args := []string{inputFile, "--board", board, "--check"}
-	if libsDir != "" {
-		args = append(args, "--libs-dir", libsDir)
+func (t *ExecTranspiler) check(req TranspileRequest) *TranspileResult {
+	args := []string{req.InputFile, "--board", req.Board, "--check"}
+	if !t.has(CapCheck) {
+		// No --check: transpile into a scratch file and discard it.
+		tmp, err := os.CreateTemp("", "tsuki-check-*.cpp")
+		if err != nil {
+			return &TranspileResult{InputFile: req.InputFile, Err: err}
+		}
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+		args = []string{req.InputFile, tmp.Name(), "--board", req.Board}
 	}
-	if len(pkgNames) > 0 {
-		args = append(args, "--packages", strings.Join(pkgNames, ","))
+	args = append(args, t.flags(req)...)
+
+	if t.verbose {
+		ui.Step("core", strings.Join(append([]string{t.binary}, args...), " "))
 	}
 
 	cmd := exec.Command(t.binary, args...)
@@ -105,19 +146,89 @@ func (t *Transpiler) Check(inputFile, board, libsDir string, pkgNames []string)
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	combined := stdout.String() + stderr.String()
 
-	warnings := parseWarnings(combined)
-	errors   := parseErrors(stderr.String())
+	diags, ok := parseDiagnostics(stderr.String())
+	if !ok {
+		diags = scrapeDiagnostics(stdout.String()+stderr.String(), req.InputFile)
+	}
 
+	res := &TranspileResult{InputFile: req.InputFile, Diagnostics: diags, Warnings: warningsOf(diags)}
 	if err != nil {
-		return warnings, errors, fmt.Errorf("check failed")
+		res.Err = fmt.Errorf("check failed")
+	}
+	return res
+}
+
+// has reports whether the core supports c; undetected cores get the benefit
+// of the doubt.
+func (t *ExecTranspiler) has(c string) bool {
+	return t.info == nil || t.info.Has(c)
+}
+
+// supports refuses a batch that needs flags the core does not have.
+func (t *ExecTranspiler) supports(reqs []TranspileRequest) error {
+	if t.info == nil {
+		return nil
+	}
+	for _, req := range reqs {
+		if req.LibsDir != "" {
+			if err := t.info.Require(CapLibsDir, "--libs-dir (external packages and buildinfo)"); err != nil {
+				return err
+			}
+		}
+		if len(req.PkgNames) > 0 {
+			if err := t.info.Require(CapPackages, "--packages (external packages)"); err != nil {
+				return err
+			}
+		}
+		if strings.HasSuffix(req.InputFile, "_test.go") {
+			if err := t.info.Require(CapTesting, "tests (the testing and sim packages)"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flags are the options shared by transpile and check runs. Options the
+// core cannot honour but can do without are dropped with a warning.
+func (t *ExecTranspiler) flags(req TranspileRequest) []string {
+	var args []string
+	if req.SourceMap {
+		if t.has(CapSourceMap) {
+			args = append(args, "--source-map")
+		} else if !t.warned[CapSourceMap] {
+			t.warned[CapSourceMap] = true
+			ui.Warn(fmt.Sprintf("tsuki-core %s does not support source maps — "+
+				"compile errors will point at the generated C++; upgrade tsuki-core", t.info.Version))
+		}
+	}
+	if req.LibsDir != "" {
+		args = append(args, "--libs-dir", req.LibsDir)
+	}
+	if len(req.PkgNames) > 0 {
+		args = append(args, "--packages", strings.Join(req.PkgNames, ","))
+	}
+	if t.has(CapDiagnosticsJSON) {
+		args = append(args, diagnosticsFlag)
+	}
+	return args
+}
+
+// failAll marks every request of a batch as failed with err.
+func failAll(reqs []TranspileRequest, err error) []*TranspileResult {
+	results := make([]*TranspileResult, 0, len(reqs))
+	for _, req := range reqs {
+		results = append(results, &TranspileResult{InputFile: req.InputFile, Err: err})
 	}
-	return warnings, errors, nil
+	return results
 }
 
 // Version returns the version string of the core binary.
-func (t *Transpiler) Version() (string, error) {
+func (t *ExecTranspiler) Version() (string, error) {
+	if t.info != nil {
+		return "tsuki " + t.info.Version, nil
+	}
 	out, err := exec.Command(t.binary, "--version").Output()
 	if err != nil {
 		return "", fmt.Errorf("cannot run %s: %w", t.binary, err)
@@ -126,11 +237,14 @@ func (t *Transpiler) Version() (string, error) {
 }
 
 // Installed reports whether the core binary is on PATH.
-func (t *Transpiler) Installed() bool {
+func (t *ExecTranspiler) Installed() bool {
 	_, err := exec.LookPath(t.binary)
 	return err == nil
 }
 
+// Close is a no-op: every run has already exited.
+func (t *ExecTranspiler) Close() error { return nil }
+
 // ── Error rendering ───────────────────────────────────────────────────────────
 
 func renderCoreError(raw, inputFile string) {
@@ -195,37 +309,24 @@ func renderCoreError(raw, inputFile string) {
 		currentFrame.Code = codeLines
 		frames = append(frames, *currentFrame)
 	}
+	// Keep only located frames, preferring the file on disk over the
+	// excerpt echoed by the core.
+	located := frames[:0]
+	for _, f := range frames {
+		ref := ui.SourceRef{File: f.File, Line: f.Line}
+		if !ref.Located() {
+			continue
+		}
+		if code := ui.SourceLines(ref, ui.DefaultContext); len(code) > 0 {
+			f.Code = code
+		}
+		located = append(located, f)
+	}
+	frames = located
 	if errType == "" {
 		errType = "TranspileError"
 		errMsg  = strings.TrimSpace(raw)
 	}
-	if len(frames) == 0 {
-		frames = []ui.Frame{{
-			File: inputFile, Line: 0, Func: "transpile",
-			Code: []ui.CodeLine{{Number: 0, Text: errMsg, IsPointer: true}},
-		}}
-	}
-
 	ui.Traceback(errType, errMsg, frames)
 	_ = os.Stderr
 }
-
-func parseWarnings(output string) []string {
-	var w []string
-	for _, line := range strings.Split(output, "\n") {
-		if strings.Contains(strings.ToLower(line), "warning") {
-			w = append(w, strings.TrimSpace(line))
-		}
-	}
-	return w
-}
-
-func parseErrors(output string) []string {
-	var e []string
-	for _, line := range strings.Split(output, "\n") {
-		if strings.Contains(strings.ToLower(line), "error") {
-			e = append(e, strings.TrimSpace(line))
-		}
-	}
-	return e
-}
\ No newline at end of file
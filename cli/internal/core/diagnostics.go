@@ -0,0 +1,238 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// DiagnosticsVersion is the newest `--diagnostics=json` document this CLI
+// understands. Newer documents are treated like output from an old core and
+// handed to the text scraper.
+const DiagnosticsVersion = 1
+
+// Severity of a diagnostic.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+	SeverityHelp    Severity = "help"
+)
+
+// Position is a 1-based line/column location.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Range is a source interval; End is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Note is secondary information attached to a diagnostic.
+type Note struct {
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Range   *Range `json:"range,omitempty"`
+}
+
+// Fix is a suggested change that resolves a diagnostic.
+type Fix struct {
+	Message string `json:"message"`
+	Edits   []Edit `json:"edits"`
+}
+
+// Edit replaces Range in File with NewText.
+type Edit struct {
+	File    string `json:"file"`
+	Range   Range  `json:"range"`
+	NewText string `json:"new_text"`
+}
+
+// Diagnostic is one error or warning reported by tsuki-core.
+// Range is nil when the core could not attribute it to a location.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	File     string   `json:"file"`
+	Range    *Range   `json:"range,omitempty"`
+	Message  string   `json:"message"`
+	Notes    []Note   `json:"notes"`
+	Fixes    []Fix    `json:"fixes"`
+}
+
+// Line returns the start line, or 0 when the diagnostic has no location.
+func (d Diagnostic) Line() int {
+	if d.Range == nil {
+		return 0
+	}
+	return d.Range.Start.Line
+}
+
+// Column returns the start column, or 0 when the diagnostic has no location.
+func (d Diagnostic) Column() int {
+	if d.Range == nil {
+		return 0
+	}
+	return d.Range.Start.Column
+}
+
+// SourceRef locates the diagnostic for ui.SourceFrame. The range is
+// underlined when it spans a single line.
+func (d Diagnostic) SourceRef() ui.SourceRef {
+	ref := ui.SourceRef{File: d.File, Line: d.Line(), Column: d.Column()}
+	if d.Range != nil && d.Range.End.Line == d.Range.Start.Line {
+		ref.EndColumn = d.Range.End.Column
+	}
+	return ref
+}
+
+// IsError reports whether the diagnostic fails the build.
+func (d Diagnostic) IsError() bool { return d.Severity == SeverityError }
+
+// String renders "file:line:col: severity: message".
+func (d Diagnostic) String() string {
+	loc := d.File
+	if d.Range != nil {
+		loc = fmt.Sprintf("%s:%d:%d", d.File, d.Range.Start.Line, d.Range.Start.Column)
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, d.Severity, d.Message)
+}
+
+type diagnosticsDocument struct {
+	Version     int          `json:"version"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// parseDiagnostics extracts diagnostics from tsuki-core's stderr.
+// A core that understands --diagnostics=json prints one JSON document on its
+// own line; ok reports whether one was found. Older cores ignore the flag
+// and print human-formatted text, which the caller must scrape instead.
+func parseDiagnostics(stderr string) (diags []Diagnostic, ok bool) {
+	sc := bufio.NewScanner(strings.NewReader(stderr))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(line, `{"version"`) {
+			continue
+		}
+		var doc diagnosticsDocument
+		if err := json.Unmarshal([]byte(line), &doc); err != nil {
+			continue
+		}
+		if doc.Version < 1 || doc.Version > DiagnosticsVersion {
+			return nil, false
+		}
+		return doc.Diagnostics, true
+	}
+	return nil, false
+}
+
+// scrapeDiagnostics is the fallback for cores without --diagnostics=json.
+// It reads the human-formatted output:
+//
+//	error: [parse] src/main.go:4:2  unexpected identifier
+//	  --> src/main.go:4:2
+//
+// Each "error:"/"warning:" line starts a diagnostic and a following "-->"
+// line gives its location. Source excerpts are never mistaken for
+// diagnostics, whatever words they contain.
+func scrapeDiagnostics(output, file string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "error:"), strings.HasPrefix(line, "warning:"):
+			sev, msg, _ := strings.Cut(line, ":")
+			diags = append(diags, Diagnostic{
+				Severity: Severity(sev),
+				Code:     "unknown",
+				File:     file,
+				Message:  stripLegacyPrefix(strings.TrimSpace(msg)),
+			})
+		case strings.HasPrefix(line, "-->") && len(diags) > 0:
+			last := &diags[len(diags)-1]
+			if f, r, ok := parseLocation(strings.TrimSpace(strings.TrimPrefix(line, "-->"))); ok {
+				last.File, last.Range = f, r
+			}
+		}
+	}
+	return diags
+}
+
+// stripLegacyPrefix removes the "[kind] file:line:col  " prefix older cores
+// put in front of the message, since the location is reported separately.
+func stripLegacyPrefix(msg string) string {
+	if !strings.HasPrefix(msg, "[") {
+		return msg
+	}
+	if i := strings.Index(msg, "  "); i >= 0 {
+		return strings.TrimSpace(msg[i:])
+	}
+	return msg
+}
+
+// parseLocation splits "file:line:col" (the file may itself contain colons).
+func parseLocation(loc string) (string, *Range, bool) {
+	parts := strings.Split(loc, ":")
+	if len(parts) < 3 {
+		return "", nil, false
+	}
+	line, err1 := strconv.Atoi(parts[len(parts)-2])
+	col, err2 := strconv.Atoi(parts[len(parts)-1])
+	if err1 != nil || err2 != nil {
+		return "", nil, false
+	}
+	return strings.Join(parts[:len(parts)-2], ":"), &Range{
+		Start: Position{Line: line, Column: col},
+		End:   Position{Line: line, Column: col + 1},
+	}, true
+}
+
+// diagnosticsOf returns the diagnostics in a core run's output, decoding the
+// JSON document when present and scraping otherwise.
+func diagnosticsOf(stderr, file string) []Diagnostic {
+	if diags, ok := parseDiagnostics(stderr); ok {
+		return diags
+	}
+	return scrapeDiagnostics(stderr, file)
+}
+
+// errorType maps a diagnostic code to the traceback heading.
+var errorType = map[string]string{
+	"lex":     "LexError",
+	"parse":   "ParseError",
+	"type":    "TypeError",
+	"codegen": "CodegenError",
+	"io":      "IOError",
+}
+
+// RenderDiagnostic prints an error diagnostic as a rich traceback, followed
+// by its notes and suggested fixes.
+func RenderDiagnostic(d Diagnostic) {
+	errType, ok := errorType[d.Code]
+	if !ok {
+		errType = "TranspileError"
+	}
+
+	var frames []ui.Frame
+	if ref := d.SourceRef(); ref.Located() {
+		frames = append(frames, ui.SourceFrame(ref, "main"))
+	}
+	ui.Traceback(errType, d.Message, frames)
+
+	for _, n := range d.Notes {
+		ui.Info("note: " + n.Message)
+	}
+	for _, f := range d.Fixes {
+		ui.Info("help: " + f.Message)
+	}
+}
@@ -0,0 +1,292 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// ── tsuki-core --serve ────────────────────────────────────────────────────────
+//
+// ServeTranspiler starts `tsuki-core --serve` on first use and sends each
+// batch as one JSON-RPC request over its stdin, one message per line:
+//
+//	→ {"jsonrpc":"2.0","id":2,"method":"transpile","params":{"files":[…]}}
+//	← {"jsonrpc":"2.0","id":2,"result":{"results":[…]}}
+//
+// Cores that do not list the "serve" capability are never started; any
+// other that cannot serve exits at once (it takes the flag for an input
+// file), so the handshake fails and every call goes to the ExecTranspiler.
+// A server that dies mid-batch is abandoned the same way.
+
+// ServeTranspiler talks to a long-running tsuki-core process.
+type ServeTranspiler struct {
+	binary   string
+	verbose  bool
+	fallback *ExecTranspiler
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	nextID  int
+	version string
+	started bool
+	broken  bool // --serve unsupported or the process died: use fallback
+}
+
+// NewServe returns a Transpiler backed by `binary --serve`.
+func NewServe(binary string, verbose bool) *ServeTranspiler {
+	if binary == "" {
+		binary = defaultBinary
+	}
+	return &ServeTranspiler{
+		binary:   binary,
+		verbose:  verbose,
+		fallback: NewExec(binary, verbose),
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type serveFile struct {
+	Input     string   `json:"input"`
+	Output    string   `json:"output,omitempty"`
+	Board     string   `json:"board"`
+	SourceMap bool     `json:"source_map"`
+	LibsDir   string   `json:"libs_dir,omitempty"`
+	Packages  []string `json:"packages"`
+}
+
+type serveResult struct {
+	Input       string       `json:"input"`
+	Output      string       `json:"output"`
+	OK          bool         `json:"ok"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type serveVersion struct {
+	Version     string `json:"version"`
+	Diagnostics int    `json:"diagnostics"`
+}
+
+// start launches the server and checks it answers. It reports false when
+// the fallback must be used instead.
+func (s *ServeTranspiler) start() bool {
+	if s.started || s.broken {
+		return !s.broken
+	}
+	s.started = true
+
+	// Cores that do not advertise --serve are not even tried.
+	if info := s.fallback.info; info != nil && !info.Has(CapServe) {
+		s.broken = true
+		return false
+	}
+
+	cmd := exec.Command(s.binary, "--serve")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		s.broken = true
+		return false
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		s.broken = true
+		return false
+	}
+	if err := cmd.Start(); err != nil {
+		s.broken = true
+		return false
+	}
+	s.cmd, s.stdin, s.stdout = cmd, stdin, bufio.NewReader(stdout)
+
+	var v serveVersion
+	if err := s.call("version", nil, &v); err != nil || v.Diagnostics > DiagnosticsVersion {
+		s.abandon()
+		return false
+	}
+	s.version = v.Version
+	if s.verbose {
+		ui.Step("core", fmt.Sprintf("%s --serve  (tsuki-core %s)", s.binary, v.Version))
+	}
+	return true
+}
+
+// call sends one request and decodes its result into out.
+func (s *ServeTranspiler) call(method string, params, out any) error {
+	s.nextID++
+	req, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: s.nextID, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if _, err := s.stdin.Write(append(req, '\n')); err != nil {
+		return fmt.Errorf("tsuki-core --serve: %w", err)
+	}
+	line, err := s.stdout.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("tsuki-core --serve: %w", err)
+	}
+	var resp rpcResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("tsuki-core --serve: bad response: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("tsuki-core --serve: %s (%d)", resp.Error.Message, resp.Error.Code)
+	}
+	if resp.ID != s.nextID {
+		return fmt.Errorf("tsuki-core --serve: response %d to request %d", resp.ID, s.nextID)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
+
+// batch runs method over reqs on the server. ok is false when the server
+// could not be used and nothing was done.
+func (s *ServeTranspiler) batch(method string, reqs []TranspileRequest) (results []serveResult, ok bool) {
+	if !s.start() {
+		return nil, false
+	}
+	files := make([]serveFile, 0, len(reqs))
+	for _, r := range reqs {
+		f := serveFile{
+			Input:     r.InputFile,
+			Board:     r.Board,
+			SourceMap: r.SourceMap,
+			LibsDir:   r.LibsDir,
+			Packages:  r.PkgNames,
+		}
+		if method == "transpile" {
+			f.Output = r.OutputFile
+		}
+		files = append(files, f)
+	}
+	if s.verbose {
+		ui.Step("core", fmt.Sprintf("%s %d file(s) via --serve", method, len(files)))
+	}
+
+	var resp struct {
+		Results []serveResult `json:"results"`
+	}
+	if err := s.call(method, map[string]any{"files": files}, &resp); err != nil || len(resp.Results) != len(reqs) {
+		if s.verbose {
+			ui.Warn(fmt.Sprintf("tsuki-core --serve failed (%v) — falling back to one process per file", err))
+		}
+		s.abandon()
+		return nil, false
+	}
+	return resp.Results, true
+}
+
+// Transpile sends every file in one request.
+func (s *ServeTranspiler) Transpile(reqs []TranspileRequest) ([]*TranspileResult, error) {
+	// Cores gained capabilities after --serve; refuse what this one lacks.
+	if err := s.fallback.supports(reqs); err != nil {
+		return nil, err
+	}
+	served, ok := s.batch("transpile", reqs)
+	if !ok {
+		return s.fallback.Transpile(reqs)
+	}
+	results := make([]*TranspileResult, 0, len(served))
+	for _, r := range served {
+		res := resultOf("transpilation", r)
+		if res.Err != nil && !renderErrors(res.Diagnostics) {
+			ui.Fail(res.Err.Error())
+		}
+		results = append(results, res)
+	}
+	return results, batchError("transpilation", results)
+}
+
+// Check sends every file in one request.
+func (s *ServeTranspiler) Check(reqs []TranspileRequest) ([]*TranspileResult, error) {
+	if err := s.fallback.supports(reqs); err != nil {
+		return nil, err
+	}
+	served, ok := s.batch("check", reqs)
+	if !ok {
+		return s.fallback.Check(reqs)
+	}
+	results := make([]*TranspileResult, 0, len(served))
+	for _, r := range served {
+		res := resultOf("check", r)
+		res.OutputFile = ""
+		results = append(results, res)
+	}
+	return results, batchError("check", results)
+}
+
+// resultOf converts a served result; what names the operation in errors.
+func resultOf(what string, r serveResult) *TranspileResult {
+	res := &TranspileResult{
+		InputFile:   r.Input,
+		OutputFile:  r.Output,
+		Diagnostics: r.Diagnostics,
+		Warnings:    warningsOf(r.Diagnostics),
+	}
+	if !r.OK {
+		res.OutputFile = ""
+		res.Err = fmt.Errorf("%s failed: %s", what, filepath.Base(r.Input))
+	}
+	return res
+}
+
+// Version returns the server's version, or asks the binary directly.
+func (s *ServeTranspiler) Version() (string, error) {
+	if s.start() && s.version != "" {
+		return "tsuki " + s.version, nil
+	}
+	return s.fallback.Version()
+}
+
+// Installed reports whether the core binary is on PATH.
+func (s *ServeTranspiler) Installed() bool { return s.fallback.Installed() }
+
+// Close asks the server to exit and waits for it.
+func (s *ServeTranspiler) Close() error {
+	if s.cmd == nil {
+		return nil
+	}
+	if !s.broken {
+		_ = s.call("shutdown", nil, nil)
+	}
+	_ = s.stdin.Close()
+	err := s.cmd.Wait()
+	s.cmd = nil
+	return err
+}
+
+// abandon kills the server and routes all further calls to the fallback.
+func (s *ServeTranspiler) abandon() {
+	s.broken = true
+	if s.cmd == nil {
+		return
+	}
+	_ = s.stdin.Close()
+	if s.cmd.Process != nil {
+		_ = s.cmd.Process.Kill()
+	}
+	_ = s.cmd.Wait()
+	s.cmd = nil
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const defaultBinary = "tsuki-core"
+
+// Transpiler turns Go source files into C++. Requests are batched: a
+// failing file never stops the others, and the returned error summarises
+// every failure while each result carries its own.
+//
+// ServeTranspiler keeps one tsuki-core process for the whole batch;
+// ExecTranspiler forks one per file and is the fallback.
+type Transpiler interface {
+	// Transpile writes each request's OutputFile. Results are in request
+	// order; error diagnostics are rendered as they are found.
+	Transpile(reqs []TranspileRequest) ([]*TranspileResult, error)
+	// Check validates each request's InputFile without producing output.
+	// Nothing is rendered; the diagnostics are for the caller to report.
+	Check(reqs []TranspileRequest) ([]*TranspileResult, error)
+	// Version returns the core's version string.
+	Version() (string, error)
+	// Installed reports whether the core binary can be found.
+	Installed() bool
+	// Close releases the core process, if any.
+	Close() error
+}
+
+// New returns the preferred Transpiler: a `tsuki-core --serve` process,
+// falling back to one process per file for cores that cannot serve.
+func New(binary string, verbose bool) Transpiler {
+	return NewServe(binary, verbose)
+}
+
+// batchError summarises the failed results of a batch, or returns nil.
+func batchError(what string, results []*TranspileResult) error {
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, filepath.Base(r.InputFile))
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		for _, r := range results {
+			if r.Err != nil {
+				return r.Err
+			}
+		}
+	}
+	return fmt.Errorf("%s failed for %d files: %s", what, len(failed), strings.Join(failed, ", "))
+}
+
+// renderErrors renders the error diagnostics and reports whether any were.
+func renderErrors(diags []Diagnostic) bool {
+	rendered := false
+	for _, d := range diags {
+		if d.IsError() {
+			RenderDiagnostic(d)
+			rendered = true
+		}
+	}
+	return rendered
+}
+
+// warningsOf formats the warning diagnostics for display.
+func warningsOf(diags []Diagnostic) []string {
+	var out []string
+	for _, d := range diags {
+		if d.Severity == SeverityWarning {
+			out = append(out, d.String())
+		}
+	}
+	return out
+}
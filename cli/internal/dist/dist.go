@@ -0,0 +1,238 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: dist  —  package a compiled build into a distributable archive
+//
+//  Archive layout (one top-level directory, so unpacking never litters cwd):
+//
+//    <name>-<version>/
+//      <sketch>.hex | .bin        firmware image
+//      <sketch>.elf               debug symbols (when the backend produced it)
+//      <sketch>.buildinfo.json    provenance (board, commit, hash…)
+//      tsuki_package.json         manifest
+//      tsuki.lock                 exact package versions (when present)
+//      README.md                  upload instructions
+// ─────────────────────────────────────────────────────────────────────────────
+
+package dist
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the archive container.
+type Format string
+
+const (
+	FormatZip   Format = "zip"
+	FormatTarGz Format = "tar.gz"
+)
+
+// ParseFormat accepts "zip", "tar.gz" and "tgz".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "zip":
+		return FormatZip, nil
+	case "tar.gz", "tgz":
+		return FormatTarGz, nil
+	}
+	return "", fmt.Errorf("unknown archive format %q (use zip or tar.gz)", s)
+}
+
+// File is one entry of the archive. Exactly one of Path or Data is set.
+type File struct {
+	Name string // name inside the archive's top-level directory
+	Path string // copy from disk
+	Data []byte // or write these bytes
+}
+
+// ArchiveName returns "<name>-<version>.<ext>".
+func ArchiveName(name, version string, format Format) string {
+	return fmt.Sprintf("%s-%s.%s", name, version, format)
+}
+
+// Create writes files into a new archive at path, all under the root directory.
+func Create(path, root string, format Format, files []File) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatZip:
+		err = writeZip(f, root, files)
+	case FormatTarGz:
+		err = writeTarGz(f, root, files)
+	default:
+		err = fmt.Errorf("unknown archive format %q", format)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+func writeZip(w io.Writer, root string, files []File) error {
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		data, err := file.contents()
+		if err != nil {
+			return err
+		}
+		hdr := &zip.FileHeader{Name: root + "/" + file.Name, Method: zip.Deflate}
+		hdr.SetMode(0644)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeTarGz(w io.Writer, root string, files []File) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		data, err := file.contents()
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:     root + "/" + file.Name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (f File) contents() ([]byte, error) {
+	if f.Path == "" {
+		return f.Data, nil
+	}
+	return os.ReadFile(f.Path)
+}
+
+// Extract unpacks a .zip or .tar.gz archive into dest and returns the
+// directory holding the manifest (the archive's top-level directory).
+func Extract(archive, dest string) (string, error) {
+	lower := strings.ToLower(archive)
+	var err error
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		err = extractZip(archive, dest)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		err = extractTarGz(archive, dest)
+	default:
+		return "", fmt.Errorf("%s: not a .zip or .tar.gz archive", filepath.Base(archive))
+	}
+	if err != nil {
+		return "", fmt.Errorf("extracting %s: %w", filepath.Base(archive), err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, "tsuki_package.json")); err == nil {
+		return dest, nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(dest, "*", "tsuki_package.json"))
+	if len(matches) != 1 {
+		return "", fmt.Errorf("%s: no tsuki_package.json found — not a tsuki dist archive", filepath.Base(archive))
+	}
+	return filepath.Dir(matches[0]), nil
+}
+
+func extractZip(archive, dest string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeEntry(dest, f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTarGz(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := writeEntry(dest, hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// writeEntry copies r to dest/name, refusing names that escape dest.
+func writeEntry(dest, name string, r io.Reader) error {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("unsafe path %q in archive", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,143 @@
+package dist
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateExtract(t *testing.T) {
+	for _, format := range []Format{FormatZip, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			tmp := t.TempDir()
+			image := filepath.Join(tmp, "blink.hex")
+			if err := os.WriteFile(image, []byte(":00000001FF\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			archive := filepath.Join(tmp, "dist", ArchiveName("blink", "1.0.0", format))
+			err := Create(archive, "blink-1.0.0", format, []File{
+				{Name: "blink.hex", Path: image},
+				{Name: "tsuki_package.json", Data: []byte("{}")},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dest := filepath.Join(tmp, "out")
+			dir, err := Extract(archive, dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dest, "blink-1.0.0"); dir != want {
+				t.Errorf("Extract = %q, want %q", dir, want)
+			}
+			if data, err := os.ReadFile(filepath.Join(dir, "blink.hex")); err != nil || string(data) != ":00000001FF\n" {
+				t.Errorf("blink.hex = %q, %v", data, err)
+			}
+		})
+	}
+}
+
+// writeTestZip writes a zip with entries named names, each holding "x".
+func writeTestZip(t *testing.T, path string, names ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("x"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestTarGz is writeTestZip for a .tar.gz.
+func writeTestTarGz(t *testing.T, path string, names ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 1, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte("x"))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Entries that climb out of the destination are refused and nothing is
+// written outside it.
+func TestExtractRejectsZipSlip(t *testing.T) {
+	for _, name := range []string{"../evil", "blink-1.0.0/../../evil", "../../../../../../tmp/evil"} {
+		for ext, write := range map[string]func(*testing.T, string, ...string){
+			".zip":    writeTestZip,
+			".tar.gz": writeTestTarGz,
+		} {
+			t.Run(ext+" "+name, func(t *testing.T) {
+				tmp := t.TempDir()
+				archive := filepath.Join(tmp, "bad"+ext)
+				write(t, archive, "blink-1.0.0/tsuki_package.json", name)
+
+				dest := filepath.Join(tmp, "a", "out")
+				_, err := Extract(archive, dest)
+				if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+					t.Fatalf("err = %v, want an unsafe path", err)
+				}
+				for _, p := range []string{filepath.Join(tmp, "a", "evil"), filepath.Join(tmp, "evil"), "/tmp/evil"} {
+					if _, err := os.Stat(p); err == nil {
+						t.Errorf("%s written outside the destination", p)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExtractAbsoluteNameStaysInside(t *testing.T) {
+	tmp := t.TempDir()
+	archive := filepath.Join(tmp, "abs.zip")
+	writeTestZip(t, archive, "/blink-1.0.0/tsuki_package.json")
+
+	dest := filepath.Join(tmp, "out")
+	dir, err := Extract(archive, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dest, "blink-1.0.0"); dir != want {
+		t.Errorf("Extract = %q, want %q", dir, want)
+	}
+}
+
+func TestExtractNotADist(t *testing.T) {
+	tmp := t.TempDir()
+	archive := filepath.Join(tmp, "other.zip")
+	writeTestZip(t, archive, "readme.txt")
+	if _, err := Extract(archive, filepath.Join(tmp, "out")); err == nil || !strings.Contains(err.Error(), "not a tsuki dist archive") {
+		t.Errorf("err = %v, want not a dist archive", err)
+	}
+	if _, err := Extract(filepath.Join(tmp, "blink.rar"), filepath.Join(tmp, "out")); err == nil {
+		t.Error("Extract accepted a .rar")
+	}
+}
@@ -0,0 +1,77 @@
+package dist
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ReadmeInfo is what the generated README documents.
+type ReadmeInfo struct {
+	Name    string
+	Version string
+	Board   string
+	Backend string
+	Image   string // file name of the firmware image inside the archive
+	Archive string // file name of the archive itself
+
+	// BackendCmd is the exact argv the backend runs, with "<PORT>" as the
+	// port placeholder and the unpacked directory as the working directory;
+	// nil when the backend cannot upload outside a project (platformio).
+	BackendCmd []string
+	// DirectCmd flashes the image with the vendor uploader (avrdude,
+	// esptool) alone; nil when the board has no such mapping.
+	DirectCmd []string
+}
+
+// Readme renders README.md for a dist archive.
+func Readme(info ReadmeInfo) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s %s\n\n", info.Name, info.Version)
+	fmt.Fprintf(&b, "Prebuilt firmware for board `%s`, compiled with `%s`.\n", info.Board, info.Backend)
+	fmt.Fprintf(&b, "See `%s` for the source commit and image hash.\n\n",
+		strings.TrimSuffix(info.Image, imageExt(info.Image))+".buildinfo.json")
+
+	b.WriteString("## Upload with tsuki\n\n")
+	b.WriteString("```sh\n")
+	fmt.Fprintf(&b, "tsuki upload --from %s --port <PORT>\n", info.Archive)
+	b.WriteString("```\n")
+
+	if len(info.BackendCmd) > 0 {
+		b.WriteString("\n## Upload with the backend directly\n\n")
+		b.WriteString("Run from the unpacked directory:\n\n")
+		b.WriteString("```sh\n")
+		b.WriteString(shellJoin(info.BackendCmd) + "\n")
+		b.WriteString("```\n")
+	}
+
+	if len(info.DirectCmd) > 0 {
+		b.WriteString("\n## Upload without Arduino tooling\n\n")
+		b.WriteString("```sh\n")
+		b.WriteString(shellJoin(info.DirectCmd) + "\n")
+		b.WriteString("```\n")
+	}
+
+	b.WriteString("\nReplace `<PORT>` with the board's serial port (e.g. `/dev/ttyUSB0`, `COM3`).\n")
+	return b.Bytes()
+}
+
+func imageExt(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i:]
+	}
+	return ""
+}
+
+// shellJoin quotes arguments that a POSIX shell would split or expand.
+// The <PORT> placeholder is left bare so it reads as something to replace.
+func shellJoin(args []string) string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		if a != "<PORT>" && (a == "" || strings.ContainsAny(a, " \t'\"$`\\<>|&;*?")) {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		out[i] = a
+	}
+	return strings.Join(out, " ")
+}
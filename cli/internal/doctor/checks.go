@@ -0,0 +1,369 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/core"
+	"github.com/tsuki/cli/internal/pkgmgr"
+)
+
+// ── tsuki-core ────────────────────────────────────────────────────────────────
+
+func checkCore(env Env) *Check {
+	const name = "tsuki-core"
+
+	// A configured path that is wrong needs a different fix than a core
+	// that was never installed.
+	fix := "sudo make install-all   (in the tsuki checkout)"
+	if env.Config.CoreBinary != "" && env.Config.CoreBinary != "tsuki-core" {
+		fix = "tsuki config set core_binary /path/to/tsuki-core"
+	}
+
+	if !env.Transpiler.Installed() {
+		binary := env.Config.CoreBinary
+		if binary == "" {
+			binary = "tsuki-core"
+		}
+		return problem(name, Fail, fmt.Sprintf("%s not found on PATH", binary), fix, nil)
+	}
+
+	info, err := core.Detect(env.Config.CoreBinary)
+	if err != nil {
+		return problem(name, Fail, err.Error(), fix, nil)
+	}
+	if info.Legacy {
+		return problem(name, Warn,
+			fmt.Sprintf("tsuki-core %s predates --capabilities — JSON diagnostics and --serve are unavailable", info.Version),
+			"sudo make install-all   (in an up-to-date tsuki checkout)", nil)
+	}
+	return passed(name, fmt.Sprintf("tsuki-core %s  (%s)", info.Version, info.Path))
+}
+
+// ── Compile backend ───────────────────────────────────────────────────────────
+
+func checkBackend(env Env) []*Check {
+	switch name := env.backend(); name {
+	case "tsuki-flash", "tsuki-flash+cores":
+		return checkTsukiFlash(env)
+	case "arduino-cli":
+		return checkArduinoCLI(env)
+	case "platformio":
+		return checkPlatformIO(env)
+	default:
+		// An external template: all that can be checked is that it loads.
+		if _, err := backend.New(name, backend.Tools{ProjectDir: env.ProjectDir}); err != nil {
+			return []*Check{problem("backend", Fail, err.Error(), "tsuki config set backend arduino-cli", nil)}
+		}
+		return []*Check{passed("backend", name+" (external template)")}
+	}
+}
+
+func checkArduinoCLI(env Env) []*Check {
+	bin := env.Config.ArduinoCLI
+	if bin == "" {
+		bin = "arduino-cli"
+	}
+	if _, err := exec.LookPath(bin); err != nil {
+		return []*Check{problem("arduino-cli", Fail,
+			fmt.Sprintf("%s not found on PATH (backend: arduino-cli)", bin),
+			"make install-arduino   (in the tsuki checkout)", nil)}
+	}
+	checks := []*Check{passed("arduino-cli", bin)}
+
+	board := env.board()
+	platform, err := backend.Platform(board)
+	if err != nil {
+		return append(checks, problem("arduino-cli core", Warn,
+			fmt.Sprintf("unknown board %q — cannot tell which core it needs", board),
+			"tsuki boards list", nil))
+	}
+
+	out, err := exec.Command(bin, "core", "list").Output()
+	if err != nil {
+		return append(checks, problem("arduino-cli core", Fail,
+			fmt.Sprintf("%s core list failed: %v", bin, err),
+			bin+" core update-index", nil))
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == platform {
+			return append(checks, passed("arduino-cli core", fmt.Sprintf("%s %s  (board: %s)", platform, fields[1], board)))
+		}
+	}
+
+	return append(checks, problem("arduino-cli core", Fail,
+		fmt.Sprintf("core %s for board %q is not installed", platform, board),
+		fmt.Sprintf("%s core update-index && %s core install %s", bin, bin, platform),
+		func() error {
+			if out, err := exec.Command(bin, "core", "update-index").CombinedOutput(); err != nil {
+				return fmt.Errorf("%s core update-index: %v\n%s", bin, err, strings.TrimSpace(string(out)))
+			}
+			if out, err := exec.Command(bin, "core", "install", platform).CombinedOutput(); err != nil {
+				return fmt.Errorf("%s core install %s: %v\n%s", bin, platform, err, strings.TrimSpace(string(out)))
+			}
+			return nil
+		}))
+}
+
+func checkPlatformIO(env Env) []*Check {
+	bin := env.Config.PlatformIO
+	if bin == "" {
+		bin = "pio"
+	}
+	if _, err := exec.LookPath(bin); err != nil {
+		return []*Check{problem("platformio", Fail,
+			fmt.Sprintf("%s not found on PATH (backend: platformio)", bin),
+			"pip install -U platformio", nil)}
+	}
+	checks := []*Check{passed("platformio", bin)}
+
+	board := env.board()
+	platform, err := backend.PlatformIOPlatform(board)
+	if err != nil {
+		return append(checks, problem("platformio platform", Warn,
+			fmt.Sprintf("board %q has no PlatformIO mapping — cannot tell which platform it needs", board),
+			"tsuki boards list", nil))
+	}
+	if backend.PlatformIOInstalled(platform) {
+		return append(checks, passed("platformio platform", fmt.Sprintf("%s  (board: %s)", platform, board)))
+	}
+
+	return append(checks, problem("platformio platform", Warn,
+		fmt.Sprintf("platform %s for board %q is not installed yet — the next build installs it", platform, board),
+		fmt.Sprintf("%s pkg install --global --platform %s", bin, platform),
+		func() error {
+			if out, err := exec.Command(bin, "pkg", "install", "--global", "--platform", platform).CombinedOutput(); err != nil {
+				return fmt.Errorf("%s pkg install --platform %s: %v\n%s", bin, platform, err, strings.TrimSpace(string(out)))
+			}
+			return nil
+		}))
+}
+
+func checkTsukiFlash(env Env) []*Check {
+	name := env.backend()
+	bin := env.Config.FlashBinary
+	if bin == "" {
+		bin = "tsuki-flash"
+	}
+	if _, err := exec.LookPath(bin); err != nil {
+		return []*Check{problem("tsuki-flash", Fail,
+			fmt.Sprintf("%s not found on PATH (backend: %s)", bin, name),
+			"sudo make install-all   (in the tsuki checkout)", nil)}
+	}
+	checks := []*Check{passed("tsuki-flash", bin)}
+
+	board := env.board()
+	arch, err := backend.Arch(board)
+	if err != nil {
+		return append(checks, problem("tsuki-flash SDK", Warn,
+			fmt.Sprintf("unknown board %q — cannot tell which SDK it needs", board),
+			"tsuki boards list", nil))
+	}
+
+	// tsuki-flash+cores only ever reads the tsuki-modules store; plain
+	// tsuki-flash resolves the SDK as compile does (sdk-info): TSUKI_SDK_ROOT,
+	// then tsuki-modules, then .arduino15.
+	if name == "tsuki-flash+cores" {
+		if backend.ModulesInstalled(arch) {
+			return append(checks, passed("tsuki-flash SDK", fmt.Sprintf("%s core in %s  (board: %s)", arch, backend.ModulesRoot(), board)))
+		}
+	} else if err := exec.Command(bin, "sdk-info", board).Run(); err == nil {
+		return append(checks, passed("tsuki-flash SDK", fmt.Sprintf("%s SDK found  (board: %s)", arch, board)))
+	}
+
+	// Either way the next build installs the core, so a gap only costs a
+	// download.
+	return append(checks, problem("tsuki-flash SDK", Warn,
+		fmt.Sprintf("no %s SDK for board %q yet — the next build downloads it into %s", arch, board, backend.ModulesRoot()),
+		fmt.Sprintf("%s modules install %s", bin, arch),
+		func() error { return backend.InstallModules(bin, arch) }))
+}
+
+// ── Serial port permissions ───────────────────────────────────────────────────
+
+// checkSerial verifies the current user can open connected USB serial
+// devices. Only Linux gates them behind a group (dialout, or uucp on Arch).
+func checkSerial() []*Check {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	var ports []string
+	for _, pattern := range []string{"/dev/ttyUSB*", "/dev/ttyACM*"} {
+		matches, _ := filepath.Glob(pattern)
+		ports = append(ports, matches...)
+	}
+	if len(ports) == 0 {
+		return []*Check{passed("serial ports", "no USB serial devices connected")}
+	}
+
+	var checks []*Check
+	for _, port := range ports {
+		name := "serial " + port
+		group, ok := portAccess(port)
+		if ok {
+			checks = append(checks, passed(name, "readable and writable"))
+			continue
+		}
+		if group == "" {
+			group = "dialout"
+		}
+		detail := fmt.Sprintf("permission denied — %s is owned by group %s", port, group)
+		if inGroup(group) {
+			detail += " (you were added to it, log out and back in)"
+		}
+		checks = append(checks, problem(name, Fail, detail,
+			fmt.Sprintf("sudo usermod -aG %s $USER   (then log out and back in)", group), nil))
+	}
+	return checks
+}
+
+// inGroup reports whether the user's account lists group, whether or not
+// the current session has picked it up.
+func inGroup(group string) bool {
+	u, err := user.Current()
+	if err != nil {
+		return false
+	}
+	ids, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if g, err := user.LookupGroupId(id); err == nil && g.Name == group {
+			return true
+		}
+	}
+	return false
+}
+
+// ── Package store ─────────────────────────────────────────────────────────────
+
+func checkLibsDir(env Env) *Check {
+	const name = "libs dir"
+	dir := env.Config.ResolvedLibsDir()
+
+	st, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		return problem(name, Warn, fmt.Sprintf("%s does not exist yet", dir),
+			fmt.Sprintf("mkdir -p %s", dir),
+			func() error { return os.MkdirAll(dir, 0755) })
+	case err != nil:
+		return problem(name, Fail, err.Error(), "tsuki config set libs_dir /path/to/libs", nil)
+	case !st.IsDir():
+		return problem(name, Fail, fmt.Sprintf("%s is not a directory", dir),
+			"tsuki config set libs_dir /path/to/libs", nil)
+	}
+
+	probe, err := os.CreateTemp(dir, ".tsuki-doctor-*")
+	if err != nil {
+		return problem(name, Fail, fmt.Sprintf("%s is not writable", dir),
+			fmt.Sprintf("sudo chown -R $USER %s", dir), nil)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	pkgs, err := pkgmgr.ListInstalled()
+	if err != nil {
+		return problem(name, Fail, err.Error(), "tsuki config set libs_dir /path/to/libs", nil)
+	}
+	return passed(name, fmt.Sprintf("%s  (%d package(s))", dir, len(pkgs)))
+}
+
+// checkRegistries fetches every configured registry in parallel.
+func checkRegistries(env Env) []*Check {
+	urls := env.Config.ResolvedRegistryURLs()
+	counts := make([]int, len(urls))
+	errs := make([]error, len(urls))
+
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			counts[i], errs[i] = pkgmgr.CheckRegistry(u)
+		}(i, u)
+	}
+	wg.Wait()
+
+	var reachable []string
+	for i, u := range urls {
+		if errs[i] == nil {
+			reachable = append(reachable, u)
+		}
+	}
+
+	checks := make([]*Check, 0, len(urls))
+	for i, u := range urls {
+		if errs[i] == nil {
+			checks = append(checks, passed("registry", fmt.Sprintf("%s  (%d package(s))", u, counts[i])))
+			continue
+		}
+		detail := fmt.Sprintf("%s unreachable: %v", u, rootCause(errs[i]))
+		// One dead mirror is survivable; no registry at all is not.
+		if len(reachable) > 0 {
+			checks = append(checks, problem("registry", Warn, detail,
+				"tsuki config set registry_urls "+strings.Join(reachable, ","), nil))
+		} else {
+			checks = append(checks, problem("registry", Fail, detail,
+				"curl -I "+u+"   (check the network or proxy)", nil))
+		}
+	}
+	return checks
+}
+
+// rootCause strips the wrapping that repeats the URL three times.
+func rootCause(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
+
+// ── Project ───────────────────────────────────────────────────────────────────
+
+// checkPackages verifies every package the manifest declares is installed.
+func checkPackages(env Env) []*Check {
+	if env.Manifest == nil {
+		return nil
+	}
+	names := env.Manifest.PackageNames()
+	if len(names) == 0 {
+		return []*Check{passed("packages", "the manifest declares no packages")}
+	}
+
+	installed := map[string]string{}
+	pkgs, _ := pkgmgr.ListInstalled()
+	for _, p := range pkgs {
+		installed[p.Name] = p.Version
+	}
+
+	var checks []*Check
+	for _, pkgName := range names {
+		name := "package " + pkgName
+		if v, ok := installed[pkgName]; ok {
+			checks = append(checks, passed(name, "v"+v))
+			continue
+		}
+		pkgName := pkgName
+		checks = append(checks, problem(name, Fail, "declared in the manifest but not installed",
+			"tsuki pkg install "+pkgName,
+			func() error {
+				_, err := pkgmgr.InstallFromRegistry(pkgName, "")
+				return err
+			}))
+	}
+	return checks
+}
@@ -0,0 +1,122 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: doctor  —  diagnose the toolchain and environment
+//
+//  Every check inspects one thing a build or upload depends on and returns
+//  pass, warn or fail, plus the exact command that fixes a problem. Fixes
+//  that only create directories or download into tsuki's own stores are
+//  marked safe and can be applied with `tsuki doctor --fix`; anything that
+//  needs root or edits the user's system is left to the user.
+// ─────────────────────────────────────────────────────────────────────────────
+
+package doctor
+
+import (
+	"fmt"
+
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/config"
+	"github.com/tsuki/cli/internal/core"
+	"github.com/tsuki/cli/internal/manifest"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Check is the result of one diagnosis.
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+	// Fix is the command that resolves the problem; empty when passing.
+	Fix string `json:"fix,omitempty"`
+	// Fixable is set when `tsuki doctor --fix` can apply Fix itself.
+	Fixable bool `json:"fixable"`
+
+	apply func() error
+}
+
+// Apply runs the safe fix. It is an error to call it on a check that is
+// not Fixable.
+func (c *Check) Apply() error {
+	if c.apply == nil {
+		return fmt.Errorf("%s: no automatic fix — run: %s", c.Name, c.Fix)
+	}
+	return c.apply()
+}
+
+// Env is what the checks inspect.
+type Env struct {
+	Config *config.Config
+	// ProjectDir and Manifest are empty outside a project; the
+	// project-specific checks are then skipped.
+	ProjectDir string
+	Manifest   *manifest.Manifest
+	// Transpiler is used to look for tsuki-core.
+	Transpiler core.Transpiler
+}
+
+// board is the project's board, or the configured default.
+func (e Env) board() string {
+	if e.Manifest != nil && e.Manifest.Board != "" {
+		return e.Manifest.Board
+	}
+	if e.Config.DefaultBoard != "" {
+		return e.Config.DefaultBoard
+	}
+	return "uno"
+}
+
+// backend is the project's backend, or the configured default.
+func (e Env) backend() string {
+	return backend.Resolve("", e.Manifest, e.Config)
+}
+
+// Run performs every check, in the order a build needs them.
+func Run(env Env) []*Check {
+	var checks []*Check
+	checks = append(checks, checkCore(env))
+	checks = append(checks, checkBackend(env)...)
+	checks = append(checks, checkSerial()...)
+	checks = append(checks, checkLibsDir(env))
+	checks = append(checks, checkRegistries(env)...)
+	checks = append(checks, checkPackages(env)...)
+	return checks
+}
+
+// Summary counts the checks per status.
+func Summary(checks []*Check) (pass, warn, fail int) {
+	for _, c := range checks {
+		switch c.Status {
+		case Pass:
+			pass++
+		case Warn:
+			warn++
+		case Fail:
+			fail++
+		}
+	}
+	return
+}
+
+func passed(name, detail string) *Check {
+	return &Check{Name: name, Status: Pass, Detail: detail}
+}
+
+// problem builds a warn or fail check. apply may be nil when fix can only
+// be run by the user.
+func problem(name string, status Status, detail, fix string, apply func() error) *Check {
+	return &Check{
+		Name:    name,
+		Status:  status,
+		Detail:  detail,
+		Fix:     fix,
+		Fixable: apply != nil,
+		apply:   apply,
+	}
+}
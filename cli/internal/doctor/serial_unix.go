@@ -0,0 +1,26 @@
+//go:build unix
+
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"syscall"
+)
+
+// portAccess reports whether the user can read and write port and, when
+// not, the group that owns it ("" when it cannot be told).
+func portAccess(port string) (group string, ok bool) {
+	if syscall.Access(port, 0x6 /* R_OK|W_OK */) == nil {
+		return "", true
+	}
+	if st, err := os.Stat(port); err == nil {
+		if sys, ok := st.Sys().(*syscall.Stat_t); ok {
+			if g, err := user.LookupGroupId(fmt.Sprint(sys.Gid)); err == nil {
+				return g.Name, false
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,67 @@
+package firmware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BuildInfo is written next to every compiled image so a unit in the field
+// can be traced back to the exact source and toolchain that produced it.
+type BuildInfo struct {
+	Project   string    `json:"project"`
+	Version   string    `json:"version"`
+	Board     string    `json:"board"`
+	Backend   string    `json:"backend"`
+	Core      string    `json:"core,omitempty"` // board core, "id@version"
+	Profile   string    `json:"profile,omitempty"`
+	GitCommit string    `json:"git_commit,omitempty"`
+	GitDirty  bool      `json:"git_dirty,omitempty"`
+	BuiltAt   time.Time `json:"built_at"`
+
+	Image  string  `json:"image"` // file name, relative to the build-info file
+	Format Format  `json:"format"`
+	Size   int     `json:"size"`
+	Ranges []Range `json:"ranges"`
+	SHA256 string  `json:"sha256"`
+}
+
+// BuildInfoPath returns the build-info file that belongs to an image:
+// build/.cache/blink.hex → build/.cache/blink.buildinfo.json
+func BuildInfoPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".buildinfo.json"
+}
+
+// Describe fills the image-derived fields of info from img.
+func (info *BuildInfo) Describe(img *Image) {
+	info.Image = filepath.Base(img.Path)
+	info.Format = img.Format
+	info.Size = img.Size()
+	info.Ranges = img.Ranges()
+	info.SHA256 = img.SHA256()
+}
+
+// WriteBuildInfo saves info as indented JSON.
+func WriteBuildInfo(path string, info *BuildInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadBuildInfo loads a build-info file.
+func ReadBuildInfo(path string) (*BuildInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var info BuildInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+	}
+	return &info, nil
+}
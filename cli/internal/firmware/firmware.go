@@ -0,0 +1,264 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: firmware  —  parse and inspect compiled firmware images
+//
+//  Two formats are understood:
+//    Intel HEX (.hex / .ihex)  — AVR, SAM, Teensy
+//    raw binary (.bin)         — ESP32 / ESP8266 / RP2040
+//
+//  Every record of a HEX file is checksum-verified; a single bad byte makes
+//  the whole image invalid rather than silently flashing garbage.
+// ─────────────────────────────────────────────────────────────────────────────
+
+package firmware
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format identifies the on-disk encoding of a firmware image.
+type Format string
+
+const (
+	FormatIntelHex Format = "ihex"
+	FormatBinary   Format = "bin"
+)
+
+// Segment is a contiguous run of bytes at an absolute address.
+type Segment struct {
+	Address uint32
+	Data    []byte
+}
+
+// Range is a half-open address interval [Start, End).
+type Range struct {
+	Start uint32 `json:"start"`
+	End   uint32 `json:"end"`
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("0x%08X-0x%08X", r.Start, r.End)
+}
+
+// Image is a parsed firmware image.
+type Image struct {
+	Path     string
+	Format   Format
+	Segments []Segment // sorted by address, non-overlapping
+	// Entry is the start address from a type 03/05 record (HEX only).
+	Entry    uint32
+	HasEntry bool
+}
+
+// Load parses the firmware file at path, choosing the format by extension.
+func Load(path string) (*Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var img *Image
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".hex", ".ihex":
+		img, err = ParseHex(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	default:
+		img = ParseBin(data, 0)
+	}
+	img.Path = path
+	return img, nil
+}
+
+// ParseBin wraps a raw binary image loaded at base.
+func ParseBin(data []byte, base uint32) *Image {
+	img := &Image{Format: FormatBinary}
+	if len(data) > 0 {
+		img.Segments = []Segment{{Address: base, Data: data}}
+	}
+	return img
+}
+
+// ParseHex decodes an Intel HEX stream, validating every record checksum.
+func ParseHex(r io.Reader) (*Image, error) {
+	img := &Image{Format: FormatIntelHex}
+	var base uint32 // from type 02 / 04 records
+	sawEOF := false
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 1024), 1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if sawEOF {
+			return nil, fmt.Errorf("line %d: data after end-of-file record", lineNo)
+		}
+		if line[0] != ':' {
+			return nil, fmt.Errorf("line %d: record does not start with ':'", lineNo)
+		}
+		raw, err := hex.DecodeString(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid hex digits", lineNo)
+		}
+		if len(raw) < 5 || len(raw) != int(raw[0])+5 {
+			return nil, fmt.Errorf("line %d: record length mismatch", lineNo)
+		}
+		var sum byte
+		for _, b := range raw {
+			sum += b
+		}
+		if sum != 0 {
+			return nil, fmt.Errorf("line %d: checksum mismatch", lineNo)
+		}
+
+		count := int(raw[0])
+		offset := uint32(raw[1])<<8 | uint32(raw[2])
+		payload := raw[4 : 4+count]
+
+		switch raw[3] {
+		case 0x00: // data
+			img.addData(base+offset, payload)
+		case 0x01: // end of file
+			sawEOF = true
+		case 0x02: // extended segment address
+			if count != 2 {
+				return nil, fmt.Errorf("line %d: bad extended segment address record", lineNo)
+			}
+			base = (uint32(payload[0])<<8 | uint32(payload[1])) << 4
+		case 0x03: // start segment address (CS:IP)
+			if count != 4 {
+				return nil, fmt.Errorf("line %d: bad start segment address record", lineNo)
+			}
+			cs := uint32(payload[0])<<8 | uint32(payload[1])
+			ip := uint32(payload[2])<<8 | uint32(payload[3])
+			img.Entry, img.HasEntry = cs<<4+ip, true
+		case 0x04: // extended linear address
+			if count != 2 {
+				return nil, fmt.Errorf("line %d: bad extended linear address record", lineNo)
+			}
+			base = (uint32(payload[0])<<8 | uint32(payload[1])) << 16
+		case 0x05: // start linear address
+			if count != 4 {
+				return nil, fmt.Errorf("line %d: bad start linear address record", lineNo)
+			}
+			img.Entry = uint32(payload[0])<<24 | uint32(payload[1])<<16 | uint32(payload[2])<<8 | uint32(payload[3])
+			img.HasEntry = true
+		default:
+			return nil, fmt.Errorf("line %d: unknown record type 0x%02X", lineNo, raw[3])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !sawEOF {
+		return nil, fmt.Errorf("missing end-of-file record (truncated image?)")
+	}
+	return img, img.normalize()
+}
+
+// addData appends payload at addr, extending the last segment when contiguous.
+func (img *Image) addData(addr uint32, payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+	if n := len(img.Segments); n > 0 {
+		last := &img.Segments[n-1]
+		if last.Address+uint32(len(last.Data)) == addr {
+			last.Data = append(last.Data, payload...)
+			return
+		}
+	}
+	img.Segments = append(img.Segments, Segment{Address: addr, Data: append([]byte(nil), payload...)})
+}
+
+// normalize sorts segments, merges adjacent ones and rejects overlaps.
+func (img *Image) normalize() error {
+	sort.SliceStable(img.Segments, func(i, j int) bool {
+		return img.Segments[i].Address < img.Segments[j].Address
+	})
+	var merged []Segment
+	for _, s := range img.Segments {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			end := last.Address + uint32(len(last.Data))
+			if s.Address < end {
+				return fmt.Errorf("overlapping data at 0x%08X", s.Address)
+			}
+			if s.Address == end {
+				last.Data = append(last.Data, s.Data...)
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+	img.Segments = merged
+	return nil
+}
+
+// Ranges returns the address ranges covered by the image.
+func (img *Image) Ranges() []Range {
+	out := make([]Range, len(img.Segments))
+	for i, s := range img.Segments {
+		out[i] = Range{Start: s.Address, End: s.Address + uint32(len(s.Data))}
+	}
+	return out
+}
+
+// Size returns the number of bytes that will be programmed.
+func (img *Image) Size() int {
+	n := 0
+	for _, s := range img.Segments {
+		n += len(s.Data)
+	}
+	return n
+}
+
+// SHA256 hashes each segment's address, length and bytes in address order,
+// so the same bytes loaded elsewhere hash differently. Segments are merged
+// when contiguous, so the same firmware hashes identically whatever line
+// length or record layout the HEX uses.
+func (img *Image) SHA256() string {
+	h := sha256.New()
+	var hdr [8]byte
+	for _, s := range img.Segments {
+		binary.BigEndian.PutUint32(hdr[:4], s.Address)
+		binary.BigEndian.PutUint32(hdr[4:], uint32(len(s.Data)))
+		h.Write(hdr[:])
+		h.Write(s.Data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Find returns the application image in a build directory, preferring .hex
+// over .bin. Bootloader, partition-table and merged images produced next to
+// the application by arduino-cli are skipped.
+func Find(buildDir string) string {
+	for _, ext := range []string{".hex", ".bin"} {
+		matches, _ := filepath.Glob(filepath.Join(buildDir, "*"+ext))
+		sort.Strings(matches)
+		for _, m := range matches {
+			base := strings.ToLower(filepath.Base(m))
+			if strings.Contains(base, "bootloader") ||
+				strings.Contains(base, "partitions") ||
+				strings.Contains(base, "merged") {
+				continue
+			}
+			return m
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package firmware
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// record encodes one Intel HEX record with a valid checksum.
+func record(typ byte, offset uint16, data ...byte) string {
+	raw := append([]byte{byte(len(data)), byte(offset >> 8), byte(offset), typ}, data...)
+	var sum byte
+	for _, b := range raw {
+		sum += b
+	}
+	raw = append(raw, -sum)
+	return fmt.Sprintf(":%X", raw)
+}
+
+const eof = ":00000001FF"
+
+func parse(t *testing.T, lines ...string) (*Image, error) {
+	t.Helper()
+	return ParseHex(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+}
+
+func TestParseHex(t *testing.T) {
+	img, err := parse(t,
+		record(0x00, 0x0000, 1, 2, 3, 4),
+		record(0x00, 0x0004, 5, 6), // contiguous: merged into the first segment
+		record(0x00, 0x0100, 7),
+		eof,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Segment{
+		{Address: 0x0000, Data: []byte{1, 2, 3, 4, 5, 6}},
+		{Address: 0x0100, Data: []byte{7}},
+	}
+	if !reflect.DeepEqual(img.Segments, want) {
+		t.Errorf("segments = %+v, want %+v", img.Segments, want)
+	}
+	if want := []Range{{0, 6}, {0x100, 0x101}}; !reflect.DeepEqual(img.Ranges(), want) {
+		t.Errorf("ranges = %v, want %v", img.Ranges(), want)
+	}
+	if img.Size() != 7 || img.Format != FormatIntelHex {
+		t.Errorf("size/format = %d/%s, want 7/ihex", img.Size(), img.Format)
+	}
+}
+
+func TestParseHexExtendedAddresses(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []Range
+	}{
+		{"linear", []string{
+			record(0x04, 0, 0x08, 0x00), // 0x0800_0000
+			record(0x00, 0x0010, 0xAA, 0xBB),
+			record(0x05, 0, 0x08, 0x00, 0x01, 0x00),
+			eof,
+		}, []Range{{0x08000010, 0x08000012}}},
+		{"segment", []string{
+			record(0x02, 0, 0x10, 0x00), // 0x1000 << 4
+			record(0x00, 0x0020, 0xCC),
+			record(0x03, 0, 0x00, 0x00, 0x00, 0x00),
+			eof,
+		}, []Range{{0x00010020, 0x00010021}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := parse(t, tt.lines...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(img.Ranges(), tt.want) {
+				t.Errorf("ranges = %v, want %v", img.Ranges(), tt.want)
+			}
+			if !img.HasEntry {
+				t.Error("start address record not read")
+			}
+		})
+	}
+	img, _ := parse(t, record(0x05, 0, 0x08, 0x00, 0x01, 0x00), eof)
+	if img.Entry != 0x08000100 {
+		t.Errorf("Entry = 0x%08X, want 0x08000100", img.Entry)
+	}
+}
+
+func TestParseHexErrors(t *testing.T) {
+	bad := record(0x00, 0, 1, 2)
+	bad = bad[:len(bad)-2] + "00" // corrupt the checksum
+	tests := []struct {
+		name  string
+		lines []string
+		err   string
+	}{
+		{"bad checksum", []string{bad, eof}, "line 1: checksum mismatch"},
+		{"missing EOF", []string{record(0x00, 0, 1, 2)}, "missing end-of-file record"},
+		{"unknown record type", []string{record(0x06, 0, 1), eof}, "line 1: unknown record type 0x06"},
+		{"data after EOF", []string{eof, record(0x00, 0, 1)}, "line 2: data after end-of-file record"},
+		{"length mismatch", []string{":0400000001FB", eof}, "line 1: record length mismatch"},
+		{"overlap", []string{record(0x00, 0, 1, 2), record(0x00, 1, 3), eof}, "overlapping data at 0x00000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, tt.lines...)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadBin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blink.bin")
+	if err := os.WriteFile(path, []byte{0xE9, 1, 2, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	img, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != FormatBinary || img.Path != path {
+		t.Errorf("format/path = %s/%s, want bin/%s", img.Format, img.Path, path)
+	}
+	if want := []Range{{0, 4}}; !reflect.DeepEqual(img.Ranges(), want) {
+		t.Errorf("ranges = %v, want %v", img.Ranges(), want)
+	}
+	if empty := ParseBin(nil, 0x1000); len(empty.Segments) != 0 {
+		t.Errorf("empty image has segments %+v", empty.Segments)
+	}
+}
+
+func TestSHA256(t *testing.T) {
+	// The same bytes in other records hash the same...
+	a, err := parse(t, record(0x00, 0, 1, 2, 3, 4), eof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parse(t, record(0x00, 0, 1, 2), record(0x00, 2, 3, 4), eof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.SHA256() != b.SHA256() {
+		t.Error("record layout changed the SHA-256")
+	}
+	// ...but not at another address, nor split into two segments.
+	moved, err := parse(t, record(0x00, 0x10, 1, 2, 3, 4), eof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.SHA256() == a.SHA256() {
+		t.Error("moving the segment kept the SHA-256")
+	}
+	split := &Image{Segments: []Segment{{0, []byte{1, 2}}, {0x10, []byte{3, 4}}}}
+	joined := &Image{Segments: []Segment{{0, []byte{1, 2, 3, 4}}}}
+	if split.SHA256() == joined.SHA256() {
+		t.Error("segment boundaries do not affect the SHA-256")
+	}
+	if ParseBin([]byte{1, 2, 3, 4}, 0).SHA256() == ParseBin([]byte{1, 2, 3, 4}, 0x1000).SHA256() {
+		t.Error("the load address of a BIN does not affect the SHA-256")
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"blink.ino.bootloader.bin", "blink.ino.partitions.bin", "blink.ino.bin"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := Find(dir), filepath.Join(dir, "blink.ino.bin"); got != want {
+		t.Errorf("Find = %q, want %q", got, want)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "blink.ino.hex"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Find(dir), filepath.Join(dir, "blink.ino.hex"); got != want {
+		t.Errorf("Find = %q, want the .hex %q", got, want)
+	}
+}
@@ -5,12 +5,18 @@
 package flash
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/tsuki/cli/internal/backend"
+	"github.com/tsuki/cli/internal/firmware"
 	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/toolchain"
 	"github.com/tsuki/cli/internal/ui"
 )
 
@@ -21,25 +27,33 @@ type Options struct {
 	BuildDir    string // directory with compiled firmware (.hex)
 	ArduinoCLI  string
 	FlashBinary string // path to tsuki-flash binary
-	Backend     string // "tsuki-flash" or "arduino-cli"
+	PlatformIO  string // path to pio
+	Backend     string // backend name, see backend.New
 	Verbose     bool
+	// Baud overrides the board's upload speed; 0 keeps the board's.
+	Baud int
+
+	// Retries is the number of extra attempts after a failed upload.
+	// Only failures that look transient (port busy, sync timeout) are retried.
+	Retries int
+	// RetryDelay is the wait before the first retry; it doubles each attempt.
+	RetryDelay time.Duration
+	// Verify reads the flash back after uploading and compares it with the image.
+	Verify bool
 }
 
-// boardFQBN maps short board IDs to FQBNs.
-var boardFQBN = map[string]string{
-	"uno":      "arduino:avr:uno",
-	"nano":     "arduino:avr:nano",
-	"mega":     "arduino:avr:mega",
-	"leonardo": "arduino:avr:leonardo",
-	"micro":    "arduino:avr:micro",
-	"due":      "arduino:sam:arduino_due_x",
-	"esp32":    "esp32:esp32:esp32",
-	"esp8266":  "esp8266:esp8266:generic",
-	"pico":     "rp2040:rp2040:rpipico",
+// Result describes a finished upload.
+type Result struct {
+	Board    string `json:"board"`
+	Port     string `json:"port"`
+	Backend  string `json:"backend"`
+	BuildDir string `json:"build_dir"`
+	Attempts int    `json:"attempts"`
+	Verified bool   `json:"verified"`
 }
 
 // Run uploads the firmware to the board.
-func Run(projectDir string, m *manifest.Manifest, opts Options) error {
+func Run(projectDir string, m *manifest.Manifest, opts Options) (*Result, error) {
 	board := opts.Board
 	if board == "" {
 		board = m.Board
@@ -51,123 +65,310 @@ func Run(projectDir string, m *manifest.Manifest, opts Options) error {
 		buildDir = filepath.Join(projectDir, m.Build.OutputDir, ".cache")
 	}
 
-	backend := opts.Backend
-	if backend == "" {
-		backend = "arduino-cli"
+	if err := applyPinnedTools(m, &opts); err != nil {
+		return nil, err
+	}
+
+	b, err := backend.New(opts.Backend, backend.Tools{
+		ArduinoCLI:  opts.ArduinoCLI,
+		FlashBinary: opts.FlashBinary,
+		PlatformIO:  opts.PlatformIO,
+		ProjectDir:  projectDir,
+		Verbose:     opts.Verbose,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	switch backend {
-	case "tsuki-flash":
-		return uploadTsukiFlash(board, buildDir, opts)
-	default:
-		return uploadArduinoCLI(board, buildDir, opts)
+	res := &Result{Board: board, Port: opts.Port, Backend: b.Name(), BuildDir: buildDir}
+	if err := upload(res, b, opts); err != nil {
+		return nil, err
 	}
+	return res, nil
 }
 
-// ─────────────────────────────────────────────────────────────────────────────
-//  Backend: tsuki-flash upload
-// ─────────────────────────────────────────────────────────────────────────────
+// applyPinnedTools makes the upload use the tools the firmware was built
+// with. Only those the backend runs to upload must be installed: a machine
+// that only flashes, e.g. from a dist archive, has no use for tsuki-core.
+func applyPinnedTools(m *manifest.Manifest, opts *Options) error {
+	name := opts.Backend
+	if name == "" {
+		name = backend.Default
+	}
+	var tools []string
+	switch name {
+	case "arduino-cli":
+		tools = []string{toolchain.ArduinoCLI}
+	case "tsuki-flash", "tsuki-flash+cores":
+		tools = []string{toolchain.TsukiFlash}
+	}
+	pinned, err := toolchain.ResolveTools(m.Toolchain, tools...)
+	if err != nil {
+		return err
+	}
+	pinned.Apply(nil, &opts.FlashBinary, &opts.ArduinoCLI)
+	return nil
+}
 
-func uploadTsukiFlash(board, buildDir string, opts Options) error {
-	flashBin := opts.FlashBinary
-	if flashBin == "" {
-		flashBin = "tsuki-flash"
+func upload(res *Result, b backend.Backend, opts Options) error {
+	// The uploader (avrdude, bossac, esptool…) can ship with the core, so a
+	// board flashed before it was ever built gets its core here.
+	if err := backend.EnsureCore(b, res.Board); err != nil {
+		return err
 	}
 
-	port := opts.Port
-	if port == "" {
+	if res.Port == "" {
 		ui.Info("Auto-detecting board on serial ports...")
-		detected, err := detectPortTsukiFlash(flashBin)
+		port, err := detectPort(b)
 		if err != nil {
 			return fmt.Errorf(
 				"no board detected: %w\n  Hint: connect the board and try again, or pass --port /dev/ttyUSBx", err,
 			)
 		}
-		port = detected
+		res.Port = port
 		ui.Success(fmt.Sprintf("Found board on %s", port))
 	}
 
-	args := []string{
-		"upload",
-		"--board", board,
-		"--port", port,
-		"--build-dir", buildDir,
+	req := backend.UploadRequest{
+		Board:    res.Board,
+		Port:     res.Port,
+		BuildDir: res.BuildDir,
+		Name:     imageName(res.BuildDir),
+		Baud:     opts.Baud,
+		Verify:   opts.Verify,
+		Verbose:  opts.Verbose,
 	}
-	if opts.Verbose {
-		args = append(args, "--verbose")
+	if len(backend.Ignored(b, req.Settings())) > 0 {
+		ui.Warn(fmt.Sprintf("backend %s ignores --baud %d — uploading at the board's default speed", b.Name(), req.Baud))
 	}
 
-	ui.SectionTitle(fmt.Sprintf("Uploading to %s  [board: %s]  [tsuki-flash]", port, board))
-	sp := ui.NewSpinner("Flashing firmware...")
-	sp.Start()
+	ui.SectionTitle(fmt.Sprintf("Uploading to %s  [board: %s]  [%s]", res.Port, res.Board, b.Name()))
+	if err := uploadWithRetries(res, opts, func() ([]byte, error) {
+		return b.Upload(req)
+	}); err != nil {
+		return err
+	}
 
-	cmd := exec.Command(flashBin, args...)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		sp.Stop(false, "upload failed")
-		renderFlashError(string(out), port)
-		return fmt.Errorf("upload failed")
+	if !opts.Verify {
+		return nil
 	}
+	// arduino-cli fails the upload itself when --verify finds a mismatch;
+	// for the others, read the flash back ourselves.
+	if v, ok := b.(backend.Verifier); ok && v.VerifiesUpload() {
+		res.Verified = true
+		return nil
+	}
+	verified, err := verifyUpload(res.Board, res.Port, res.BuildDir)
+	res.Verified = verified
+	return err
+}
 
-	sp.Stop(true, fmt.Sprintf("firmware uploaded to %s", port))
-	return nil
+// detectPort returns the first port the backend finds a board on.
+func detectPort(b backend.Backend) (string, error) {
+	ports, err := b.DetectPorts()
+	if err != nil {
+		return "", err
+	}
+	if len(ports) == 0 {
+		return "", fmt.Errorf("no board found on any serial port")
+	}
+	return ports[0].Address, nil
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
-//  Backend: arduino-cli upload
+//  Command lines
 // ─────────────────────────────────────────────────────────────────────────────
 
-func uploadArduinoCLI(board, buildDir string, opts Options) error {
-	fqbn, ok := boardFQBN[strings.ToLower(board)]
+// CommandLine returns the argv the selected backend runs to upload the
+// firmware image in buildDir to port, or nil when the backend has none that
+// works outside a project. Used to document uploads for machines without
+// tsuki installed; projectDir is where external templates are found.
+func CommandLine(projectDir, board, port, buildDir, image string, opts Options) ([]string, error) {
+	b, err := backend.New(opts.Backend, backend.Tools{
+		ArduinoCLI:  opts.ArduinoCLI,
+		FlashBinary: opts.FlashBinary,
+		PlatformIO:  opts.PlatformIO,
+		ProjectDir:  projectDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c, ok := b.(backend.UploadCommander)
 	if !ok {
-		return fmt.Errorf("unknown board %q — run `tsuki boards list` for the full list", board)
+		return nil, nil
 	}
+	return c.UploadCommand(backend.UploadRequest{
+		Board: board, Port: port, BuildDir: buildDir, Name: baseName(image), Baud: opts.Baud, Verbose: opts.Verbose,
+	})
+}
 
-	port := opts.Port
-	if port == "" {
-		ui.Info("Auto-detecting board on serial ports...")
-		detected, err := detectPortArduinoCLI(opts.ArduinoCLI)
-		if err != nil {
-			return fmt.Errorf(
-				"no board detected: %w\n  Hint: connect the board and try again, or pass --port /dev/ttyUSBx", err,
-			)
-		}
-		port = detected
-		ui.Success(fmt.Sprintf("Found board on %s", port))
-	}
+// imageName is the base name of the firmware in buildDir — the sketch's
+// name, "blink" for blink.hex — or "" when there is none.
+func imageName(buildDir string) string {
+	return baseName(firmware.Find(buildDir))
+}
 
-	arduinoCLI := opts.ArduinoCLI
-	if arduinoCLI == "" {
-		arduinoCLI = "arduino-cli"
+func baseName(image string) string {
+	if image == "" {
+		return ""
 	}
+	return strings.TrimSuffix(filepath.Base(image), filepath.Ext(image))
+}
 
-	args := []string{
-		"upload",
-		"--fqbn", fqbn,
-		"--port", port,
-		"--input-dir", buildDir,
+// DirectCommandLine returns the avrdude/esptool invocation that writes image
+// without any Arduino tooling, or nil when the board has no such mapping.
+func DirectCommandLine(board, port, image string) []string {
+	board = strings.ToLower(board)
+	if t, ok := avrTargets[board]; ok {
+		return []string{
+			"avrdude", "-p", t.mcu, "-c", t.programmer, "-P", port,
+			"-b", strconv.Itoa(t.baud), "-D", "-U", fmt.Sprintf("flash:w:%s:i", image),
+		}
 	}
-	if opts.Verbose {
-		args = append(args, "--verbose")
+	if chip, ok := espChips[board]; ok {
+		return []string{"esptool.py", "--chip", chip, "--port", port, "write_flash", "0x1000", image}
 	}
+	return nil
+}
 
-	ui.SectionTitle(fmt.Sprintf("Uploading to %s  [%s]", port, fqbn))
-	sp := ui.NewSpinner("Flashing firmware...")
-	sp.Start()
+// ─────────────────────────────────────────────────────────────────────────────
+//  Retries
+// ─────────────────────────────────────────────────────────────────────────────
 
-	cmd := exec.Command(arduinoCLI, args...)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		sp.Stop(false, "upload failed")
-		renderFlashError(string(out), port)
-		return fmt.Errorf("upload failed")
+// uploadWithRetries runs attempt until it succeeds or opts.Retries is used up.
+// Between attempts the port is kicked back into the bootloader: a 1200-baud
+// touch for boards with native USB, a DTR reset for everything else.
+func uploadWithRetries(res *Result, opts Options, attempt func() ([]byte, error)) error {
+	board, port := res.Board, res.Port
+	total := opts.Retries + 1
+	delay := opts.RetryDelay
+
+	for n := 1; ; n++ {
+		res.Attempts = n
+		label := "Flashing firmware..."
+		if n > 1 {
+			label = fmt.Sprintf("Flashing firmware (attempt %d/%d)...", n, total)
+		}
+		sp := ui.NewSpinner(label)
+		sp.Start()
+
+		out, err := attempt()
+		if err == nil {
+			sp.Stop(true, fmt.Sprintf("firmware uploaded to %s", port))
+			return nil
+		}
+		// A missing uploader will still be missing on the next attempt.
+		if errors.Is(err, exec.ErrNotFound) {
+			sp.Stop(false, "upload failed")
+			return fmt.Errorf("upload failed: %w", err)
+		}
+
+		failure := classifyFlashError(string(out), port)
+		if n >= total || !failure.Retryable {
+			sp.Stop(false, "upload failed")
+			renderFlashError(string(out), port, board)
+			if failure.Kind != "" {
+				return fmt.Errorf("upload failed: %s", failure.Summary)
+			}
+			return fmt.Errorf("upload failed")
+		}
+
+		sp.Stop(false, fmt.Sprintf("attempt %d/%d failed (%s) — retrying in %s", n, total, failure.Summary, delay))
+		if err := recoverPort(board, port); err != nil && opts.Verbose {
+			ui.Warn(fmt.Sprintf("could not reset %s: %v", port, err))
+		}
+		time.Sleep(delay)
+		delay *= 2
 	}
+}
 
-	sp.Stop(true, fmt.Sprintf("firmware uploaded to %s", port))
-	return nil
+// ─────────────────────────────────────────────────────────────────────────────
+//  Error classification
+// ─────────────────────────────────────────────────────────────────────────────
+
+// flashFailure is a recognised class of upload error.
+type flashFailure struct {
+	Kind      string // traceback error type, e.g. "PortBusyError"
+	Summary   string // short description used in the returned error
+	Hint      string // %[1]s = port, %[2]s = board
+	Retryable bool
+	patterns  []string // %[1]s = port
 }
 
-func renderFlashError(output, port string) {
+// flashFailures is checked in order; the first matching pattern wins.
+// Patterns are matched case-insensitively against the uploader output.
+// Port-not-found patterns must name the port: a bare "no such file or
+// directory" is also what a missing uploader binary produces.
+var flashFailures = []flashFailure{
+	{
+		Kind:    "PermissionDeniedError",
+		Summary: "permission denied",
+		Hint: "Your user cannot open %[1]s. Add it to the serial group and log in again:\n" +
+			"  sudo usermod -aG dialout $USER",
+		patterns: []string{"permission denied", "access is denied", "eacces"},
+	},
+	{
+		Kind:    "PortBusyError",
+		Summary: "port busy",
+		Hint: "%[1]s is in use by another program. Close any serial monitor " +
+			"(tsuki, Arduino IDE, screen, minicom) and try again.",
+		Retryable: true,
+		patterns:  []string{"resource busy", "device or resource busy", "port is busy", "ebusy"},
+	},
+	{
+		Kind:    "WrongBoardError",
+		Summary: "wrong board",
+		Hint: "The chip on %[1]s does not match board %[2]q. Check the board in " +
+			"tsuki_package.json or pass --board.",
+		patterns: []string{
+			"expected signature", "invalid device signature", "device signature =",
+			"wrong chip", "this chip is", "unexpected chip id",
+		},
+	},
+	{
+		Kind:    "SyncTimeoutError",
+		Summary: "bootloader did not respond",
+		Hint: "The bootloader on %[1]s did not answer. Check the USB cable, press " +
+			"reset just before uploading, and make sure board %[2]q is correct.",
+		Retryable: true,
+		patterns: []string{
+			"not in sync", "programmer is not responding", "stk500_getsync",
+			"stk500v2_getsync", "timed out waiting for packet", "failed to connect to esp",
+			"no response from", "butterfly_recv",
+		},
+	},
+	{
+		Kind:    "PortNotFoundError",
+		Summary: "port not found",
+		Hint:    "%[1]s does not exist. Reconnect the board or run `tsuki boards detect`.",
+		// Native-USB boards re-enumerate after a reset; give them a second chance.
+		Retryable: true,
+		patterns:  []string{"%[1]s: no such file", "could not open port", "can't open device", "cannot open port"},
+	},
+}
+
+// classifyFlashError returns the first failure class whose pattern appears
+// in the output of an upload to port, or a zero flashFailure when nothing
+// matches.
+func classifyFlashError(output, port string) flashFailure {
+	lower := strings.ToLower(output)
+	for _, f := range flashFailures {
+		for _, p := range f.patterns {
+			if strings.Contains(p, "%[1]s") {
+				if port == "" {
+					continue
+				}
+				p = fmt.Sprintf(p, strings.ToLower(port))
+			}
+			if strings.Contains(lower, p) {
+				return f
+			}
+		}
+	}
+	return flashFailure{}
+}
+
+func renderFlashError(output, port, board string) {
 	lines := strings.Split(output, "\n")
 	var relevant []string
 	for _, l := range lines {
@@ -180,51 +381,16 @@ func renderFlashError(output, port string) {
 	if msg == "" {
 		msg = strings.TrimSpace(output)
 	}
-	ui.Traceback("FlashError", msg, []ui.Frame{
-		{
-			File: port,
-			Func: "upload",
-			Line: 0,
-			Code: []ui.CodeLine{{Number: 0, Text: msg, IsPointer: true}},
-		},
-	})
-}
 
-// detectPortTsukiFlash uses `tsuki-flash detect` to find the board port.
-func detectPortTsukiFlash(flashBin string) (string, error) {
-	out, err := exec.Command(flashBin, "detect").Output()
-	if err != nil {
-		return "", fmt.Errorf("tsuki-flash detect failed: %w", err)
-	}
-	for _, line := range strings.Split(string(out), "\n") {
-		fields := strings.Fields(line)
-		if len(fields) >= 1 {
-			port := fields[0]
-			if strings.HasPrefix(port, "/dev/") || strings.HasPrefix(port, "COM") {
-				return port, nil
-			}
-		}
+	errType := "FlashError"
+	failure := classifyFlashError(output, port)
+	if failure.Kind != "" {
+		errType = failure.Kind
 	}
-	return "", fmt.Errorf("no board found on any serial port")
-}
 
-// detectPortArduinoCLI uses `arduino-cli board list` to find the board port.
-func detectPortArduinoCLI(arduinoCLI string) (string, error) {
-	if arduinoCLI == "" {
-		arduinoCLI = "arduino-cli"
+	// The uploader's output has no source location to show a frame for.
+	ui.Traceback(errType, msg, nil)
+	if failure.Hint != "" {
+		ui.Info("Hint: " + fmt.Sprintf(failure.Hint, port, board))
 	}
-	out, err := exec.Command(arduinoCLI, "board", "list").Output()
-	if err != nil {
-		return "", fmt.Errorf("arduino-cli board list failed: %w", err)
-	}
-	for _, line := range strings.Split(string(out), "\n") {
-		fields := strings.Fields(line)
-		if len(fields) >= 1 {
-			port := fields[0]
-			if strings.HasPrefix(port, "/dev/") || strings.HasPrefix(port, "COM") {
-				return port, nil
-			}
-		}
-	}
-	return "", fmt.Errorf("no board found on any serial port")
-}
\ No newline at end of file
+}
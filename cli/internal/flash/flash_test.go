@@ -0,0 +1,67 @@
+package flash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tsuki/cli/internal/manifest"
+	"github.com/tsuki/cli/internal/toolchain"
+)
+
+// installTool puts an empty binary for version of tool in the store.
+func installTool(t *testing.T, tool, version string) string {
+	t.Helper()
+	path, err := toolchain.Path(tool, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// An upload never runs tsuki-core, so a pinned but missing tsuki-core does
+// not stop it — as on a machine flashing a dist archive.
+func TestApplyPinnedToolsSkipsCore(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m := manifest.Default("blink", "uno")
+	m.Toolchain = &manifest.Toolchain{TsukiCore: "3.1.0"}
+
+	for _, b := range []string{"", "arduino-cli", "tsuki-flash", "tsuki-flash+cores", "platformio"} {
+		opts := Options{Backend: b, ArduinoCLI: "arduino-cli", FlashBinary: "tsuki-flash"}
+		if err := applyPinnedTools(m, &opts); err != nil {
+			t.Errorf("backend %q: %v", b, err)
+		}
+		if opts.ArduinoCLI != "arduino-cli" || opts.FlashBinary != "tsuki-flash" {
+			t.Errorf("backend %q: configured tools replaced: %+v", b, opts)
+		}
+	}
+}
+
+func TestApplyPinnedToolsUploader(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m := manifest.Default("blink", "uno")
+	m.Toolchain = &manifest.Toolchain{TsukiCore: "3.1.0", TsukiFlash: "0.6.2", ArduinoCLI: "1.0.4"}
+	flashBin := installTool(t, toolchain.TsukiFlash, "0.6.2")
+
+	// tsuki-flash is installed; tsuki-core and arduino-cli are not needed.
+	opts := Options{Backend: "tsuki-flash", FlashBinary: "tsuki-flash"}
+	if err := applyPinnedTools(m, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.FlashBinary != flashBin {
+		t.Errorf("FlashBinary = %q, want the pinned %q", opts.FlashBinary, flashBin)
+	}
+
+	// The arduino-cli backend uploads with the pinned arduino-cli, which is missing.
+	err := applyPinnedTools(m, &Options{Backend: "arduino-cli"})
+	if err == nil || !strings.Contains(err.Error(), "arduino-cli@1.0.4") || strings.Contains(err.Error(), "tsuki-core") {
+		t.Errorf("err = %v, want only arduino-cli@1.0.4 missing", err)
+	}
+}
@@ -0,0 +1,85 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: flash :: serial  —  kick a board back into its bootloader
+//
+//  Used between upload retries. Boards with native USB (ATmega32u4) enter
+//  the bootloader when the host opens the port at 1200 baud and closes it;
+//  boards behind a USB-serial adapter reset when DTR is pulsed.
+// ─────────────────────────────────────────────────────────────────────────────
+
+//go:build linux
+
+package flash
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// nativeUSBBoards enter the bootloader on a 1200-baud touch.
+var nativeUSBBoards = map[string]bool{
+	"leonardo": true,
+	"micro":    true,
+}
+
+// cbaud masks the baud-rate bits of termios.Cflag (not exported by syscall).
+const cbaud = 0o10017
+
+// recoverPort resets the board on port so the next upload attempt finds
+// the bootloader listening.
+func recoverPort(board, port string) error {
+	if nativeUSBBoards[strings.ToLower(board)] {
+		if err := touchPort(port); err != nil {
+			return err
+		}
+		// The board re-enumerates; give the bootloader time to come up.
+		time.Sleep(800 * time.Millisecond)
+		return nil
+	}
+	return resetPort(port)
+}
+
+// touchPort opens port at 1200 baud and closes it again.
+func touchPort(port string) error {
+	f, err := os.OpenFile(port, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var t syscall.Termios
+	if err := ioctl(f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		return err
+	}
+	t.Cflag = t.Cflag&^cbaud | syscall.B1200
+	t.Ispeed = syscall.B1200
+	t.Ospeed = syscall.B1200
+	return ioctl(f.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&t)))
+}
+
+// resetPort pulses DTR low for 100 ms — the auto-reset circuit on Uno/Nano
+// style boards turns that edge into a reset pulse.
+func resetPort(port string) error {
+	f, err := os.OpenFile(port, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dtr := syscall.TIOCM_DTR
+	if err := ioctl(f.Fd(), syscall.TIOCMBIC, uintptr(unsafe.Pointer(&dtr))); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return ioctl(f.Fd(), syscall.TIOCMBIS, uintptr(unsafe.Pointer(&dtr)))
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: flash :: serial  —  no port reset outside Linux
+//
+//  The termios and modem-control ioctls serial_linux.go uses are Linux
+//  ones. Elsewhere upload retries go straight to the next attempt.
+// ─────────────────────────────────────────────────────────────────────────────
+
+//go:build !linux
+
+package flash
+
+// recoverPort is a no-op: the board is not reset between attempts.
+func recoverPort(board, port string) error {
+	return nil
+}
@@ -0,0 +1,117 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: flash :: verify  —  read the flash back after a tsuki-flash upload
+//
+//  arduino-cli has its own --verify switch; tsuki-flash does not, so we run
+//  the uploader's read-and-compare mode ourselves:
+//    AVR  →  avrdude -U flash:v:<image>.hex:i
+//    ESP  →  esptool verify_flash <offset> <image>.bin
+// ─────────────────────────────────────────────────────────────────────────────
+
+package flash
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tsuki/cli/internal/firmware"
+	"github.com/tsuki/cli/internal/ui"
+)
+
+// avrTarget holds the avrdude parameters tsuki-flash uses for a board.
+type avrTarget struct {
+	mcu        string
+	programmer string
+	baud       int
+}
+
+// avrTargets mirrors the AVR entries of tsuki-flash's board table.
+var avrTargets = map[string]avrTarget{
+	"uno":      {"atmega328p", "arduino", 115200},
+	"nano":     {"atmega328p", "arduino", 115200},
+	"mega":     {"atmega2560", "wiring", 115200},
+	"leonardo": {"atmega32u4", "avr109", 57600},
+	"micro":    {"atmega32u4", "avr109", 57600},
+}
+
+// espChips maps ESP boards to esptool's --chip value.
+var espChips = map[string]string{
+	"esp32":   "esp32",
+	"esp8266": "esp8266",
+}
+
+// verifyUpload compares the flash contents of the board on port with the
+// firmware image in buildDir. It reports false without an error when the
+// board cannot be verified.
+func verifyUpload(board, port, buildDir string) (bool, error) {
+	board = strings.ToLower(board)
+
+	var cmd *exec.Cmd
+	if t, ok := avrTargets[board]; ok {
+		image, err := findImage(buildDir, ".hex")
+		if err != nil {
+			return false, err
+		}
+		avrdude, conf := findAvrdude()
+		args := []string{"-p", t.mcu, "-c", t.programmer, "-P", port, "-b", strconv.Itoa(t.baud)}
+		if conf != "" {
+			args = append([]string{"-C", conf}, args...)
+		}
+		args = append(args, "-U", fmt.Sprintf("flash:v:%s:i", image), "-q", "-q")
+		cmd = exec.Command(avrdude, args...)
+	} else if chip, ok := espChips[board]; ok {
+		image, err := findImage(buildDir, ".bin")
+		if err != nil {
+			return false, err
+		}
+		// Same offset tsuki-flash wrote the application image to.
+		cmd = exec.Command("esptool.py", "--chip", chip, "--port", port, "verify_flash", "0x1000", image)
+	} else {
+		ui.Warn(fmt.Sprintf("verify is not supported for board %q with tsuki-flash — skipped", board))
+		return false, nil
+	}
+
+	sp := ui.NewSpinner("Verifying flash contents...")
+	sp.Start()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		sp.Stop(false, "verification failed")
+		renderFlashError(string(out), port, board)
+		return false, fmt.Errorf("verification failed: flash contents on %s do not match the built image", port)
+	}
+	sp.Stop(true, "flash contents match the built image")
+	return true, nil
+}
+
+// findImage returns the application image in buildDir, the one the upload
+// wrote (firmware.Find), when it has the given extension. Bootloader and
+// merged images next to it are never picked.
+func findImage(buildDir, ext string) (string, error) {
+	image := firmware.Find(buildDir)
+	if image == "" || !strings.EqualFold(filepath.Ext(image), ext) {
+		return "", fmt.Errorf("no %s firmware found in %s — run `tsuki build --compile` first", ext, buildDir)
+	}
+	return image, nil
+}
+
+// findAvrdude prefers avrdude on PATH and falls back to the copy bundled
+// with the Arduino AVR core, which needs its own avrdude.conf.
+func findAvrdude() (bin, conf string) {
+	if p, err := exec.LookPath("avrdude"); err == nil {
+		return p, ""
+	}
+	home, _ := os.UserHomeDir()
+	matches, _ := filepath.Glob(filepath.Join(home, ".arduino15", "packages", "arduino", "tools", "avrdude", "*", "bin", "avrdude"))
+	if len(matches) == 0 {
+		return "avrdude", ""
+	}
+	bin = matches[len(matches)-1]
+	conf = filepath.Join(filepath.Dir(filepath.Dir(bin)), "etc", "avrdude.conf")
+	if _, err := os.Stat(conf); err != nil {
+		conf = ""
+	}
+	return bin, conf
+}
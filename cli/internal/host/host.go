@@ -0,0 +1,256 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: host  —  sketches built for and run on the build machine
+//
+//  `--target host` compiles the transpiled C++ with the system compiler
+//  against a stub Arduino API (runtime/), bundled into the CLI. The result
+//  is an ordinary executable that runs setup() and loop() on a simulated
+//  board and writes a trace of what the sketch did: pin changes, Serial
+//  lines, servo and LCD output (see runtime/tsuki_host.h).
+// ─────────────────────────────────────────────────────────────────────────────
+
+package host
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCompiler is the C++ compiler host builds use when none is set.
+const DefaultCompiler = "g++"
+
+//go:embed runtime
+var runtimeFiles embed.FS
+
+// WriteRuntime writes the stub Arduino runtime into dir and returns its
+// C++ sources, which are compiled with every host build.
+func WriteRuntime(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(runtimeFiles, "runtime")
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for _, e := range entries {
+		data, err := runtimeFiles.ReadFile("runtime/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, e.Name())
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(path, ".cpp") {
+			sources = append(sources, path)
+		}
+	}
+	return sources, nil
+}
+
+// CompileRequest describes one host compile.
+type CompileRequest struct {
+	// Compiler is the C++ compiler; DefaultCompiler when empty.
+	Compiler string
+	// Sources are the sketch's C++ files.
+	Sources []string
+	// RuntimeDir receives the stub runtime (WriteRuntime).
+	RuntimeDir string
+	// Includes are extra include directories, e.g. of tsuki packages.
+	Includes []string
+	// CppStd is the C++ standard, e.g. "c++11"; compiled as gnu++NN.
+	CppStd     string
+	ExtraFlags []string
+	// Out is the executable to write.
+	Out string
+}
+
+// Compile builds an executable from the sketch sources and the runtime.
+// The compiler output is returned either way; on failure it holds the errors.
+func Compile(req CompileRequest) ([]byte, error) {
+	runtimeSources, err := WriteRuntime(req.RuntimeDir)
+	if err != nil {
+		return nil, fmt.Errorf("writing the host runtime: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(req.Out), 0755); err != nil {
+		return nil, err
+	}
+
+	std := strings.TrimPrefix(strings.TrimPrefix(req.CppStd, "gnu++"), "c++")
+	if std == "" {
+		std = "11"
+	}
+	args := []string{
+		"-std=gnu++" + std,
+		"-g", "-O0", "-w",
+		"-DARDUINO=10819",
+		"-I" + req.RuntimeDir,
+	}
+	for _, inc := range req.Includes {
+		args = append(args, "-I"+inc)
+	}
+	args = append(args, req.ExtraFlags...)
+	args = append(args, req.Sources...)
+	args = append(args, runtimeSources...)
+	args = append(args, "-o", req.Out, "-lm")
+
+	compiler := req.Compiler
+	if compiler == "" {
+		compiler = DefaultCompiler
+	}
+	if _, err := exec.LookPath(compiler); err != nil {
+		return nil, fmt.Errorf("%s not found — install a C++ compiler or set host_compiler in config\n"+
+			"  tsuki config set host_compiler clang++", compiler)
+	}
+	return exec.Command(compiler, args...).CombinedOutput()
+}
+
+// Executable is the path of the host executable called name in dir.
+func Executable(dir, name string) string {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(dir, name)
+}
+
+// RunOptions bound and script one run of a host executable.
+type RunOptions struct {
+	// Loops stops the run after that many loop() calls; 0 for no limit.
+	Loops int
+	// Duration stops the run after that much simulated time; 0 for no limit.
+	Duration time.Duration
+	// Timeout bounds the real time the run may take, for sketches that
+	// never return from loop(). 0 means ten seconds.
+	Timeout time.Duration
+	// Pins are the values digitalRead and analogRead return, by pin.
+	Pins map[int]int
+	// SerialInput is what the sketch reads from Serial.
+	SerialInput string
+}
+
+// Event is one line of the trace.
+type Event struct {
+	// Micros is the simulated time of the event.
+	Micros int64  `json:"us"`
+	Kind   string `json:"kind"`
+	Pin    int    `json:"pin"`
+	Value  int64  `json:"value"`
+	Text   string `json:"text,omitempty"`
+}
+
+// Trace is what a run did.
+type Trace struct {
+	Events []Event `json:"events"`
+	// Loops is the number of completed loop() calls.
+	Loops int `json:"loops"`
+	// Micros is the simulated time the run ended at.
+	Micros int64 `json:"us"`
+}
+
+// Run runs the host executable bin and returns its trace.
+func Run(bin string, opts RunOptions) (*Trace, error) {
+	if opts.Loops <= 0 && opts.Duration <= 0 {
+		return nil, errors.New("a host run needs a loop count or a duration")
+	}
+	args := []string{}
+	if opts.Loops > 0 {
+		args = append(args, "--loops", strconv.Itoa(opts.Loops))
+	}
+	if opts.Duration > 0 {
+		args = append(args, "--time-us", strconv.FormatInt(opts.Duration.Microseconds(), 10))
+	}
+	for pin, v := range opts.Pins {
+		args = append(args, "--pin", fmt.Sprintf("%d=%d", pin, v))
+	}
+	if opts.SerialInput != "" {
+		args = append(args, "--serial-in", opts.SerialInput)
+	}
+
+	stdout, err := execute(bin, args, opts.Timeout)
+	trace, perr := ParseTrace(stdout)
+	if err != nil {
+		return trace, err
+	}
+	return trace, perr
+}
+
+// execute runs a host executable for at most timeout of real time (0 means
+// ten seconds) and returns its stdout, which is kept when it fails.
+func execute(bin string, args []string, timeout time.Duration) ([]byte, error) {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	switch {
+	case ctx.Err() != nil:
+		return stdout.Bytes(), fmt.Errorf("the sketch did not finish within %s of real time — "+
+			"does it loop forever without calling delay() or millis()?", timeout)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.Bytes(), fmt.Errorf("the sketch crashed: %v\n%s", err, msg)
+		}
+		return stdout.Bytes(), fmt.Errorf("the sketch crashed: %v", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// ParseTrace parses the trace a host executable writes.
+func ParseTrace(data []byte) (*Trace, error) {
+	trace := &Trace{Events: []Event{}}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		f := strings.SplitN(line, "\t", 5)
+		if len(f) != 5 {
+			return trace, fmt.Errorf("malformed trace line %q", line)
+		}
+		us, err1 := strconv.ParseInt(f[0], 10, 64)
+		pin, err2 := strconv.Atoi(f[2])
+		value, err3 := strconv.ParseInt(f[3], 10, 64)
+		text, err4 := strconv.Unquote(f[4])
+		if err := errors.Join(err1, err2, err3, err4); err != nil {
+			return trace, fmt.Errorf("malformed trace line %q: %w", line, err)
+		}
+		if f[1] == "end" {
+			trace.Loops, trace.Micros = pin, us
+			continue
+		}
+		trace.Events = append(trace.Events, Event{Micros: us, Kind: f[1], Pin: pin, Value: value, Text: text})
+	}
+	return trace, nil
+}
+
+// PinNumber parses a pin as the simulated board numbers it: "13", "A0"
+// (14) or "LED_BUILTIN" (13).
+func PinNumber(name string) (int, error) {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	switch {
+	case upper == "LED_BUILTIN":
+		return 13, nil
+	case len(upper) == 2 && upper[0] == 'A' && upper[1] >= '0' && upper[1] <= '7':
+		return 14 + int(upper[1]-'0'), nil
+	}
+	n, err := strconv.Atoi(upper)
+	if err != nil || n < 0 || n > 63 {
+		return 0, fmt.Errorf("invalid pin %q (want 0–63, A0–A7 or LED_BUILTIN)", name)
+	}
+	return n, nil
+}
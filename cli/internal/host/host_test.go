@@ -0,0 +1,187 @@
+package host
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// traceOutput is a trace as runtime/host.cpp writes it: microseconds,
+// kind, pin, value and the C-quoted text, tab-separated, then "end" with
+// the loop count in the pin column.
+const traceOutput = "0\tmode\t13\t1\t\"\"\n" +
+	"0\tserial\t-1\t0\t\"tab\\there \\\"quoted\\\" \\x01\"\n" +
+	"1000\tpin\t13\t1\t\"\"\n" +
+	"501000\tlcd\t1\t4\t\"hi\"\n" +
+	"1001000\tend\t2\t0\t\"\"\n"
+
+func TestParseTrace(t *testing.T) {
+	trace, err := ParseTrace([]byte(traceOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Trace{
+		Events: []Event{
+			{Micros: 0, Kind: "mode", Pin: 13, Value: 1},
+			{Micros: 0, Kind: "serial", Pin: -1, Text: "tab\there \"quoted\" \x01"},
+			{Micros: 1000, Kind: "pin", Pin: 13, Value: 1},
+			{Micros: 501000, Kind: "lcd", Pin: 1, Value: 4, Text: "hi"},
+		},
+		Loops:  2,
+		Micros: 1001000,
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %+v\nwant    %+v", trace, want)
+	}
+
+	empty, err := ParseTrace(nil)
+	if err != nil || empty.Events == nil || len(empty.Events) != 0 {
+		t.Errorf("ParseTrace(nil) = %+v, %v; want no events", empty, err)
+	}
+}
+
+func TestParseTraceMalformed(t *testing.T) {
+	for _, line := range []string{
+		"0\tpin\t13\t1",          // four fields
+		"x\tpin\t13\t1\t\"\"",    // time
+		"0\tpin\tD13\t1\t\"\"",   // pin
+		"0\tpin\t13\tHIGH\t\"\"", // value
+		"0\tserial\t-1\t0\thi",   // unquoted text
+	} {
+		trace, err := ParseTrace([]byte("0\tmode\t13\t1\t\"\"\n" + line + "\n"))
+		if err == nil || !strings.Contains(err.Error(), "malformed trace line") {
+			t.Errorf("ParseTrace(%q): err = %v", line, err)
+		}
+		// What came before is kept, for a sketch that crashed mid-line.
+		if trace == nil || len(trace.Events) != 1 {
+			t.Errorf("ParseTrace(%q) dropped the events before the bad line: %+v", line, trace)
+		}
+	}
+}
+
+func TestPinNumber(t *testing.T) {
+	for in, want := range map[string]int{"0": 0, "13": 13, "63": 63, "A0": 14, "a7": 21, " LED_BUILTIN ": 13, "led_builtin": 13} {
+		if got, err := PinNumber(in); err != nil || got != want {
+			t.Errorf("PinNumber(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "-1", "64", "A8", "D13", "A"} {
+		if got, err := PinNumber(in); err == nil {
+			t.Errorf("PinNumber(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+// fakeSketch writes a shell script standing in for a host executable.
+func fakeSketch(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sketch is a shell script")
+	}
+	bin := filepath.Join(t.TempDir(), "sketch")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return bin
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	bin := fakeSketch(t, "printf '0\\tpin\\t13\\t1\\t\"\"\\n'\nexec sleep 10\n")
+	start := time.Now()
+	trace, err := Run(bin, RunOptions{Loops: 1, Timeout: 200 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "did not finish within 200ms") {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("the hung sketch was not killed")
+	}
+	if trace == nil || len(trace.Events) != 1 {
+		t.Errorf("the trace before the timeout was lost: %+v", trace)
+	}
+}
+
+func TestExecuteCrash(t *testing.T) {
+	bin := fakeSketch(t, "printf '0\\tpin\\t13\\t1\\t\"\"\\n'\necho 'Segmentation fault' >&2\nexit 139\n")
+	trace, err := Run(bin, RunOptions{Loops: 1})
+	if err == nil || err.Error() != "the sketch crashed: exit status 139\nSegmentation fault" {
+		t.Fatalf("err = %v, want the crash with its stderr", err)
+	}
+	if trace == nil || len(trace.Events) != 1 {
+		t.Errorf("the trace before the crash was lost: %+v", trace)
+	}
+}
+
+func TestRunArgs(t *testing.T) {
+	bin := fakeSketch(t, "printf '0\\tserial\\t-1\\t0\\t\"%s\"\\n' \"$*\"\nprintf '0\\tend\\t0\\t0\\t\"\"\\n'\n")
+	trace, err := Run(bin, RunOptions{Duration: 2 * time.Second, Pins: map[int]int{2: 1}, SerialInput: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "--time-us 2000000 --pin 2=1 --serial-in go"; len(trace.Events) != 1 || trace.Events[0].Text != want {
+		t.Errorf("args = %+v, want %q", trace.Events, want)
+	}
+	if _, err := Run(bin, RunOptions{}); err == nil {
+		t.Error("Run without a loop count or duration")
+	}
+}
+
+// The trace format is a contract with runtime/host.cpp: build a real
+// sketch against the runtime and parse what it writes.
+func TestRuntimeTrace(t *testing.T) {
+	if _, err := exec.LookPath(DefaultCompiler); err != nil {
+		t.Skip(DefaultCompiler + " not installed")
+	}
+	dir := t.TempDir()
+	sketch := filepath.Join(dir, "sketch.cpp")
+	src := `#include <Arduino.h>
+void setup() {
+    pinMode(13, OUTPUT);
+    Serial.begin(9600);
+    Serial.println("hi\t\"there\"");
+}
+void loop() {
+    digitalWrite(13, HIGH);
+    delay(500);
+    digitalWrite(13, LOW);
+    delay(500);
+}
+`
+	if err := os.WriteFile(sketch, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bin := Executable(dir, "sketch")
+	if out, err := Compile(CompileRequest{Sources: []string{sketch}, RuntimeDir: filepath.Join(dir, "runtime"), Out: bin}); err != nil {
+		t.Fatalf("compile: %v\n%s", err, out)
+	}
+
+	trace, err := Run(bin, RunOptions{Loops: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Loops != 2 || trace.Micros < 2000000 {
+		t.Errorf("ended after %d loops at %dus, want 2 loops and at least 2s", trace.Loops, trace.Micros)
+	}
+	var serial []string
+	var pins []int64
+	for _, e := range trace.Events {
+		switch e.Kind {
+		case "serial":
+			serial = append(serial, e.Text)
+		case "pin":
+			if e.Pin == 13 {
+				pins = append(pins, e.Value)
+			}
+		}
+	}
+	if want := []string{"hi\t\"there\""}; !reflect.DeepEqual(serial, want) {
+		t.Errorf("serial = %q, want %q", serial, want)
+	}
+	if want := []int64{1, 0, 1, 0}; !reflect.DeepEqual(pins, want) {
+		t.Errorf("pin 13 = %v, want %v", pins, want)
+	}
+}
@@ -0,0 +1,146 @@
+package host
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ── Tests ─────────────────────────────────────────────────────────────────────
+//
+// `tsuki test` transpiles src/*_test.go with the sketch and compiles them all
+// as one translation unit, so tests see the sketch's functions and globals
+// as they would in Go. The generated main runs the TestXxx functions through
+// tsuki_test_main (runtime/tsuki_test.h), which reports them in the trace.
+
+// TestMainFile is the generated translation unit of a test executable.
+const TestMainFile = "tsuki_test_main.cpp"
+
+// NoMainFlag leaves main() out of the runtime, for executables that bring
+// their own.
+const NoMainFlag = "-DTSUKI_HOST_NO_MAIN"
+
+// Test statuses.
+const (
+	TestPass = "pass"
+	TestFail = "fail"
+	TestSkip = "skip"
+)
+
+// TestFunc is a test function found in a _test.go file.
+type TestFunc struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// TestResult is the outcome of one test.
+type TestResult struct {
+	TestFunc
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration_ns"`
+	// Output holds the test's t.Log and t.Error messages, "file.go:12: msg".
+	Output []string `json:"output"`
+}
+
+// testFuncPattern matches a test's declaration as `go test` does: Test
+// followed by anything but a lower-case letter, taking a *testing.T.
+var testFuncPattern = regexp.MustCompile(`(?m)^func\s+(Test(?:[^a-z\s(]\w*)?)\s*\(\s*\w+\s+\*\s*testing\.T\s*\)`)
+
+// IsTestFile reports whether path is a test source, *_test.go.
+func IsTestFile(path string) bool {
+	return strings.HasSuffix(filepath.Base(path), "_test.go")
+}
+
+// DiscoverTests returns the test functions declared in files, in order.
+func DiscoverTests(files []string) ([]TestFunc, error) {
+	var tests []TestFunc
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		src := string(data)
+		for _, m := range testFuncPattern.FindAllStringSubmatchIndex(src, -1) {
+			tests = append(tests, TestFunc{
+				Name: src[m[2]:m[3]],
+				File: file,
+				Line: strings.Count(src[:m[0]], "\n") + 1,
+			})
+		}
+	}
+	return tests, nil
+}
+
+// WriteTestMain writes the translation unit of a test executable to path:
+// every transpiled source, sketch first, then a table of the tests.
+func WriteTestMain(path string, sources []string, tests []TestFunc) error {
+	var b strings.Builder
+	b.WriteString("// Generated by tsuki test — do not edit.\n\n")
+	for _, src := range sources {
+		fmt.Fprintf(&b, "#include %s\n", strconv.Quote(filepath.ToSlash(src)))
+	}
+	b.WriteString("\n#include \"tsuki_test.h\"\n\n")
+	b.WriteString("static const tsuki_test_case tsuki_tests[] = {\n")
+	for _, t := range tests {
+		fmt.Fprintf(&b, "    {%s, %s},\n", strconv.Quote(t.Name), t.Name)
+	}
+	b.WriteString("};\n\n")
+	b.WriteString("int main(int argc, char **argv) {\n")
+	b.WriteString("    return tsuki_test_main(tsuki_tests, sizeof(tsuki_tests) / sizeof(tsuki_tests[0]), argc, argv);\n")
+	b.WriteString("}\n")
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// RunTests runs tests in the test executable bin and returns their results
+// in order. When the executable hangs or crashes, the test it was running
+// fails and is the last result; the error says what happened.
+func RunTests(bin string, tests []TestFunc, timeout time.Duration) ([]TestResult, error) {
+	if len(tests) == 0 {
+		return []TestResult{}, nil // no names would run them all
+	}
+	args := make([]string, 0, len(tests))
+	byName := map[string]TestFunc{}
+	for _, t := range tests {
+		args = append(args, t.Name)
+		byName[t.Name] = t
+	}
+
+	stdout, err := execute(bin, args, timeout)
+	trace, perr := ParseTrace(stdout)
+
+	results := []TestResult{}
+	var running *TestResult
+	for _, e := range trace.Events {
+		switch e.Kind {
+		case "test-run":
+			results = append(results, TestResult{TestFunc: byName[e.Text], Output: []string{}})
+			results[len(results)-1].Name = e.Text
+			running = &results[len(results)-1]
+		case "test-log":
+			if running != nil {
+				running.Output = append(running.Output, e.Text)
+			}
+		case "test-pass", "test-fail", "test-skip":
+			if running != nil {
+				running.Status = strings.TrimPrefix(e.Kind, "test-")
+				running.Duration = time.Duration(e.Value) * time.Microsecond
+				running = nil
+			}
+		}
+	}
+	if running != nil {
+		running.Status = TestFail
+		if err != nil {
+			err = fmt.Errorf("%s: %w", running.Name, err)
+		}
+	}
+	if err != nil {
+		return results, err
+	}
+	return results, perr
+}
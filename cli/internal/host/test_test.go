@@ -0,0 +1,106 @@
+package host
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverTests(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "led_test.go")
+	src := `package main
+
+import "testing"
+
+func TestFoo(t *testing.T) {}
+
+func Test(t *testing.T) {}
+
+func Test_x(tt * testing.T) {}
+
+func TestÜber(t *testing.T) {}
+
+func Testfoo(t *testing.T) {}
+
+func TestWrongArg(t testing.T) {}
+
+func TestBench(b *testing.B) {}
+
+func TestNoArgs() {}
+
+func helper(t *testing.T) {}
+`
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests, err := DiscoverTests([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TestFunc{
+		{Name: "TestFoo", File: file, Line: 5},
+		{Name: "Test", File: file, Line: 7},
+		{Name: "Test_x", File: file, Line: 9},
+		{Name: "TestÜber", File: file, Line: 11},
+	}
+	if !reflect.DeepEqual(tests, want) {
+		t.Errorf("tests = %+v\nwant    %+v", tests, want)
+	}
+
+	if _, err := DiscoverTests([]string{filepath.Join(dir, "missing_test.go")}); err == nil {
+		t.Error("DiscoverTests of a missing file")
+	}
+}
+
+// testTrace is a test executable that passes TestA, logs from TestB and
+// then dies with the exit status of a segfault.
+const testTrace = `printf '0\ttest-run\t0\t0\t"TestA"\n'
+printf '0\ttest-log\t0\t0\t"led_test.go:6: on"\n'
+printf '0\ttest-pass\t0\t1500\t""\n'
+printf '0\ttest-run\t0\t0\t"TestB"\n'
+printf '0\ttest-log\t0\t0\t"led_test.go:12: about to crash"\n'
+echo 'Segmentation fault' >&2
+exit 139
+`
+
+func TestRunTestsCrash(t *testing.T) {
+	bin := fakeSketch(t, testTrace)
+	tests := []TestFunc{
+		{Name: "TestA", File: "led_test.go", Line: 5},
+		{Name: "TestB", File: "led_test.go", Line: 11},
+		{Name: "TestC", File: "led_test.go", Line: 20},
+	}
+	results, err := RunTests(bin, tests, 0)
+	if err == nil || !strings.HasPrefix(err.Error(), "TestB: the sketch crashed: exit status 139") {
+		t.Fatalf("err = %v, want TestB's crash", err)
+	}
+	want := []TestResult{
+		{TestFunc: tests[0], Status: TestPass, Duration: 1500000, Output: []string{"led_test.go:6: on"}},
+		{TestFunc: tests[1], Status: TestFail, Output: []string{"led_test.go:12: about to crash"}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %+v\nwant      %+v", results, want)
+	}
+}
+
+func TestRunTestsArgs(t *testing.T) {
+	bin := fakeSketch(t, `for name in "$@"; do
+  printf '0\ttest-run\t0\t0\t"%s"\n' "$name"
+  printf '0\ttest-skip\t0\t0\t""\n'
+done
+`)
+	tests := []TestFunc{{Name: "TestB"}, {Name: "TestA"}}
+	results, err := RunTests(bin, tests, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "TestB" || results[1].Name != "TestA" || results[1].Status != TestSkip {
+		t.Errorf("results = %+v, want TestB and TestA skipped, in order", results)
+	}
+	if results, err := RunTests(bin, nil, 0); err != nil || len(results) != 0 {
+		t.Errorf("RunTests(no tests) = %+v, %v; want nothing run", results, err)
+	}
+}
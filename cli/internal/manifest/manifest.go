@@ -14,7 +14,14 @@
 //      { "name": "ws2812",  "version": "^1.0.0" },
 //      { "name": "dht",     "version": "^1.0.0" }
 //    ],
-//    "build": { ... }
+//    "libraries": [
+//      { "name": "Adafruit NeoPixel", "version": "1.12.0", "packages": ["ws2812"] }
+//    ],
+//    "build": { ... },
+//    "toolchain": {
+//      "tsuki_core": "3.1.0", "tsuki_flash": "0.6.2", "arduino_cli": "1.0.4",
+//      "cores": { "arduino:avr": "1.8.6" }
+//    }
 //  }
 // ─────────────────────────────────────────────────────────────────────────────
 
@@ -25,6 +32,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const FileName = "tsuki_package.json"
@@ -35,12 +43,32 @@ type Manifest struct {
 	Board       string       `json:"board"`
 	GoVersion   string       `json:"go_version"`
 	Description string       `json:"description,omitempty"`
-	// Compiler backend: "tsuki-flash", "tsuki-flash+cores", or "arduino-cli".
+	// Compiler backend: "tsuki-flash", "tsuki-flash+cores", "arduino-cli", or
+	// the name of an external template (see backend.New).
 	// Empty string falls back to the global CLI config (cfg.Backend).
 	Backend     string       `json:"backend,omitempty"`
 	// External tsukilib packages used by this project.
 	Packages    []Package    `json:"packages"`
+	// Arduino libraries the sketch links against, pinned by `tsuki lib install`.
+	Libraries   []Library    `json:"libraries,omitempty"`
 	Build       BuildConfig  `json:"build"`
+	// Exact tool versions to build with, installed by `tsuki toolchain install`.
+	Toolchain   *Toolchain   `json:"toolchain,omitempty"`
+}
+
+// Toolchain pins the tools a project builds with, so every machine produces
+// the same firmware. Empty fields leave that tool to the user's config.
+type Toolchain struct {
+	TsukiCore  string `json:"tsuki_core,omitempty"`
+	TsukiFlash string `json:"tsuki_flash,omitempty"`
+	ArduinoCLI string `json:"arduino_cli,omitempty"`
+	// Board cores by ID, as `tsuki core list` names them.
+	Cores      map[string]string `json:"cores,omitempty"`
+	// SHA-256 of each tool's release file, by tool name ("tsuki-core",
+	// "tsuki-flash", "arduino-cli"). Optional; checked on download.
+	SHA256     map[string]string `json:"sha256,omitempty"`
+	// Mirror replaces the download locations: <mirror>/<tool>/<version>/<file>.
+	Mirror     string `json:"mirror,omitempty"`
 }
 
 // Package is a single tsukilib dependency declared in the manifest.
@@ -51,12 +79,26 @@ type Package struct {
 	Version string `json:"version"`
 }
 
+// Library is an Arduino library the project needs.
+type Library struct {
+	// Library-manager name (e.g. "Adafruit NeoPixel").
+	Name     string   `json:"name"`
+	// Exact version; empty until the library is installed and pinned.
+	Version  string   `json:"version,omitempty"`
+	// tsukilib packages whose arduino_lib pulled the library in. Empty for
+	// a library installed directly with `tsuki lib install <name>`.
+	Packages []string `json:"packages,omitempty"`
+}
+
 type BuildConfig struct {
 	OutputDir  string   `json:"output_dir"`
 	CppStd     string   `json:"cpp_std"`
 	Optimize   string   `json:"optimize"`
 	ExtraFlags []string `json:"extra_flags"`
 	SourceMap  bool     `json:"source_map"`
+	// Defines become string constants of the generated buildinfo package;
+	// `tsuki build --define NAME=VALUE` overrides them per build.
+	Defines    map[string]string `json:"defines,omitempty"`
 }
 
 // Default returns a manifest with sensible defaults.
@@ -158,4 +200,69 @@ func (m *Manifest) RemovePackage(name string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// Library returns the declared Arduino library called name, or nil.
+// Library-manager names are matched case-insensitively.
+func (m *Manifest) Library(name string) *Library {
+	for i := range m.Libraries {
+		if strings.EqualFold(m.Libraries[i].Name, name) {
+			return &m.Libraries[i]
+		}
+	}
+	return nil
+}
+
+// AddLibrary declares an Arduino library, or updates the declared one: a
+// non-empty version replaces the pin, and pkg (when set) is recorded as
+// one of the packages that need it.
+func (m *Manifest) AddLibrary(name, version, pkg string) {
+	lib := m.Library(name)
+	if lib == nil {
+		m.Libraries = append(m.Libraries, Library{Name: name})
+		lib = &m.Libraries[len(m.Libraries)-1]
+	}
+	if version != "" {
+		lib.Version = version
+	}
+	if pkg != "" {
+		for _, p := range lib.Packages {
+			if p == pkg {
+				return
+			}
+		}
+		lib.Packages = append(lib.Packages, pkg)
+	}
+}
+
+// RemoveLibrary removes an Arduino library by name.
+func (m *Manifest) RemoveLibrary(name string) bool {
+	for i, l := range m.Libraries {
+		if strings.EqualFold(l.Name, name) {
+			m.Libraries = append(m.Libraries[:i], m.Libraries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// DropPackageLibraries forgets that pkg needs its Arduino libraries, and
+// removes those no other package needs.
+func (m *Manifest) DropPackageLibraries(pkg string) {
+	kept := m.Libraries[:0]
+	for _, l := range m.Libraries {
+		pulled := len(l.Packages) > 0
+		pkgs := l.Packages[:0]
+		for _, p := range l.Packages {
+			if p != pkg {
+				pkgs = append(pkgs, p)
+			}
+		}
+		l.Packages = pkgs
+		if pulled && len(pkgs) == 0 {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	m.Libraries = kept
+}
@@ -0,0 +1,157 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemVer is a parsed MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD] version.
+type SemVer struct {
+	Major, Minor, Patch int
+	Pre                 string
+	Build               string
+}
+
+// ParseSemVer parses a semantic version. A leading "v" is accepted.
+func ParseSemVer(s string) (SemVer, error) {
+	var v SemVer
+	rest := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		rest, v.Build = rest[:i], rest[i+1:]
+		if !validIdents(v.Build) {
+			return v, fmt.Errorf("invalid version %q: bad build metadata %q", s, v.Build)
+		}
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		rest, v.Pre = rest[:i], rest[i+1:]
+		if !validIdents(v.Pre) {
+			return v, fmt.Errorf("invalid version %q: bad pre-release %q", s, v.Pre)
+		}
+	}
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("invalid version %q (expected MAJOR.MINOR.PATCH)", s)
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (len(p) > 1 && p[0] == '0') {
+			return v, fmt.Errorf("invalid version %q: bad number %q", s, p)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+// validIdents reports whether s is one or more dot-separated non-empty
+// identifiers of [0-9A-Za-z-].
+func validIdents(s string) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, c := range id {
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Less orders versions by precedence; build metadata is ignored and a
+// pre-release sorts before its release.
+func (v SemVer) Less(o SemVer) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	if v.Patch != o.Patch {
+		return v.Patch < o.Patch
+	}
+	if v.Pre == "" || o.Pre == "" {
+		return v.Pre != "" && o.Pre == ""
+	}
+	return comparePre(v.Pre, o.Pre) < 0
+}
+
+// comparePre compares dot-separated pre-release identifiers: numeric ones
+// numerically, others lexically, and a shorter prefix first.
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(as) - len(bs)
+}
+
+// BumpVersion returns the version that follows current.
+// part is "major", "minor", "patch" or an explicit version, which must be
+// greater than current.
+func BumpVersion(current, part string) (string, error) {
+	cur, err := ParseSemVer(current)
+	if err != nil {
+		return "", err
+	}
+
+	// Releasing a pre-release keeps its numbers when it already leads to
+	// the bumped part: 2.0.0-rc.1 → 2.0.0 for major, 1.3.0-rc.1 → 1.3.0
+	// for minor, 1.2.3-rc.1 → 1.2.3 for patch.
+	next := SemVer{Major: cur.Major, Minor: cur.Minor, Patch: cur.Patch}
+	pre := cur.Pre != ""
+	switch part {
+	case "major":
+		if !pre || cur.Minor != 0 || cur.Patch != 0 {
+			next = SemVer{Major: cur.Major + 1}
+		}
+	case "minor":
+		if !pre || cur.Patch != 0 {
+			next = SemVer{Major: cur.Major, Minor: cur.Minor + 1}
+		}
+	case "patch":
+		if !pre {
+			next.Patch++
+		}
+	default:
+		if next, err = ParseSemVer(part); err != nil {
+			return "", err
+		}
+		if !cur.Less(next) {
+			return "", fmt.Errorf("new version %s is not greater than current %s", next, cur)
+		}
+	}
+	return next.String(), nil
+}
@@ -0,0 +1,92 @@
+package manifest
+
+import "testing"
+
+func TestParseSemVer(t *testing.T) {
+	tests := []struct {
+		in   string
+		want SemVer
+	}{
+		{"1.2.3", SemVer{Major: 1, Minor: 2, Patch: 3}},
+		{"v0.10.0", SemVer{Minor: 10}},
+		{"1.2.3-rc.1", SemVer{Major: 1, Minor: 2, Patch: 3, Pre: "rc.1"}},
+		{"1.2.3-rc.1+build.5", SemVer{Major: 1, Minor: 2, Patch: 3, Pre: "rc.1", Build: "build.5"}},
+		{"1.2.3+sha-1a2b", SemVer{Major: 1, Minor: 2, Patch: 3, Build: "sha-1a2b"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSemVer(tt.in)
+		if err != nil {
+			t.Errorf("ParseSemVer(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSemVer(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if tt.in[0] != 'v' && got.String() != tt.in {
+			t.Errorf("ParseSemVer(%q).String() = %q", tt.in, got)
+		}
+	}
+
+	for _, in := range []string{"", "1.2", "1.2.3.4", "01.2.3", "1.-2.3", "a.b.c", "1.2.3-", "1.2.3+", "1.2.3-rc..1", "1.2.3-rc_1"} {
+		if v, err := ParseSemVer(in); err == nil {
+			t.Errorf("ParseSemVer(%q) = %+v, want an error", in, v)
+		}
+	}
+}
+
+func TestSemVerLess(t *testing.T) {
+	// In increasing precedence.
+	order := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+	for i := 0; i+1 < len(order); i++ {
+		a, _ := ParseSemVer(order[i])
+		b, _ := ParseSemVer(order[i+1])
+		if !a.Less(b) || b.Less(a) {
+			t.Errorf("want %s < %s", a, b)
+		}
+	}
+	a, _ := ParseSemVer("1.0.0+a")
+	b, _ := ParseSemVer("1.0.0+b")
+	if a.Less(b) || b.Less(a) {
+		t.Error("build metadata affects precedence")
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	tests := []struct{ current, part, want string }{
+		{"1.2.3", "patch", "1.2.4"},
+		{"1.2.3", "minor", "1.3.0"},
+		{"1.2.3", "major", "2.0.0"},
+		{"1.2.3+build.1", "patch", "1.2.4"},
+		{"1.2.3-rc.1", "patch", "1.2.3"},
+		{"1.2.3-rc.1", "minor", "1.3.0"},
+		{"1.3.0-rc.1", "minor", "1.3.0"},
+		{"1.3.0-rc.1", "major", "2.0.0"},
+		{"2.0.0-rc.1", "major", "2.0.0"},
+		{"2.0.0-rc.1", "minor", "2.0.0"},
+		{"1.2.3", "1.2.4-beta", "1.2.4-beta"},
+		{"1.2.3-rc.1", "1.2.3-rc.2", "1.2.3-rc.2"},
+	}
+	for _, tt := range tests {
+		got, err := BumpVersion(tt.current, tt.part)
+		if err != nil {
+			t.Errorf("BumpVersion(%q, %q): %v", tt.current, tt.part, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("BumpVersion(%q, %q) = %q, want %q", tt.current, tt.part, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct{ current, part string }{
+		{"1.2.3", "1.2.3"},
+		{"1.2.3", "1.2.2"},
+		{"1.2.3", "1.2.3-rc.1"},
+		{"1.2.3", "next"},
+		{"bad", "patch"},
+	} {
+		if got, err := BumpVersion(tt.current, tt.part); err == nil {
+			t.Errorf("BumpVersion(%q, %q) = %q, want an error", tt.current, tt.part, got)
+		}
+	}
+}
@@ -76,12 +76,12 @@ func KeysDir() string {
 // ── InstalledPackage ──────────────────────────────────────────────────────────
 
 type InstalledPackage struct {
-	Name        string
-	Version     string
-	Description string
-	CppHeader   string
-	ArduinoLib  string
-	Path        string
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	CppHeader   string `json:"cpp_header"`
+	ArduinoLib  string `json:"arduino_lib"`
+	Path        string `json:"path"`
 }
 
 func ListInstalled() ([]InstalledPackage, error) {
@@ -428,6 +428,16 @@ func fetchRegistryFromURL(url string) (*RegistryIndex, error) {
 	return &idx, nil
 }
 
+// CheckRegistry fetches a single registry and returns how many packages it
+// lists. Used by `tsuki doctor` to report each registry separately.
+func CheckRegistry(url string) (int, error) {
+	idx, err := fetchRegistryFromURL(url)
+	if err != nil {
+		return 0, err
+	}
+	return len(idx.Packages), nil
+}
+
 // FetchAllRegistries fetches and merges packages from all configured registry
 // URLs.  The first registry in the list wins on name collisions.  A warning
 // is printed whenever a package name is shadowed by an earlier registry.
@@ -0,0 +1,151 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JUnit XML as read by Jenkins, GitLab and most test dashboards: one suite
+// per source file, one failing test case per error, and a single passing
+// case for files without errors or tests. Warnings go to the suite's
+// system-out; tests are cases of their file's suite.
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit renders doc as a JUnit XML report.
+func JUnit(doc Document) ([]byte, error) {
+	suites := map[string]*junitTestSuite{}
+	var order []string
+	suite := func(file string) *junitTestSuite {
+		name := relPath(doc.Root, file)
+		if name == "" {
+			name = doc.Command
+		}
+		s, ok := suites[name]
+		if !ok {
+			s = &junitTestSuite{Name: name}
+			suites[name] = s
+			order = append(order, name)
+		}
+		return s
+	}
+
+	for _, f := range doc.Files {
+		suite(f)
+	}
+
+	warnings := map[*junitTestSuite][]string{}
+	for _, f := range doc.Findings {
+		s := suite(f.File)
+		loc := location(s.Name, f)
+		if f.Level != LevelError {
+			warnings[s] = append(warnings[s], fmt.Sprintf("%s: %s: %s [%s]", loc, f.Level, f.Message, ruleID(f)))
+			continue
+		}
+		s.Cases = append(s.Cases, junitTestCase{
+			Name:      fmt.Sprintf("%s %s", loc, ruleID(f)),
+			ClassName: doc.Command,
+			File:      relPath(doc.Root, f.File),
+			Line:      f.Line,
+			Failure: &junitFailure{
+				Message: f.Message,
+				Type:    ruleID(f),
+				Text:    fmt.Sprintf("%s: %s", loc, f.Message),
+			},
+		})
+		s.Failures++
+	}
+
+	for _, t := range doc.Tests {
+		s := suite(t.File)
+		c := junitTestCase{
+			Name:      t.Name,
+			ClassName: strings.TrimSuffix(s.Name, ".go"),
+			File:      relPath(doc.Root, t.File),
+			Line:      t.Line,
+			Time:      fmt.Sprintf("%.3f", t.Seconds),
+			SystemOut: t.Output,
+		}
+		switch t.Status {
+		case TestFail:
+			c.Failure = &junitFailure{Message: "test failed", Type: "test", Text: t.Output}
+			c.SystemOut = ""
+			s.Failures++
+		case TestSkip:
+			c.Skipped = &junitSkipped{Message: "test skipped"}
+			s.Skipped++
+		}
+		s.Cases = append(s.Cases, c)
+	}
+
+	sort.Strings(order)
+	out := junitTestSuites{Name: "tsuki " + doc.Command}
+	for _, name := range order {
+		s := suites[name]
+		if len(s.Cases) == 0 {
+			s.Cases = append(s.Cases, junitTestCase{Name: name, ClassName: doc.Command})
+		}
+		if w := warnings[s]; len(w) > 0 {
+			s.SystemOut = strings.Join(w, "\n")
+		}
+		s.Tests = len(s.Cases)
+		out.Tests += s.Tests
+		out.Failures += s.Failures
+		out.Suites = append(out.Suites, *s)
+	}
+
+	data, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(xml.Header), data...), '\n'), nil
+}
+
+// location renders "file:line:col", dropping the parts that are unknown.
+func location(file string, f Finding) string {
+	switch {
+	case f.Line > 0 && f.Column > 0:
+		return fmt.Sprintf("%s:%d:%d", file, f.Line, f.Column)
+	case f.Line > 0:
+		return fmt.Sprintf("%s:%d", file, f.Line)
+	}
+	return file
+}
@@ -0,0 +1,172 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: report  —  CI reports for check and build results
+//
+//  `--report sarif=<file>` feeds code-scanning (GitHub, GitLab…) and
+//  `--report junit=<file>` feeds test dashboards. Both are written from the
+//  same list of findings, JUnit also from tsuki test's results; paths are
+//  made relative to the project root so annotations land inline on pull
+//  requests.
+// ─────────────────────────────────────────────────────────────────────────────
+
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kind is the report format.
+type Kind string
+
+const (
+	KindSARIF Kind = "sarif"
+	KindJUnit Kind = "junit"
+)
+
+// Spec is one parsed --report value.
+type Spec struct {
+	Kind Kind
+	Path string
+}
+
+// ParseSpec parses "sarif=<file>" or "junit=<file>".
+func ParseSpec(s string) (Spec, error) {
+	kind, path, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return Spec{}, fmt.Errorf("invalid --report %q (use sarif=<file> or junit=<file>)", s)
+	}
+	switch Kind(strings.ToLower(kind)) {
+	case KindSARIF:
+		return Spec{Kind: KindSARIF, Path: path}, nil
+	case KindJUnit:
+		return Spec{Kind: KindJUnit, Path: path}, nil
+	}
+	return Spec{}, fmt.Errorf("unknown report kind %q (use sarif or junit)", kind)
+}
+
+// ParseSpecs parses every --report value.
+func ParseSpecs(values []string) ([]Spec, error) {
+	specs := make([]Spec, 0, len(values))
+	for _, v := range values {
+		spec, err := ParseSpec(v)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// Level of a finding.
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+)
+
+// Finding is one issue to report. Line and Column are 1-based; 0 means
+// unknown. File is empty when the tool gave no location at all.
+type Finding struct {
+	File    string
+	Line    int
+	Column  int
+	Rule    string // e.g. "parse", "type", "compile"
+	Level   Level
+	Message string
+}
+
+// Document is everything a report describes.
+type Document struct {
+	// Command names the run, e.g. "check" or "build".
+	Command string
+	// Version is the tsuki version recorded as the tool version.
+	Version string
+	// Root is the project root that paths are made relative to.
+	Root string
+	// Files are the sources that were processed; each becomes a JUnit suite
+	// even when it has no findings.
+	Files    []string
+	Findings []Finding
+	// Tests are the results of a test run; in JUnit each becomes a case of
+	// its file's suite.
+	Tests []Test
+}
+
+// Test statuses.
+const (
+	TestPass = "pass"
+	TestFail = "fail"
+	TestSkip = "skip"
+)
+
+// Test is the result of one test.
+type Test struct {
+	Name    string
+	File    string
+	Line    int
+	Status  string
+	Seconds float64
+	// Output is what the test logged, one message per line.
+	Output string
+}
+
+// Write renders doc in spec's format to spec.Path, creating parent dirs.
+func Write(spec Spec, doc Document) error {
+	var data []byte
+	var err error
+	switch spec.Kind {
+	case KindSARIF:
+		data, err = SARIF(doc)
+	case KindJUnit:
+		data, err = JUnit(doc)
+	default:
+		return fmt.Errorf("unknown report kind %q", spec.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(spec.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("creating report dir: %w", err)
+		}
+	}
+	if err := os.WriteFile(spec.Path, data, 0644); err != nil {
+		return fmt.Errorf("writing %s report: %w", spec.Kind, err)
+	}
+	return nil
+}
+
+// relPath returns file relative to root with forward slashes. Files outside
+// root (or when either cannot be resolved) are returned cleaned but as-is.
+func relPath(root, file string) string {
+	if file == "" {
+		return ""
+	}
+	abs := file
+	if !filepath.IsAbs(abs) {
+		if a, err := filepath.Abs(abs); err == nil {
+			abs = a
+		}
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(file))
+	}
+	rel, err := filepath.Rel(absRoot, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(filepath.Clean(file))
+	}
+	return filepath.ToSlash(rel)
+}
+
+// ruleID returns f.Rule, or "unknown" when the tool gave none.
+func ruleID(f Finding) string {
+	if f.Rule == "" {
+		return "unknown"
+	}
+	return f.Rule
+}
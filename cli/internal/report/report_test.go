@@ -0,0 +1,91 @@
+package report
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with testdata/name, or rewrites it with -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file:\n%s", name, got)
+	}
+}
+
+const root = "/work/blink"
+
+// checkDoc has findings with and without a rule, line, column or file,
+// and one outside the project root.
+var checkDoc = Document{
+	Command: "check",
+	Version: "1.2.3",
+	Root:    root,
+	Files:   []string{root + "/src/main.go", root + "/src/util.go", root + "/src/clean.go"},
+	Findings: []Finding{
+		{File: root + "/src/main.go", Line: 4, Column: 7, Rule: "type", Level: LevelError, Message: "undefined: foo"},
+		{File: root + "/src/main.go", Line: 9, Rule: "goroutine", Level: LevelWarning, Message: "goroutines are not supported"},
+		{File: root + "/src/util.go", Line: 2, Column: 1, Rule: "parse", Level: LevelError, Message: "expected declaration"},
+		{File: "/usr/share/arduino/Arduino.h", Line: 3, Column: 1, Rule: "compile", Level: LevelError, Message: "conflicting declaration"},
+		{Level: LevelError, Message: "ld returned 1 exit status"},
+	},
+}
+
+// testDoc is a tsuki test run with a pass, a failure and a skip.
+var testDoc = Document{
+	Command: "test",
+	Version: "1.2.3",
+	Root:    root,
+	Files:   []string{root + "/src/led_test.go"},
+	Tests: []Test{
+		{Name: "TestBlink", File: root + "/src/led_test.go", Line: 5, Status: TestPass, Seconds: 0.0012, Output: "led on"},
+		{Name: "TestButton", File: root + "/src/led_test.go", Line: 12, Status: TestFail, Seconds: 0.5, Output: "led_test.go:14: pin 13 = LOW, want HIGH"},
+		{Name: "TestSerial", File: root + "/src/led_test.go", Line: 20, Status: TestSkip, Output: "no serial yet"},
+	},
+}
+
+func TestSARIF(t *testing.T) {
+	data, err := SARIF(checkDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "check.sarif", data)
+}
+
+func TestJUnit(t *testing.T) {
+	for name, doc := range map[string]Document{"check.xml": checkDoc, "test.xml": testDoc} {
+		t.Run(name, func(t *testing.T) {
+			data, err := JUnit(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, name, data)
+		})
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	if s, err := ParseSpec("SARIF=build/check.sarif"); err != nil || s != (Spec{Kind: KindSARIF, Path: "build/check.sarif"}) {
+		t.Errorf("ParseSpec = %+v, %v", s, err)
+	}
+	for _, in := range []string{"sarif", "junit=", "html=out.html"} {
+		if _, err := ParseSpec(in); err == nil {
+			t.Errorf("ParseSpec(%q) accepted", in)
+		}
+	}
+}
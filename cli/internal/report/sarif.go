@@ -0,0 +1,157 @@
+package report
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/tsuki/cli/internal/config"
+)
+
+// SARIF 2.1.0 — only the subset code-scanning services read.
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     Level           `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// ruleDescriptions describes the rule IDs tsuki itself produces.
+var ruleDescriptions = map[string]string{
+	"lex":         "Lexical error in Go source",
+	"parse":       "Syntax error in Go source",
+	"type":        "Type error in Go source",
+	"codegen":     "Construct that cannot be translated to C++",
+	"io":          "File could not be read or written",
+	"compile":     "C++ compiler error in the generated sketch",
+	"tsuki-flash": "tsuki-flash toolchain error",
+	"entry-point": "Missing or malformed setup/loop",
+	"import":      "Import not declared in the manifest",
+	"undefined":   "Name not in the tsuki Arduino API",
+	"stdlib":      "Standard library feature not available on the board",
+	"goroutine":   "Goroutines are not supported",
+	"channel":     "Channels and select are not supported",
+	"map":         "Maps are not supported",
+	"interface":   "Interfaces are not supported",
+	"closure":     "Function literals are not supported",
+	"defer":       "defer does not run at function exit",
+}
+
+// SARIF renders doc as a SARIF 2.1.0 log with a single run.
+func SARIF(doc Document) ([]byte, error) {
+	ruleIndex := map[string]int{}
+	var ids []string
+	for _, f := range doc.Findings {
+		id := ruleID(f)
+		if _, ok := ruleIndex[id]; !ok {
+			ruleIndex[id] = 0
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	rules := make([]sarifRule, len(ids))
+	for i, id := range ids {
+		ruleIndex[id] = i
+		desc, ok := ruleDescriptions[id]
+		if !ok {
+			desc = id
+		}
+		rules[i] = sarifRule{ID: id, ShortDescription: sarifMessage{Text: desc}}
+	}
+
+	results := make([]sarifResult, 0, len(doc.Findings))
+	for _, f := range doc.Findings {
+		id := ruleID(f)
+		r := sarifResult{
+			RuleID:    id,
+			RuleIndex: ruleIndex[id],
+			Level:     f.Level,
+			Message:   sarifMessage{Text: f.Message},
+		}
+		if f.File != "" {
+			loc := sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: relPath(doc.Root, f.File)},
+			}
+			if f.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+			}
+			r.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		results = append(results, r)
+	}
+
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "tsuki",
+				Version:        doc.Version,
+				InformationURI: config.ProjectURL,
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
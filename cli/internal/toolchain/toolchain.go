@@ -0,0 +1,325 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: toolchain  —  tools pinned in the manifest, installed per version
+//
+//  Store:  <data dir>/toolchains/<tool>/<version>/<binary>
+//
+//  Downloads come from the project's GitHub releases (tsuki-core,
+//  tsuki-flash) and downloads.arduino.cc (arduino-cli), or from a mirror
+//  laid out as <mirror>/<tool>/<version>/<file>, with the same file names.
+//  file:// mirrors are read from disk.
+// ─────────────────────────────────────────────────────────────────────────────
+
+package toolchain
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/tsuki/cli/internal/config"
+	"github.com/tsuki/cli/internal/manifest"
+)
+
+// Tool names, which are also their directories in the store.
+const (
+	TsukiCore  = "tsuki-core"
+	TsukiFlash = "tsuki-flash"
+	ArduinoCLI = "arduino-cli"
+)
+
+// MirrorEnv overrides the manifest's mirror, e.g. to test against a local one.
+const MirrorEnv = "TSUKI_TOOLCHAIN_MIRROR"
+
+const (
+	releasesURL   = config.ProjectURL + "/releases/download"
+	arduinoCLIURL = "https://downloads.arduino.cc/arduino-cli"
+)
+
+// versionRe is a plain semver version, the only form a pinned version may
+// take: it becomes a directory in the store and part of the download URL.
+var versionRe = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+// Pin is one tool pinned in the manifest.
+type Pin struct {
+	Tool      string `json:"tool"`
+	Version   string `json:"version"`
+	SHA256    string `json:"sha256,omitempty"`
+	Path      string `json:"path"`
+	Installed bool   `json:"installed"`
+}
+
+// Pins lists the tools tc pins, in a fixed order.
+func Pins(tc *manifest.Toolchain) ([]Pin, error) {
+	if tc == nil {
+		return nil, nil
+	}
+	var pins []Pin
+	for _, p := range []struct{ tool, version string }{
+		{TsukiCore, tc.TsukiCore},
+		{TsukiFlash, tc.TsukiFlash},
+		{ArduinoCLI, tc.ArduinoCLI},
+	} {
+		if p.version == "" {
+			continue
+		}
+		path, err := Path(p.tool, p.version)
+		if err != nil {
+			return nil, err
+		}
+		_, err = os.Stat(path)
+		pins = append(pins, Pin{
+			Tool:      p.tool,
+			Version:   p.version,
+			SHA256:    tc.SHA256[p.tool],
+			Path:      path,
+			Installed: err == nil,
+		})
+	}
+	return pins, nil
+}
+
+// Binaries are the installed binaries of a toolchain; "" for tools it does
+// not pin.
+type Binaries struct {
+	TsukiCore  string
+	TsukiFlash string
+	ArduinoCLI string
+}
+
+// Apply replaces each configured binary with the pinned one, if any.
+func (b Binaries) Apply(tsukiCore, tsukiFlash, arduinoCLI *string) {
+	for _, p := range []struct {
+		pinned string
+		dst    *string
+	}{
+		{b.TsukiCore, tsukiCore}, {b.TsukiFlash, tsukiFlash}, {b.ArduinoCLI, arduinoCLI},
+	} {
+		if p.pinned != "" && p.dst != nil {
+			*p.dst = p.pinned
+		}
+	}
+}
+
+// Resolve returns the binaries tc pins, or an error naming those that are
+// not installed yet.
+func Resolve(tc *manifest.Toolchain) (Binaries, error) {
+	return ResolveTools(tc, TsukiCore, TsukiFlash, ArduinoCLI)
+}
+
+// ResolveTools is Resolve for the named tools only: the others are neither
+// required to be installed nor returned.
+func ResolveTools(tc *manifest.Toolchain, tools ...string) (Binaries, error) {
+	var bins Binaries
+	var missing []string
+	pins, err := Pins(tc)
+	if err != nil {
+		return bins, err
+	}
+	wanted := map[string]bool{}
+	for _, t := range tools {
+		wanted[t] = true
+	}
+	for _, p := range pins {
+		if !wanted[p.Tool] {
+			continue
+		}
+		if !p.Installed {
+			missing = append(missing, p.Tool+"@"+p.Version)
+			continue
+		}
+		switch p.Tool {
+		case TsukiCore:
+			bins.TsukiCore = p.Path
+		case TsukiFlash:
+			bins.TsukiFlash = p.Path
+		case ArduinoCLI:
+			bins.ArduinoCLI = p.Path
+		}
+	}
+	if len(missing) > 0 {
+		return bins, fmt.Errorf("tools pinned in %s are not installed: %s\n  Run: tsuki toolchain install",
+			manifest.FileName, strings.Join(missing, ", "))
+	}
+	return bins, nil
+}
+
+// Dir is the toolchain store.
+func Dir() string {
+	return filepath.Join(config.DataDir(), "toolchains")
+}
+
+// Path is where version of tool is installed. version must be a plain
+// semver version.
+func Path(tool, version string) (string, error) {
+	if !versionRe.MatchString(version) {
+		return "", fmt.Errorf("%s version %q in %s is not a plain version like 1.2.3", tool, version, manifest.FileName)
+	}
+	return filepath.Join(Dir(), tool, version, binaryName(tool)), nil
+}
+
+// Mirror is the download mirror: the flag's, else $TSUKI_TOOLCHAIN_MIRROR,
+// else the manifest's. Empty means the official locations.
+func Mirror(flag string, tc *manifest.Toolchain) string {
+	switch {
+	case flag != "":
+		return flag
+	case os.Getenv(MirrorEnv) != "":
+		return os.Getenv(MirrorEnv)
+	case tc != nil:
+		return tc.Mirror
+	}
+	return ""
+}
+
+// URL is where Install downloads version of tool.
+func URL(tool, version, mirror string) string {
+	file := fileName(tool, version)
+	switch {
+	case mirror != "":
+		return strings.TrimRight(mirror, "/") + "/" + tool + "/" + version + "/" + file
+	case tool == ArduinoCLI:
+		return arduinoCLIURL + "/" + file
+	}
+	return releasesURL + "/v" + version + "/" + file
+}
+
+// Install downloads p's version of its tool into the store, unless it is
+// already there, and returns the binary's path. When p pins a SHA-256, the
+// downloaded file must match it.
+func Install(p Pin, mirror string) (string, error) {
+	tool, version := p.Tool, p.Version
+	dest, err := Path(tool, version)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return dest, nil
+	}
+
+	url := URL(tool, version, mirror)
+	data, err := fetch(url)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s %s: %w", tool, version, err)
+	}
+	if p.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, p.SHA256) {
+			return "", fmt.Errorf("%s: SHA-256 is %s, but %s pins %s", url, got, manifest.FileName, p.SHA256)
+		}
+	}
+	bin, err := binaryFrom(fileName(tool, version), data, binaryName(tool))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", url, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	// Write then rename, so an interrupted install never looks installed.
+	tmp := dest + ".part"
+	if err := os.WriteFile(tmp, bin, 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return dest, nil
+}
+
+// fileName is the release file of tool for this host.
+func fileName(tool, version string) string {
+	if tool != ArduinoCLI {
+		return fmt.Sprintf("%s-%s-%s%s", tool, runtime.GOOS, runtime.GOARCH, exeSuffix())
+	}
+	osName := map[string]string{"linux": "Linux", "darwin": "macOS", "windows": "Windows"}[runtime.GOOS]
+	arch := map[string]string{"amd64": "64bit", "386": "32bit", "arm64": "ARM64", "arm": "ARMv7"}[runtime.GOARCH]
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("arduino-cli_%s_%s_%s%s", version, osName, arch, ext)
+}
+
+func binaryName(tool string) string {
+	return tool + exeSuffix()
+}
+
+func exeSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+func fetch(url string) ([]byte, error) {
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		return os.ReadFile(path)
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// binaryFrom returns the executable in a download: the file itself, or the
+// member called name of a .tar.gz or .zip archive.
+func binaryFrom(file string, data []byte, name string) ([]byte, error) {
+	switch {
+	case strings.HasSuffix(file, ".tar.gz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == name {
+				return io.ReadAll(tr)
+			}
+		}
+	case strings.HasSuffix(file, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() || filepath.Base(f.Name) != name {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+	default:
+		return data, nil
+	}
+	return nil, fmt.Errorf("archive has no %s", name)
+}
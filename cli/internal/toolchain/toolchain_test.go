@@ -0,0 +1,69 @@
+package toolchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tsuki/cli/internal/manifest"
+)
+
+func TestPathRejectsNonSemver(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	for _, v := range []string{"../../x", "1.2", "v1.2.3", "1.2.3/..", "1.2.3-rc..1"} {
+		if _, err := Path(TsukiCore, v); err == nil {
+			t.Errorf("Path accepted version %q", v)
+		}
+	}
+	for _, v := range []string{"3.1.0", "1.0.0-rc.1"} {
+		if _, err := Path(TsukiCore, v); err != nil {
+			t.Errorf("Path(%q): %v", v, err)
+		}
+	}
+	if _, err := Pins(&manifest.Toolchain{TsukiFlash: "../../x"}); err == nil {
+		t.Error("Pins accepted a version outside the store")
+	}
+}
+
+// mirror lays out a file:// mirror serving release for tool 1.0.0 and
+// returns its URL.
+func mirror(t *testing.T, tool string, release []byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, tool, "1.0.0", fileName(tool, "1.0.0"))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, release, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + dir
+}
+
+func TestInstallChecksSHA256(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	release := []byte("#!/bin/sh\n")
+	url := mirror(t, TsukiFlash, release)
+	sum := sha256.Sum256(release)
+
+	p := Pin{Tool: TsukiFlash, Version: "1.0.0", SHA256: strings.Repeat("0", 64)}
+	if _, err := Install(p, url); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Fatalf("Install with a wrong digest: err = %v", err)
+	}
+	dest, _ := Path(TsukiFlash, "1.0.0")
+	if _, err := os.Stat(dest); err == nil {
+		t.Fatal("a download with a wrong digest was installed")
+	}
+
+	p.SHA256 = strings.ToUpper(hex.EncodeToString(sum[:]))
+	path, err := Install(p, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != string(release) {
+		t.Errorf("installed %q (%v), want the release file", got, err)
+	}
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"os"
+	"strings"
+)
+
+// ── Source snippets ───────────────────────────────────────────────────────────
+
+// DefaultContext is how many lines SourceFrame shows either side of an error.
+const DefaultContext = 2
+
+// tabWidth is the number of columns a tab expands to in snippets.
+const tabWidth = 4
+
+// SourceRef locates an error in a source file. Line and Column are 1-based;
+// a zero Column draws no caret, and a zero EndColumn (exclusive) a single one.
+type SourceRef struct {
+	File      string
+	Line      int
+	Column    int
+	EndColumn int
+}
+
+// Located reports whether ref points at a line of a file, which is what a
+// traceback frame needs.
+func (ref SourceRef) Located() bool {
+	return ref.File != "" && ref.Line > 0
+}
+
+// SourceLines reads up to context lines either side of ref.Line from disk.
+// The pointer line carries ref's columns, adjusted for expanded tabs. It
+// returns nil when the file cannot be read or has no such line.
+func SourceLines(ref SourceRef, context int) []CodeLine {
+	if ref.File == "" || ref.Line <= 0 {
+		return nil
+	}
+	data, err := os.ReadFile(ref.File)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if ref.Line > len(lines) {
+		return nil
+	}
+
+	var out []CodeLine
+	for n := max(1, ref.Line-context); n <= min(len(lines), ref.Line+context); n++ {
+		raw := lines[n-1]
+		cl := CodeLine{Number: n, Text: expandTabs(raw), IsPointer: n == ref.Line}
+		if cl.IsPointer && ref.Column > 0 {
+			cl.Column = visualColumn(raw, ref.Column)
+			if ref.EndColumn > ref.Column {
+				cl.EndColumn = visualColumn(raw, ref.EndColumn)
+			}
+		}
+		out = append(out, cl)
+	}
+	return out
+}
+
+// SourceFrame is a traceback frame for ref showing the surrounding source.
+// When the file cannot be read the frame has a location but no code.
+func SourceFrame(ref SourceRef, fn string) Frame {
+	return Frame{
+		File: ref.File,
+		Line: ref.Line,
+		Func: fn,
+		Code: SourceLines(ref, DefaultContext),
+	}
+}
+
+// expandTabs replaces tabs with spaces up to the next tab stop.
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var sb strings.Builder
+	col := 0
+	for _, r := range s {
+		if r == '\t' {
+			n := tabWidth - col%tabWidth
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		sb.WriteRune(r)
+		col++
+	}
+	return sb.String()
+}
+
+// visualColumn converts a 1-based character column in s to the column it
+// appears at once tabs are expanded.
+func visualColumn(s string, column int) int {
+	col := 0
+	i := 1
+	for _, r := range s {
+		if i >= column {
+			break
+		}
+		if r == '\t' {
+			col += tabWidth - col%tabWidth
+		} else {
+			col++
+		}
+		i++
+	}
+	return col + column - i + 1
+}
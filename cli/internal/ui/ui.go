@@ -137,9 +137,13 @@ type Frame struct {
 
 // CodeLine is one line of source context.
 type CodeLine struct {
-	Number    int
+	Number    int // 0 for lines that are tool output rather than source
 	Text      string
 	IsPointer bool // the line that caused the error (marked with ❱)
+	// Column and EndColumn (1-based, exclusive) underline the error on the
+	// pointer line with carets. Zero Column draws none; zero EndColumn one.
+	Column    int
+	EndColumn int
 }
 
 // Traceback renders a rich-style traceback to stderr, mirroring the style
@@ -160,7 +164,14 @@ type CodeLine struct {
 //	│  │  divisor = 0                                           │
 //	╰────────────────────────────────────────────────────────────╯
 //	ZeroDivisionError: division by zero
+//
+// Without frames — an error with no source location — only the last line
+// is printed.
 func Traceback(errType, errMsg string, frames []Frame) {
+	if len(frames) == 0 {
+		printErrorLine(errType, errMsg)
+		return
+	}
 	w := termWidth()
 	inner := w - 2
 
@@ -192,7 +203,10 @@ func Traceback(errType, errMsg string, frames []Frame) {
 	for i, frame := range frames {
 		_ = i
 		// file + func title line
-		fileStr := ColorTBFile.Sprint(frame.File) + ":" + ColorTBLine.Sprint(fmt.Sprintf("%d", frame.Line))
+		fileStr := ColorTBFile.Sprint(frame.File)
+		if frame.Line > 0 {
+			fileStr += ":" + ColorTBLine.Sprint(fmt.Sprintf("%d", frame.Line))
+		}
 		funcStr := " in " + ColorTBFunc.Sprint(frame.Func)
 		printBorderLine(fileStr + funcStr)
 		printEmpty()
@@ -200,12 +214,20 @@ func Traceback(errType, errMsg string, frames []Frame) {
 		// source lines
 		for _, cl := range frame.Code {
 			lineNum := fmt.Sprintf("%4d", cl.Number)
+			if cl.Number <= 0 {
+				lineNum = "    "
+			}
 			if cl.IsPointer {
 				prefix := ColorTBHigh.Sprint(" ❱ ")
 				numStr := ColorTBHigh.Sprint(lineNum)
 				sep := ColorTBBorder.Sprint(" │ ")
 				code := ColorTBHigh.Sprint(cl.Text)
 				printBorderLine(prefix + numStr + sep + code)
+				if cl.Column > 0 {
+					width := max(1, cl.EndColumn-cl.Column)
+					carets := strings.Repeat(" ", cl.Column-1) + ColorTBHigh.Sprint(strings.Repeat("^", width))
+					printBorderLine("   " + "    " + ColorTBBorder.Sprint(" │ ") + carets)
+				}
 			} else {
 				numStr := ColorMuted.Sprint(lineNum)
 				sep := ColorTBBorder.Sprint(" │ ")
@@ -229,8 +251,11 @@ func Traceback(errType, errMsg string, frames []Frame) {
 	}
 
 	ColorTBBorder.Fprintln(os.Stderr, "╰"+hline(inner, "─")+"╯")
+	printErrorLine(errType, errMsg)
+}
 
-	// error type + message
+// printErrorLine prints the "ErrType: message" line that ends a traceback.
+func printErrorLine(errType, errMsg string) {
 	ColorTBErrType.Fprint(os.Stderr, errType)
 	fmt.Fprint(os.Stderr, ": ")
 	ColorTBErrMsg.Fprintln(os.Stderr, errMsg)
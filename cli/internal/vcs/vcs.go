@@ -0,0 +1,71 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: vcs  —  read git metadata for build stamping
+//
+//  The read helpers degrade to a zero value when git is missing or the
+//  project is not a repository: a build must never fail because of version
+//  control. The write helpers (release tagging) return git's error instead.
+// ─────────────────────────────────────────────────────────────────────────────
+
+package vcs
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Commit returns the full hash of HEAD in dir, or "" outside a git repo.
+func Commit(dir string) string {
+	out, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// Dirty reports whether the work tree in dir has uncommitted changes.
+func Dirty(dir string) bool {
+	out, err := git(dir, "status", "--porcelain")
+	return err == nil && out != ""
+}
+
+// IsRepo reports whether dir is inside a git work tree.
+func IsRepo(dir string) bool {
+	out, err := git(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && out == "true"
+}
+
+// TagExists reports whether tag name exists in dir's repository.
+func TagExists(dir, name string) bool {
+	_, err := git(dir, "rev-parse", "-q", "--verify", "refs/tags/"+name)
+	return err == nil
+}
+
+// CommitFiles commits only the given paths with message, leaving any other
+// staged or unstaged changes alone.
+func CommitFiles(dir, message string, paths ...string) error {
+	if err := run(dir, append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	return run(dir, append([]string{"commit", "-m", message, "--"}, paths...)...)
+}
+
+// Tag creates an annotated tag at HEAD.
+func Tag(dir, name, message string) error {
+	return run(dir, "tag", "-a", name, "-m", message)
+}
+
+// run executes a mutating git command and folds its stderr into the error.
+func run(dir string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
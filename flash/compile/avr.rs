@@ -5,7 +5,8 @@
 //
 //  Pipeline:
 //    1. Discover + compile Arduino core → core.a  (cached, rebuilt only if stale)
-//    2. Compile sketch .cpp files in PARALLEL     (rayon, incremental cache)
+//    2. Compile sketch .cpp files in PARALLEL     (rayon, incremental cache),
+//       listing them in compile_commands.json
 //    3. Link everything → firmware.elf
 //    4. avr-objcopy → firmware.hex  +  firmware.with_bootloader.hex
 //    5. avr-size report
@@ -21,6 +22,7 @@ use crate::boards::Board;
 use crate::error::{FlashError, Result};
 use crate::sdk::{SdkPaths};
 use super::cache::{CacheManifest, obj_path, hash_str};
+use super::compiledb::{self, Entry};
 use super::{CompileRequest, CompileResult};
 
 pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<CompileResult> {
@@ -47,7 +49,7 @@ pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<Compil
         format!("-DARDUINO={}", arduino_ver),
         format!("-D{}", board_define),
         "-DARDUINO_ARCH_AVR".into(),
-        "-Os".into(),
+        format!("-{}", req.optimize.trim_start_matches('-')),
         "-w".into(),
         "-ffunction-sections".into(),
         "-fdata-sections".into(),
@@ -59,6 +61,7 @@ pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<Compil
 
     // Add extra include dirs (external libraries)
     let mut includes: Vec<String> = common_flags.clone();
+    includes.extend(req.extra_flags.iter().cloned());
     for lib_dir in &req.lib_include_dirs {
         includes.push(format!("-I{}", lib_dir.display()));
     }
@@ -79,7 +82,7 @@ pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<Compil
 
     // ── Flags fingerprint for incremental cache ───────────────────────────
     let flags_sig = hash_str(&format!("{:?}{:?}{:?}", includes, cflags, cxxflags));
-    let core_sig  = hash_str(&format!("core{}{}", mcu, sdk.sdk_version));
+    let core_sig  = hash_str(&format!("core{}{}{}{:?}", mcu, sdk.sdk_version, req.optimize, req.extra_flags));
 
     // ── Step 1: Build core.a ──────────────────────────────────────────────
     let core_dir  = req.build_dir.join("core");
@@ -101,6 +104,16 @@ pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<Compil
         )));
     }
 
+    // ── Compilation database, for editors ────────────────────────────────
+    let db: Vec<Entry> = sources.iter().map(|src| {
+        let is_c = src.extension().and_then(|e| e.to_str()) == Some("c");
+        let mut flags = includes.clone();
+        let lang: &[&str] = if is_c { &cflags } else { &cxxflags };
+        flags.extend(lang.iter().map(|f| f.to_string()));
+        Entry::new(&req.sketch_dir, if is_c { cc.as_str() } else { cxx.as_str() }, &flags, src, &obj_path(&sketch_dir, src))
+    }).collect();
+    let _ = compiledb::write(&req.build_dir, &db);
+
     // Parallel compilation with error collection
     let errors: Mutex<Vec<String>> = Mutex::new(Vec::new());
     let mut manifest = CacheManifest::load(&sketch_dir);
@@ -164,7 +177,7 @@ pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<Compil
 
     let mut link_cmd = Command::new(&cc);
     link_cmd
-        .arg("-w").arg("-Os").arg("-g").arg("-flto")
+        .arg("-w").arg(format!("-{}", req.optimize.trim_start_matches('-'))).arg("-g").arg("-flto")
         .arg("-fuse-linker-plugin").arg("-Wl,--gc-sections")
         .arg(format!("-mmcu={}", mcu));
 
@@ -0,0 +1,51 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki-flash :: compile :: compiledb
+//
+//  Writes <build_dir>/compile_commands.json — the exact compiler invocation
+//  of every sketch source — so clangd and other editors resolve includes
+//  and defines as the real compile does. arduino-cli writes the same file
+//  into its build path.
+//
+//  Sources served from the incremental cache are listed too: the database
+//  describes how each file IS compiled, not which ones ran this time.
+// ─────────────────────────────────────────────────────────────────────────────
+
+use std::path::Path;
+use serde::Serialize;
+
+pub const FILE_NAME: &str = "compile_commands.json";
+
+/// One entry of a JSON compilation database.
+#[derive(Debug, Serialize)]
+pub struct Entry {
+    pub directory: String,
+    pub file:      String,
+    pub arguments: Vec<String>,
+    pub output:    String,
+}
+
+impl Entry {
+    /// The entry for compiling `src` into `obj` with `compiler` and `flags`.
+    pub fn new(dir: &Path, compiler: &str, flags: &[String], src: &Path, obj: &Path) -> Self {
+        let mut arguments = Vec::with_capacity(flags.len() + 5);
+        arguments.push(compiler.to_owned());
+        arguments.extend(flags.iter().cloned());
+        arguments.push("-c".into());
+        arguments.push(src.display().to_string());
+        arguments.push("-o".into());
+        arguments.push(obj.display().to_string());
+        Entry {
+            directory: dir.display().to_string(),
+            file:      src.display().to_string(),
+            arguments,
+            output:    obj.display().to_string(),
+        }
+    }
+}
+
+/// Write the database into `build_dir`.
+pub fn write(build_dir: &Path, entries: &[Entry]) -> std::io::Result<()> {
+    let json = serde_json::to_string_pretty(entries)
+        .map_err(|e| std::io::Error::new(std::io::ErrorKind::Other, e))?;
+    std::fs::write(build_dir.join(FILE_NAME), json)
+}
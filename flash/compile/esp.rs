@@ -4,7 +4,8 @@
 //  Compiles Arduino ESP32 / ESP8266 sketches using the Espressif toolchain.
 //
 //  Pipeline:
-//    1. Compile sketch sources  (parallel, incremental cache)
+//    1. Compile sketch sources  (parallel, incremental cache), listing them
+//       in compile_commands.json
 //    2. Link → firmware.elf
 //    3. esptool.py → firmware.bin  +  firmware.hex (for consistency)
 // ─────────────────────────────────────────────────────────────────────────────
@@ -19,6 +20,7 @@ use crate::boards::{Board, Toolchain};
 use crate::error::{FlashError, Result};
 use crate::sdk::SdkPaths;
 use super::cache::{CacheManifest, hash_str, obj_path};
+use super::compiledb::{self, Entry};
 use super::{CompileRequest, CompileResult};
 
 pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<CompileResult> {
@@ -48,7 +50,7 @@ pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<Compil
         let mut f = vec![
             format!("-DF_CPU={}L", board.f_cpu()),
             "-DARDUINO=10819".into(),
-            "-Os".into(), "-w".into(),
+            format!("-{}", req.optimize.trim_start_matches('-')), "-w".into(),
             "-ffunction-sections".into(), "-fdata-sections".into(),
             "-Wno-error=narrowing".into(),
             "-MMD".into(),
@@ -64,6 +66,7 @@ pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<Compil
         for flag in arch_flags {
             f.push(flag.to_string());
         }
+        f.extend(req.extra_flags.iter().cloned());
         f
     };
 
@@ -81,6 +84,15 @@ pub fn run(req: &CompileRequest, board: &Board, sdk: &SdkPaths) -> Result<Compil
         return Err(FlashError::Other("No source files found".into()));
     }
 
+    // ── Compilation database, for editors ────────────────────────────────
+    let db: Vec<Entry> = sources.iter().map(|src| {
+        let is_c = src.extension().and_then(|e| e.to_str()) == Some("c");
+        let mut flags = common_flags.clone();
+        if !is_c { flags.extend(cxxflags.iter().map(|f| f.to_string())); }
+        Entry::new(&req.sketch_dir, if is_c { cc.as_str() } else { cxx.as_str() }, &flags, src, &obj_path(&sketch_obj_dir, src))
+    }).collect();
+    let _ = compiledb::write(&req.build_dir, &db);
+
     let errors: Mutex<Vec<String>> = Mutex::new(Vec::new());
     let mut manifest = CacheManifest::load(&sketch_obj_dir);
 
@@ -4,6 +4,7 @@
 
 pub mod avr;
 pub mod cache;
+pub mod compiledb;
 pub mod esp;
 
 use std::path::PathBuf;
@@ -22,6 +23,10 @@ pub struct CompileRequest {
     pub project_name:     String,
     /// C++ standard string, e.g. "c++11".
     pub cpp_std:          String,
+    /// Optimisation level without the dash, e.g. "Os", "O2".
+    pub optimize:         String,
+    /// Extra compiler flags, passed to every C/C++ compile (core included).
+    pub extra_flags:      Vec<String>,
     /// Extra -I dirs (tsuki libraries, passed via --include).
     pub lib_include_dirs: Vec<PathBuf>,
     /// When true the tsuki-modules SDK store (~/.tsuki/modules) is preferred
@@ -79,6 +84,8 @@ fn augment_lib_includes(req: &CompileRequest) -> CompileRequest {
         build_dir:        req.build_dir.clone(),
         project_name:     req.project_name.clone(),
         cpp_std:          req.cpp_std.clone(),
+        optimize:         req.optimize.clone(),
+        extra_flags:      req.extra_flags.clone(),
         lib_include_dirs: dirs,
         use_modules:      req.use_modules,
         verbose:          req.verbose,
@@ -81,7 +81,14 @@ pub fn verify(hex: &Path, port: &str, board: &Board) -> Result<()> {
 // ─────────────────────────────────────────────────────────────────────────────
 
 fn find_avrdude() -> String {
-    // 1. Arduino CLI cache location
+    // 1. tsuki-modules store — `modules install avr` brings avrdude with the core
+    if let Ok(root) = crate::modules::modules_root() {
+        if let Ok(path) = find_in_package_tools(&root, "avrdude") {
+            return path;
+        }
+    }
+
+    // 2. Arduino CLI cache location
     let home = std::env::var("HOME").unwrap_or_default();
     let candidates = [
         format!("{}/.arduino15/packages/arduino/tools/avrdude/6.3.0-arduino17/bin/avrdude", home),
@@ -98,7 +105,7 @@ fn find_avrdude() -> String {
     }
 
     // Try arduino15 glob-style search
-    if let Ok(path) = find_in_arduino15_tools(&home, "avrdude") {
+    if let Ok(path) = find_in_package_tools(&Path::new(&home).join(".arduino15"), "avrdude") {
         return path;
     }
 
@@ -128,9 +135,11 @@ fn avrdude_conf(avrdude_bin: &str) -> String {
     "/etc/avrdude.conf".to_owned()
 }
 
-fn find_in_arduino15_tools(home: &str, tool: &str) -> std::result::Result<String, ()> {
-    let tools_dir = std::path::Path::new(home)
-        .join(".arduino15/packages/arduino/tools")
+/// Newest `<base>/packages/arduino/tools/<tool>/<version>/bin/<tool>`, where
+/// base is an .arduino15 directory or the tsuki-modules store.
+fn find_in_package_tools(base: &Path, tool: &str) -> std::result::Result<String, ()> {
+    let tools_dir = base
+        .join("packages/arduino/tools")
         .join(tool);
 
     if !tools_dir.is_dir() { return Err(()); }
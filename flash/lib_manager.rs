@@ -17,9 +17,10 @@
 //
 //  Subcommands exposed via this module:
 //    tsuki-flash lib install <name> [--version x.y.z]
-//    tsuki-flash lib search  <query>
-//    tsuki-flash lib list
+//    tsuki-flash lib search  <query> [--json]
+//    tsuki-flash lib list    [--json]
 //    tsuki-flash lib info    <name>
+//    tsuki-flash lib remove  <name>
 // ─────────────────────────────────────────────────────────────────────────────
 
 use std::fs;
@@ -88,6 +89,22 @@ pub struct InstalledManifest {
     pub installed_at: u64, // unix timestamp
 }
 
+/// A library in `--json` output — the shape `tsuki lib` reads.
+#[derive(Debug, Serialize)]
+struct LibrarySummary<'a> {
+    name:    &'a str,
+    version: &'a str,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    description: Option<&'a str>,
+}
+
+fn print_json(libs: &[LibrarySummary]) -> Result<()> {
+    let json = serde_json::to_string(libs)
+        .map_err(|e| FlashError::Other(e.to_string()))?;
+    println!("{}", json);
+    Ok(())
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 //  Public API
 // ─────────────────────────────────────────────────────────────────────────────
@@ -199,7 +216,7 @@ fn install_inner(
 
 /// Search the registry for libraries matching `query` (case-insensitive
 /// substring match against name, sentence, category).
-pub fn search(query: &str, verbose: bool) -> Result<()> {
+pub fn search(query: &str, verbose: bool, json: bool) -> Result<()> {
     let index = load_index(verbose)?;
     let q = query.to_lowercase();
 
@@ -224,6 +241,17 @@ pub fn search(query: &str, verbose: bool) -> Result<()> {
         }
     }
 
+    if json {
+        let summaries: Vec<LibrarySummary> = hits.iter()
+            .map(|lib| LibrarySummary {
+                name:        &lib.name,
+                version:     &lib.version,
+                description: lib.sentence.as_deref(),
+            })
+            .collect();
+        return print_json(&summaries);
+    }
+
     if hits.is_empty() {
         println!("{} No libraries found matching '{}'", "!".yellow(), query);
         return Ok(());
@@ -253,10 +281,13 @@ pub fn search(query: &str, verbose: bool) -> Result<()> {
 }
 
 /// List all installed libraries (scans the libs_root directory).
-pub fn list() -> Result<()> {
+pub fn list(json: bool) -> Result<()> {
     let libs_root = libs_root()?;
 
     if !libs_root.exists() {
+        if json {
+            return print_json(&[]);
+        }
         println!("{} No libraries installed yet.", "!".yellow());
         println!(
             "  Install one with: {}",
@@ -282,13 +313,20 @@ pub fn list() -> Result<()> {
         }
     }
 
+    entries.sort_by(|a, b| a.0.cmp(&b.0));
+
+    if json {
+        let summaries: Vec<LibrarySummary> = entries.iter()
+            .map(|(name, version)| LibrarySummary { name, version, description: None })
+            .collect();
+        return print_json(&summaries);
+    }
+
     if entries.is_empty() {
         println!("{} No libraries installed.", "!".yellow());
         return Ok(());
     }
 
-    entries.sort_by(|a, b| a.0.cmp(&b.0));
-
     println!("{:<40}  {}", "LIBRARY".bold().underline(), "VERSION".bold().underline());
     println!("{}", "─".repeat(55).dimmed());
 
@@ -301,6 +339,36 @@ pub fn list() -> Result<()> {
     Ok(())
 }
 
+/// Uninstall a library by deleting `<libs_root>/<name>/`. The name is
+/// matched case-insensitively, like `install` resolves it.
+pub fn remove(name: &str) -> Result<()> {
+    let libs_root = libs_root()?;
+    let lower = name.to_lowercase();
+
+    let found = fs::read_dir(&libs_root).ok()
+        .into_iter()
+        .flatten()
+        .flatten()
+        .map(|e| e.path())
+        .find(|p| p.is_dir() && p.file_name()
+            .map(|n| n.to_string_lossy().to_lowercase() == lower)
+            .unwrap_or(false));
+
+    let dir = match found {
+        Some(dir) => dir,
+        None => {
+            return Err(FlashError::Other(format!(
+                "Library '{}' is not installed in {}",
+                name, libs_root.display()
+            )));
+        }
+    };
+
+    fs::remove_dir_all(&dir)?;
+    println!("{}  Removed {}", "✓".green().bold(), name.bold());
+    Ok(())
+}
+
 /// Print detailed info about a library (latest version).
 pub fn info(name: &str, verbose: bool) -> Result<()> {
     let index = load_index(verbose)?;
@@ -383,7 +451,8 @@ fn load_index(verbose: bool) -> Result<LibraryIndex> {
     }
 
     // (Re-)download the index.
-    println!("{} Fetching Arduino library index…", "→".cyan());
+    // stderr, so that `--json` output stays parseable.
+    eprintln!("{} Fetching Arduino library index…", "→".cyan());
 
     let resp = ureq::get(REGISTRY_URL)
         .call()
@@ -87,6 +87,14 @@ struct CompileArgs {
     #[arg(long, default_value = "c++11")]
     cpp_std: String,
 
+    /// Optimisation level, e.g. Os, O2 (passed as -Os, -O2)
+    #[arg(long, default_value = "Os")]
+    optimize: String,
+
+    /// Extra compiler flag; repeat for several (--flag=-DDEBUG)
+    #[arg(long = "flag", allow_hyphen_values = true)]
+    flags: Vec<String>,
+
     /// Extra include directories
     #[arg(long, value_delimiter = ',')]
     include: Vec<PathBuf>,
@@ -138,6 +146,12 @@ struct RunArgs {
     #[arg(long, default_value = "c++11")]
     cpp_std: String,
 
+    #[arg(long, default_value = "Os")]
+    optimize: String,
+
+    #[arg(long = "flag", allow_hyphen_values = true)]
+    flags: Vec<String>,
+
     #[arg(long, value_delimiter = ',')]
     include: Vec<PathBuf>,
 
@@ -163,9 +177,20 @@ enum LibCmd {
         #[arg(long)]
         version: Option<String>,
     },
-    Search { query: String },
-    List,
+    Search {
+        query: String,
+        /// Print the results as JSON
+        #[arg(long)]
+        json: bool,
+    },
+    List {
+        /// Print the installed libraries as JSON
+        #[arg(long)]
+        json: bool,
+    },
     Info { name: String },
+    /// Uninstall a library
+    Remove { name: String },
     Update,
 }
 
@@ -180,11 +205,18 @@ struct ModulesArgs {
 #[derive(Subcommand)]
 enum ModulesCmd {
     /// Download + install an Arduino SDK core (avr | esp32 | esp8266 | sam | rp2040)
-    Install { arch: String },
+    Install {
+        arch: String,
+        /// Exact core version (default: latest)
+        #[arg(long)]
+        version: Option<String>,
+    },
     /// List installed cores
     List,
     /// Force-refresh the package index cache
     Update,
+    /// Delete an installed core
+    Remove { arch: String },
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -242,6 +274,8 @@ fn cmd_compile(args: CompileArgs, verbose: bool, quiet: bool) -> Result<()> {
         build_dir:        args.build_dir,
         project_name:     name,
         cpp_std:          args.cpp_std,
+        optimize:         args.optimize,
+        extra_flags:      args.flags,
         lib_include_dirs: args.include,
         use_modules:      args.use_modules,
         verbose,
@@ -310,6 +344,8 @@ fn cmd_run(args: RunArgs, verbose: bool, quiet: bool) -> Result<()> {
         build_dir:        args.build_dir.clone(),
         project_name:     name.clone(),
         cpp_std:          args.cpp_std,
+        optimize:         args.optimize,
+        extra_flags:      args.flags,
         lib_include_dirs: args.include,
         use_modules:      args.use_modules,
         verbose,
@@ -407,9 +443,10 @@ fn cmd_sdk_info(board_id: &str) -> Result<()> {
 
 fn cmd_modules(args: ModulesArgs, verbose: bool) -> Result<()> {
     match args.command {
-        ModulesCmd::Install { arch } => modules::install(&arch, verbose),
+        ModulesCmd::Install { arch, version } => modules::install(&arch, version.as_deref(), verbose),
         ModulesCmd::List             => modules::list(),
         ModulesCmd::Update           => modules::update(verbose),
+        ModulesCmd::Remove { arch }  => modules::remove(&arch),
     }
 }
 
@@ -427,9 +464,10 @@ fn cmd_lib(args: LibArgs, verbose: bool) -> Result<()> {
             }
             Ok(())
         }
-        LibCmd::Search { query } => lib_manager::search(&query, verbose),
-        LibCmd::List              => lib_manager::list(),
-        LibCmd::Info { name }     => lib_manager::info(&name, verbose),
+        LibCmd::Search { query, json } => lib_manager::search(&query, verbose, json),
+        LibCmd::List { json }           => lib_manager::list(json),
+        LibCmd::Info { name }           => lib_manager::info(&name, verbose),
+        LibCmd::Remove { name }         => lib_manager::remove(&name),
         LibCmd::Update => {
             if let Ok(home) = std::env::var("HOME").or_else(|_| std::env::var("USERPROFILE")) {
                 let cache = PathBuf::from(home)
@@ -438,7 +476,7 @@ fn cmd_lib(args: LibArgs, verbose: bool) -> Result<()> {
                 if cache.exists() { let _ = std::fs::remove_file(&cache); }
             }
             println!("{} Refreshing library index…", "→".cyan());
-            lib_manager::search("", verbose)?;
+            lib_manager::search("", verbose, false)?;
             println!("{} Library index updated.", "✓".green().bold());
             Ok(())
         }
@@ -487,16 +525,9 @@ fn ensure_modules_ready(use_modules: bool, arch: &str) -> Result<()> {
         }
         _ => {
             if modules::is_installed(arch) { return Ok(()); }
-            eprintln!(
-                "{} Core for arch '{}' is not installed in tsuki-modules.",
-                "✗".red().bold(), arch
-            );
-            eprintln!("  Run: {}", format!("tsuki-flash modules install {}", arch).bold());
-            Err(FlashError::SdkNotFound {
-                arch: arch.into(),
-                path: "~/.tsuki/modules".into(),
-                pkg:  format!("tsuki-flash modules install {}", arch),
-            })
+            // Same download `tsuki-flash modules install <arch>` performs;
+            // its progress lines go to stdout.
+            modules::install(arch, None, false)
         }
     }
 }
@@ -17,8 +17,12 @@
 //
 //  Subcommands:
 //    tsuki-flash modules install avr   → downloads arduino:avr + avr-gcc
+//    tsuki-flash modules install avr --version 1.8.6
+//                                      → that release only; other versions of
+//                                        the core are removed so sdk.rs picks it
 //    tsuki-flash modules list          → lists installed cores
 //    tsuki-flash modules update        → refreshes cached package index
+//    tsuki-flash modules remove esp32  → deletes a core (toolchains are kept)
 //
 //  Submodules:
 //    avr   → fast AVR compile pipeline that uses the tsuki-modules SDK paths
@@ -129,12 +133,13 @@ pub fn is_installed(arch: &str) -> bool {
 //  Public: install
 // ─────────────────────────────────────────────────────────────────────────────
 
-/// Download and install the Arduino core + toolchain for `arch`.
+/// Download and install the Arduino core + toolchain for `arch`: the latest
+/// release, or exactly `version` when given.
 ///
 /// Downloads are parallel (rayon).  Re-installing an already-present versioned
 /// directory is a no-op — the check is a single `Path::exists()`, so repeated
 /// calls are near-instant.
-pub fn install(arch: &str, verbose: bool) -> Result<()> {
+pub fn install(arch: &str, version: Option<&str>, verbose: bool) -> Result<()> {
     let root = modules_root()?;
     fs::create_dir_all(&root)?;
 
@@ -143,13 +148,13 @@ pub fn install(arch: &str, verbose: bool) -> Result<()> {
 
     let index   = load_index(verbose)?;
     let (vendor, hw_arch, pkg_name) = arch_to_package(arch)?;
-    let (_pkg, platform) = find_latest_platform(&index, pkg_name, hw_arch)?;
+    let (_pkg, platform) = find_platform(&index, pkg_name, hw_arch, version)?;
 
     // ── Platform dir ─────────────────────────────────────────────────────
-    let platform_dir = root
+    let hw_dir = root
         .join("packages").join(vendor)
-        .join("hardware").join(hw_arch)
-        .join(&platform.version);
+        .join("hardware").join(hw_arch);
+    let platform_dir = hw_dir.join(&platform.version);
     let core_needed = !platform_dir.exists();
 
     // ── Tools needed ─────────────────────────────────────────────────────
@@ -176,7 +181,7 @@ pub fn install(arch: &str, verbose: bool) -> Result<()> {
     if !core_needed && tools_needed.is_empty() {
         println!("  {} {} {} already up to date",
             "•".dimmed(), arch.bold(), platform.version.dimmed());
-        return write_installed_manifest(&root, arch, &platform.version);
+        return finish_install(&root, arch, &hw_dir, &platform.version, version.is_some());
     }
 
     // ── Build flat work list then download everything in parallel ─────────
@@ -230,7 +235,7 @@ pub fn install(arch: &str, verbose: bool) -> Result<()> {
         )));
     }
 
-    write_installed_manifest(&root, arch, &platform.version)?;
+    finish_install(&root, arch, &hw_dir, &platform.version, version.is_some())?;
 
     println!(
         "\n  {} {} {} ready  ({})",
@@ -298,6 +303,31 @@ pub fn update(verbose: bool) -> Result<()> {
     Ok(())
 }
 
+// ─────────────────────────────────────────────────────────────────────────────
+//  Public: remove
+// ─────────────────────────────────────────────────────────────────────────────
+
+/// Delete every installed version of the core for `arch` and its manifest.
+/// Toolchains stay: other cores may share them, and they are versioned.
+pub fn remove(arch: &str) -> Result<()> {
+    let root = modules_root()?;
+    let (vendor, hw_arch, _) = arch_to_package(arch)?;
+    let manifest = root.join("installed").join(format!("{}.json", arch));
+    let core_dir = root.join("packages").join(vendor).join("hardware").join(hw_arch);
+
+    if !manifest.exists() && !core_dir.exists() {
+        return Err(FlashError::Other(format!("The {} core is not installed", arch)));
+    }
+    if core_dir.exists() {
+        fs::remove_dir_all(&core_dir)?;
+    }
+    if manifest.exists() {
+        fs::remove_file(&manifest)?;
+    }
+    println!("{} Removed {} core", "✓".green().bold(), arch.bold());
+    Ok(())
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 //  Internal: index loading + caching
 // ─────────────────────────────────────────────────────────────────────────────
@@ -477,10 +507,43 @@ pub fn arch_to_package(arch: &str) -> Result<(&'static str, &'static str, &'stat
     }
 }
 
-fn find_latest_platform<'a>(
+/// Delete every version directory in `hw_dir` other than `keep`.
+/// Record `version` as the installed core for `arch`. sdk.rs compiles
+/// against the newest version on disk, so a pinned version must be the only
+/// one — but the others are removed only once it is there: a failed
+/// download must not leave the user with no core at all.
+fn finish_install(root: &Path, arch: &str, hw_dir: &Path, version: &str, pinned: bool) -> Result<()> {
+    if pinned {
+        let platform_dir = hw_dir.join(version);
+        if !platform_dir.join("cores").join("arduino").is_dir() {
+            return Err(FlashError::Other(format!(
+                "{} core {} is incomplete in {} — other versions were kept",
+                arch, version, platform_dir.display()
+            )));
+        }
+        remove_other_versions(hw_dir, version)?;
+    }
+    write_installed_manifest(root, arch, version)
+}
+
+fn remove_other_versions(hw_dir: &Path, keep: &str) -> Result<()> {
+    if !hw_dir.exists() {
+        return Ok(());
+    }
+    for entry in fs::read_dir(hw_dir)?.flatten() {
+        if entry.path().is_dir() && entry.file_name().to_string_lossy() != keep {
+            fs::remove_dir_all(entry.path())?;
+        }
+    }
+    Ok(())
+}
+
+/// The platform for `hw_arch` at `version`, or the latest one.
+fn find_platform<'a>(
     index: &'a PackageIndex,
     pkg_name: &str,
     hw_arch: &str,
+    version: Option<&str>,
 ) -> Result<(&'a IndexPackage, &'a Platform)> {
     let pkg = index.packages.iter()
         .find(|p| p.name.to_lowercase() == pkg_name.to_lowercase())
@@ -488,9 +551,15 @@ fn find_latest_platform<'a>(
 
     let mut platforms: Vec<&Platform> = pkg.platforms.iter()
         .filter(|p| p.architecture == hw_arch)
+        .filter(|p| version.map_or(true, |v| p.version == v))
         .collect();
 
     if platforms.is_empty() {
+        if let Some(v) = version {
+            return Err(FlashError::Other(format!(
+                "No {} {} in package '{}'", hw_arch, v, pkg_name
+            )));
+        }
         return Err(FlashError::Other(format!(
             "No platform for arch '{}' in package '{}'", hw_arch, pkg_name
         )));
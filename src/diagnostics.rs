@@ -0,0 +1,138 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: diagnostics
+//  Machine-readable diagnostics for `--diagnostics=json`.
+//
+//  The CLI (and any IDE integration) reads one JSON document from stderr
+//  instead of scraping the human-formatted output:
+//
+//      {
+//        "version": 1,
+//        "diagnostics": [{
+//          "severity": "error",
+//          "code":     "parse",
+//          "file":     "src/main.go",
+//          "range":    { "start": { "line": 3, "column": 5 },
+//                        "end":   { "line": 3, "column": 6 } },
+//          "message":  "expected `)`",
+//          "notes":    [],
+//          "fixes":    []
+//        }]
+//      }
+//
+//  Lines and columns are 1-based; `end` is exclusive. Bump VERSION on any
+//  incompatible change — consumers refuse documents newer than they know.
+// ─────────────────────────────────────────────────────────────────────────────
+
+use serde::Serialize;
+
+use crate::error::{tsukiError, Span};
+
+/// Version of the JSON diagnostics document.
+pub const VERSION: u32 = 1;
+
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "lowercase")]
+pub enum Severity {
+    Error,
+    Warning,
+    Note,
+    Help,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Position {
+    pub line:   u32,
+    pub column: u32,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Range {
+    pub start: Position,
+    pub end:   Position,
+}
+
+/// Secondary information attached to a diagnostic.
+#[derive(Debug, Clone, Serialize)]
+pub struct Note {
+    pub message: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub file:    Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub range:   Option<Range>,
+}
+
+/// A suggested change that resolves the diagnostic.
+#[derive(Debug, Clone, Serialize)]
+pub struct Fix {
+    pub message: String,
+    pub edits:   Vec<Edit>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Edit {
+    pub file:     String,
+    pub range:    Range,
+    pub new_text: String,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Diagnostic {
+    pub severity: Severity,
+    pub code:     String,
+    pub file:     String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub range:    Option<Range>,
+    pub message:  String,
+    pub notes:    Vec<Note>,
+    pub fixes:    Vec<Fix>,
+}
+
+#[derive(Debug, Serialize)]
+struct Document<'a> {
+    version:     u32,
+    diagnostics: &'a [Diagnostic],
+}
+
+impl Range {
+    /// A one-character range at `span`.
+    pub fn at(span: &Span) -> Self {
+        Self {
+            start: Position { line: span.line, column: span.col },
+            end:   Position { line: span.line, column: span.col + 1 },
+        }
+    }
+}
+
+impl Diagnostic {
+    /// Convert a pipeline error into a diagnostic. `file` is used when the
+    /// error carries no span of its own.
+    pub fn from_error(err: &tsukiError, file: &str) -> Self {
+        let (code, message) = match err {
+            tsukiError::Lex   { msg, .. } => ("lex",     msg.clone()),
+            tsukiError::Parse { msg, .. } => ("parse",   msg.clone()),
+            tsukiError::Type  { msg, .. } => ("type",    msg.clone()),
+            tsukiError::Codegen(msg)      => ("codegen", msg.clone()),
+            tsukiError::Io(e)             => ("io",      e.to_string()),
+            tsukiError::Json(e)           => ("json",    e.to_string()),
+            tsukiError::Other(msg)        => ("other",   msg.clone()),
+        };
+        let span = err.span().filter(|s| s.line > 0);
+        Self {
+            severity: Severity::Error,
+            code:     code.into(),
+            file:     span.filter(|s| !s.file.is_empty())
+                          .map(|s| s.file.clone())
+                          .unwrap_or_else(|| file.to_owned()),
+            range:    span.map(Range::at),
+            message,
+            notes:    Vec::new(),
+            fixes:    Vec::new(),
+        }
+    }
+}
+
+/// Serialize diagnostics as a single-line JSON document.
+pub fn to_json(diagnostics: &[Diagnostic]) -> String {
+    serde_json::to_string(&Document { version: VERSION, diagnostics })
+        .unwrap_or_else(|_| format!("{{\"version\":{},\"diagnostics\":[]}}", VERSION))
+}
@@ -2,13 +2,37 @@
 //  tsuki_core  —  public library API  (updated for external libs)
 // ─────────────────────────────────────────────────────────────────────────────
 
+pub mod diagnostics;
 pub mod error;
 pub mod lexer;
 pub mod parser;
 pub mod runtime;
+pub mod server;
 pub mod transpiler;
 
 pub use error::{tsukiError, Result, Span};
+
+/// Features this core supports, reported by `--capabilities` and the
+/// server's `version` method. The CLI checks these before passing flags,
+/// so add a name whenever a flag or protocol is added.
+pub const CAPABILITIES: &[&str] = &[
+    "check",
+    "source-map",
+    "libs-dir",
+    "packages",
+    "diagnostics-json",
+    "serve",
+    "testing",
+];
+
+/// JSON document printed by `tsuki-core --capabilities`.
+pub fn capabilities_json() -> String {
+    serde_json::json!({
+        "version":      env!("CARGO_PKG_VERSION"),
+        "diagnostics":  diagnostics::VERSION,
+        "capabilities": CAPABILITIES,
+    }).to_string()
+}
 pub use transpiler::TranspileConfig;
 pub use runtime::{Board, Runtime};
 pub use runtime::pkg_loader::{LibManifest, load_from_str as load_lib_from_str};
@@ -6,20 +6,37 @@
 //  New flags:
 //    --libs-dir <path>        root directory of installed tsukilib packages
 //    --packages ws2812,dht    comma-separated package names to load
+//    --diagnostics=json       report diagnostics as JSON on stderr
+//    --serve                  JSON-RPC over stdin/stdout (see server.rs)
+//    --capabilities           print version and supported features as JSON
 // ─────────────────────────────────────────────────────────────────────────────
 
 use std::path::PathBuf;
 use tsuki_core::{Pipeline, PipelineOptions, TranspileConfig, Board};
+use tsuki_core::diagnostics::{self, Diagnostic};
 use tsuki_core::pkg_manager;
 use tsuki_core::pkg_manager::default_libs_dir;
 
 fn main() {
     let args: Vec<String> = std::env::args().collect();
 
+    if args.iter().any(|a| a == "--capabilities") {
+        println!("{}", tsuki_core::capabilities_json());
+        return;
+    }
     if args.iter().any(|a| a == "--version" || a == "-V") {
         println!("tsuki {}", env!("CARGO_PKG_VERSION"));
         return;
     }
+    if args.iter().any(|a| a == "--serve") {
+        let stdin  = std::io::stdin();
+        let stdout = std::io::stdout();
+        if let Err(e) = tsuki_core::server::serve(stdin.lock(), stdout.lock()) {
+            eprintln!("error: serve: {}", e);
+            std::process::exit(1);
+        }
+        return;
+    }
     if args.iter().any(|a| a == "--help" || a == "-h") || args.len() < 2 {
         print_help();
         return;
@@ -45,6 +62,8 @@ fn main() {
     let board      = flag_value(&args, "--board").unwrap_or_else(|| "uno".into());
     let source_map = args.iter().any(|a| a == "--source-map");
     let check_only = args.iter().any(|a| a == "--check");
+    let json_diags = args.iter().any(|a| a == "--diagnostics=json")
+        || flag_value(&args, "--diagnostics").as_deref() == Some("json");
 
     // External library flags
     let libs_dir   = flag_value(&args, "--libs-dir").map(PathBuf::from);
@@ -62,7 +81,12 @@ fn main() {
     let source = match std::fs::read_to_string(&input) {
         Ok(s)  => s,
         Err(e) => {
-            eprintln!("error: cannot read {}: {}", input.display(), e);
+            if json_diags {
+                let err = tsuki_core::tsukiError::other(format!("cannot read {}: {}", input.display(), e));
+                report_error(&err, "", &input.to_string_lossy(), true);
+            } else {
+                eprintln!("error: cannot read {}: {}", input.display(), e);
+            }
             std::process::exit(1);
         }
     };
@@ -80,11 +104,15 @@ fn main() {
     if check_only {
         match pipeline.run(&source, &filename) {
             Ok(_)  => {
-                eprintln!("ok  {} — no errors", input.display());
+                if json_diags {
+                    eprintln!("{}", diagnostics::to_json(&[]));
+                } else {
+                    eprintln!("ok  {} — no errors", input.display());
+                }
                 std::process::exit(0);
             }
             Err(e) => {
-                eprintln!("{}", tsuki_core::pretty_error(&e, &source));
+                report_error(&e, &source, &filename, json_diags);
                 std::process::exit(1);
             }
         }
@@ -98,18 +126,31 @@ fn main() {
                         eprintln!("error: cannot write {}: {}", path.display(), e);
                         std::process::exit(1);
                     }
-                    eprintln!("ok  {}", path.display());
+                    if json_diags {
+                        eprintln!("{}", diagnostics::to_json(&[]));
+                    } else {
+                        eprintln!("ok  {}", path.display());
+                    }
                 }
                 None => print!("{}", cpp),
             }
         }
         Err(e) => {
-            eprintln!("{}", tsuki_core::pretty_error(&e, &source));
+            report_error(&e, &source, &filename, json_diags);
             std::process::exit(1);
         }
     }
 }
 
+/// Print a pipeline error either as a pretty diagnostic or as a JSON document.
+fn report_error(e: &tsuki_core::tsukiError, source: &str, filename: &str, json: bool) {
+    if json {
+        eprintln!("{}", diagnostics::to_json(&[Diagnostic::from_error(e, filename)]));
+    } else {
+        eprintln!("{}", tsuki_core::pretty_error(e, source));
+    }
+}
+
 // ── pkg subcommand handler ────────────────────────────────────────────────────
 
 fn handle_pkg(args: &[String]) {
@@ -272,6 +313,9 @@ FLAGS:
     --check                Validate source only (no output produced)
     --libs-dir <path>      Root directory of installed tsukilib packages
     --packages <n,...>     Comma-separated package names to load from libs-dir
+    --diagnostics=json     Report diagnostics as a JSON document on stderr
+    --serve                Transpile batches of files over JSON-RPC on stdin/stdout
+    --capabilities         Print version and supported features as JSON
     --version              Print version
     --help                 Print this help
 
@@ -207,6 +207,24 @@ pub enum Stmt {
     Block(Block),
 }
 
+impl Stmt {
+    /// Where the statement starts in the Go source.
+    pub fn span(&self) -> &Span {
+        match self {
+            Stmt::VarDecl   { span, .. } | Stmt::ConstDecl { span, .. }
+            | Stmt::ShortDecl { span, .. } | Stmt::Assign  { span, .. }
+            | Stmt::Inc       { span, .. } | Stmt::Dec     { span, .. }
+            | Stmt::Return    { span, .. } | Stmt::Break   { span, .. }
+            | Stmt::Continue  { span, .. } | Stmt::Goto    { span, .. }
+            | Stmt::Label     { span, .. } | Stmt::If      { span, .. }
+            | Stmt::For       { span, .. } | Stmt::Range   { span, .. }
+            | Stmt::Switch    { span, .. } | Stmt::Defer   { span, .. }
+            | Stmt::Go        { span, .. } | Stmt::Expr    { span, .. } => span,
+            Stmt::Block(b) => &b.span,
+        }
+    }
+}
+
 #[derive(Debug, Clone)]
 pub struct SwitchCase {
     pub exprs: Vec<Expr>,  // empty ⇒ default
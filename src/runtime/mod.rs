@@ -92,6 +92,8 @@ impl Runtime {
         r.init_serial();
         r.init_servo();
         r.init_liquidcrystal();
+        r.init_testing();
+        r.init_sim();
         r
     }
 
@@ -416,6 +418,48 @@ impl Runtime {
         self.reg("LiquidCrystal",m);
     }
 
+    // ── Host-only packages (tsuki test) ───────────────────────────────────────
+    //
+    // `testing` and `sim` only exist in the host runtime the CLI compiles
+    // tests against; on a board their headers are missing. `t` is the
+    // receiver ({0}); messages are prefixed with the Go file and line
+    // that `#line` directives give __FILE__ and __LINE__.
+
+    fn init_testing(&mut self) {
+        let at = |f: &str| FnMap::Variadic(format!("{}(__FILE__, __LINE__, {{args}})", f));
+        let m = PkgMap::new(Some("tsuki_test.h"))
+            .fun("Error",   at("tsuki_test_error"))
+            .fun("Errorf",  at("tsuki_test_errorf"))
+            .fun("Fatal",   at("tsuki_test_fatal"))
+            .fun("Fatalf",  at("tsuki_test_fatalf"))
+            .fun("Log",     at("tsuki_test_log"))
+            .fun("Logf",    at("tsuki_test_logf"))
+            .fun("Skip",    at("tsuki_test_skip"))
+            .fun("Skipf",   at("tsuki_test_skipf"))
+            .fun("Fail",    FnMap::Template("{0}->Fail()".into()))
+            .fun("FailNow", FnMap::Template("{0}->FailNow()".into()))
+            .fun("SkipNow", FnMap::Template("{0}->SkipNow()".into()))
+            .fun("Failed",  FnMap::Template("{0}->Failed()".into()))
+            .fun("Skipped", FnMap::Template("{0}->Skipped()".into()))
+            .fun("Name",    FnMap::Template("{0}->Name()".into()))
+            .fun("Helper",  FnMap::Template("((void){0})".into()));
+        self.reg("testing", m);
+    }
+
+    fn init_sim(&mut self) {
+        self.reg("sim", PkgMap::new(Some("tsuki_host.h"))
+            .fun("SetPin",       FnMap::Template("tsuki_host_set_pin({0}, {1})".into()))
+            .fun("Pin",          FnMap::Template("tsuki_host_pin({0})".into()))
+            .fun("PinMode",      FnMap::Template("tsuki_host_pin_mode({0})".into()))
+            .fun("SetMillis",    FnMap::Template("tsuki_host_set_micros((uint64_t)({0}) * 1000)".into()))
+            .fun("SetMicros",    FnMap::Template("tsuki_host_set_micros({0})".into()))
+            .fun("Advance",      FnMap::Template("delay({0})".into()))
+            .fun("SerialInput",  FnMap::Template("tsuki_host_serial_input({0})".into()))
+            .fun("SerialOutput", FnMap::Direct("tsuki_host_serial_output()".into()))
+            .fun("Reset",        FnMap::Direct("tsuki_host_reset()".into()))
+        );
+    }
+
     // ── Lookup API ────────────────────────────────────────────────────────────
 
     pub fn pkg(&self, name: &str) -> Option<&PkgMap> {
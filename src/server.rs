@@ -0,0 +1,162 @@
+// ─────────────────────────────────────────────────────────────────────────────
+//  tsuki :: server
+//  `tsuki-core --serve`: a long-running transpiler driven over stdin/stdout,
+//  so the CLI pays process start-up once per build instead of once per file.
+//
+//  One JSON-RPC 2.0 message per line. Methods:
+//
+//      version    → { "version": "3.0.0", "diagnostics": 1, "capabilities": […] }
+//      transpile  { "files": [File] } → { "results": [FileResult] }
+//      check      { "files": [File] } → { "results": [FileResult] }
+//      shutdown   → null, then the server exits
+//
+//  File:       { "input", "output", "board", "source_map",
+//                "libs_dir", "packages" }
+//  FileResult: { "input", "output", "ok", "diagnostics" }
+//
+//  Diagnostics use the `--diagnostics=json` schema (see diagnostics.rs).
+//  A failing file never fails the batch; only malformed requests get an
+//  error response.
+// ─────────────────────────────────────────────────────────────────────────────
+
+use std::io::{BufRead, Write};
+use std::path::PathBuf;
+
+use serde::{Deserialize, Serialize};
+use serde_json::{json, Value};
+
+use crate::diagnostics::{self, Diagnostic};
+use crate::error::tsukiError;
+use crate::{Pipeline, PipelineOptions, TranspileConfig};
+
+const PARSE_ERROR:      i32 = -32700;
+const METHOD_NOT_FOUND: i32 = -32601;
+const INVALID_PARAMS:   i32 = -32602;
+
+#[derive(Debug, Deserialize)]
+struct Request {
+    #[serde(default)]
+    id:     Value,
+    method: String,
+    #[serde(default)]
+    params: Value,
+}
+
+#[derive(Debug, Deserialize)]
+struct File {
+    input:      String,
+    #[serde(default)]
+    output:     Option<String>,
+    #[serde(default = "default_board")]
+    board:      String,
+    #[serde(default)]
+    source_map: bool,
+    #[serde(default)]
+    libs_dir:   Option<String>,
+    #[serde(default)]
+    packages:   Vec<String>,
+}
+
+#[derive(Debug, Deserialize)]
+struct Batch {
+    files: Vec<File>,
+}
+
+#[derive(Debug, Serialize)]
+struct FileResult {
+    input:       String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    output:      Option<String>,
+    ok:          bool,
+    diagnostics: Vec<Diagnostic>,
+}
+
+fn default_board() -> String { "uno".into() }
+
+/// Serve requests from `input` until `shutdown` or end of input.
+pub fn serve<R: BufRead, W: Write>(input: R, mut out: W) -> std::io::Result<()> {
+    for line in input.lines() {
+        let line = line?;
+        if line.trim().is_empty() { continue; }
+
+        let (reply, stop) = match serde_json::from_str::<Request>(&line) {
+            Ok(req) => handle(req),
+            Err(e)  => (error(Value::Null, PARSE_ERROR, &e.to_string()), false),
+        };
+        writeln!(out, "{}", reply)?;
+        out.flush()?;
+        if stop { break; }
+    }
+    Ok(())
+}
+
+fn handle(req: Request) -> (Value, bool) {
+    match req.method.as_str() {
+        "version" => (ok(req.id, json!({
+            "version":      env!("CARGO_PKG_VERSION"),
+            "diagnostics":  diagnostics::VERSION,
+            "capabilities": crate::CAPABILITIES,
+        })), false),
+
+        "transpile" | "check" => {
+            let batch: Batch = match serde_json::from_value(req.params) {
+                Ok(b)  => b,
+                Err(e) => return (error(req.id, INVALID_PARAMS, &e.to_string()), false),
+            };
+            let check_only = req.method == "check";
+            let results: Vec<FileResult> = batch.files.iter()
+                .map(|f| run_file(f, check_only))
+                .collect();
+            (ok(req.id, json!({ "results": results })), false)
+        }
+
+        "shutdown" => (ok(req.id, Value::Null), true),
+
+        other => (error(req.id, METHOD_NOT_FOUND, &format!("unknown method `{}`", other)), false),
+    }
+}
+
+fn run_file(f: &File, check_only: bool) -> FileResult {
+    let failed = |e: tsukiError| FileResult {
+        input:       f.input.clone(),
+        output:      None,
+        ok:          false,
+        diagnostics: vec![Diagnostic::from_error(&e, &f.input)],
+    };
+
+    let source = match std::fs::read_to_string(&f.input) {
+        Ok(s)  => s,
+        Err(e) => return failed(tsukiError::other(format!("cannot read {}: {}", f.input, e))),
+    };
+
+    let cfg = TranspileConfig {
+        board:           f.board.clone(),
+        emit_source_map: f.source_map,
+        ..Default::default()
+    };
+    let pipeline = Pipeline::new(cfg).with_options(PipelineOptions {
+        libs_dir:  f.libs_dir.as_ref().map(PathBuf::from),
+        pkg_names: f.packages.clone(),
+    });
+
+    let cpp = match pipeline.run(&source, &f.input) {
+        Ok(cpp) => cpp,
+        Err(e)  => return failed(e),
+    };
+
+    let output = if check_only { None } else { f.output.clone() };
+    if let Some(path) = &output {
+        if let Err(e) = std::fs::write(path, &cpp) {
+            return failed(tsukiError::other(format!("cannot write {}: {}", path, e)));
+        }
+    }
+    FileResult { input: f.input.clone(), output, ok: true, diagnostics: Vec::new() }
+}
+
+fn ok(id: Value, result: Value) -> Value {
+    json!({ "jsonrpc": "2.0", "id": id, "result": result })
+}
+
+fn error(id: Value, code: i32, message: &str) -> Value {
+    json!({ "jsonrpc": "2.0", "id": id, "error": { "code": code, "message": message } })
+}
@@ -10,7 +10,7 @@ pub use config::TranspileConfig;
 use std::collections::{HashMap, HashSet};
 use std::fmt::Write as FmtWrite;
 
-use crate::error::{tsukiError, Result};
+use crate::error::{tsukiError, Result, Span};
 use crate::parser::ast::*;
 use crate::runtime::Runtime;
 
@@ -102,12 +102,18 @@ impl Transpiler {
                 if name == "setup" || name == "main" { saw_setup = true; }
                 if name == "loop"  { saw_loop  = true; }
             }
+            if let Decl::Func { span, .. } = f {
+                out += &self.line_directive(span);
+            }
             out += &self.emit_func(f)?;
             out += "\n";
         }
 
-        if !saw_setup { out += "void setup() {}\n\n"; }
-        if !saw_loop  { out += "void loop()  {}\n\n"; }
+        // Test files (those importing `testing`) run against the sketch's
+        // own setup and loop, so they get no empty ones.
+        let is_test = self.pkg_map.values().any(|p| p == "testing");
+        if !saw_setup && !is_test { out += "void setup() {}\n\n"; }
+        if !saw_loop  && !is_test { out += "void loop()  {}\n\n"; }
 
         Ok(out)
     }
@@ -137,6 +143,25 @@ impl Transpiler {
     }
 
     fn pad(&self) -> String { "    ".repeat(self.indent) }
+
+    /// `#line N "file"` pointing the following C++ line back at the Go
+    /// source, when source maps are enabled. The CLI reads these to map
+    /// compiler errors to Go lines; gcc honours them directly too.
+    fn line_directive(&self, span: &Span) -> String {
+        if !self.cfg.emit_source_map || span.line == 0 || span.file.is_empty() {
+            return String::new();
+        }
+        let file = span.file.replace('\\', "\\\\").replace('"', "\\\"");
+        format!("#line {} \"{}\"\n", span.line, file)
+    }
+
+    /// A statement on its own line(s), preceded by its `#line` directive.
+    /// Statements nested inline (`for` init, `else if`) go through
+    /// `emit_stmt` directly, since a directive there would break the line.
+    fn emit_stmt_line(&mut self, stmt: &Stmt) -> Result<String> {
+        let directive = self.line_directive(stmt.span());
+        Ok(directive + &self.emit_stmt(stmt)?)
+    }
     fn push_indent(&mut self) { self.indent += 1; }
     fn pop_indent(&mut self)  { if self.indent > 0 { self.indent -= 1; } }
 
@@ -207,6 +232,16 @@ impl Transpiler {
             let ret    = ret_type(sig);
             let params = params_str(sig);
 
+            // Parameters of package types dispatch their method calls like
+            // globals do, e.g. `t.Errorf(...)` for `t *testing.T`.
+            for p in &sig.params {
+                let (Some(pname), Some(type_name)) = (&p.name, named_type(&p.ty)) else { continue };
+                let Some((pkg_part, _)) = type_name.split_once('.') else { continue };
+                if let Some(canon) = self.pkg_map.get(pkg_part).cloned() {
+                    self.var_types.insert(pname.clone(), canon);
+                }
+            }
+
             let full_name = if let Some(r) = recv {
                 let type_name = match &r.ty {
                     Type::Ptr(inner) => match inner.as_ref() { Type::Named(n) => n.clone(), t => t.to_cpp() },
@@ -233,7 +268,7 @@ impl Transpiler {
         self.push_indent();
         let mut s = "{\n".to_string();
         for stmt in &block.stmts {
-            s += &self.emit_stmt(stmt)?;
+            s += &self.emit_stmt_line(stmt)?;
         }
         self.pop_indent();
         s += &format!("{}}}", self.pad());
@@ -359,7 +394,7 @@ impl Transpiler {
                             }
                         }
                         self.push_indent();
-                        for st in &case.body { s += &self.emit_stmt(st)?; }
+                        for st in &case.body { s += &self.emit_stmt_line(st)?; }
                         self.pop_indent();
                         s += &format!("{}}}", pad);
                     }
@@ -380,7 +415,7 @@ impl Transpiler {
                             }
                         }
                         self.push_indent();
-                        for st in &case.body { s += &self.emit_stmt(st)?; }
+                        for st in &case.body { s += &self.emit_stmt_line(st)?; }
                         s += &format!("{}break;\n", self.pad());
                         self.pop_indent();
                     }
@@ -632,6 +667,15 @@ fn params_str(sig: &FuncSig) -> String {
     }).collect::<Vec<_>>().join(", ")
 }
 
+/// The name of a named type or of the type a pointer points to.
+fn named_type(ty: &Type) -> Option<&str> {
+    match ty {
+        Type::Named(n) => Some(n.as_str()),
+        Type::Ptr(inner) => match inner.as_ref() { Type::Named(n) => Some(n.as_str()), _ => None },
+        _ => None,
+    }
+}
+
 fn ret_type(sig: &FuncSig) -> String {
     match sig.results.len() {
         0 => "void".into(),